@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 10 * time.Second
+	DefaultPollInterval  = time.Second
+)
+
+// SubmitFunc anchors lines in one batch transaction. Once the transaction
+// is signed, and before it enters the mempool, it calls sealed with its ID;
+// if sealed fails the batch is not submitted.
+type SubmitFunc func(lines []string, sealed func(txID string) error) (string, error)
+
+// LookupFunc reports whether the transaction with the given ID reached the
+// mempool or the chain.
+type LookupFunc func(txID string) bool
+
+type Agent struct {
+	Tailer        *Tailer
+	Checkpoint    *Checkpoint
+	Submit        SubmitFunc
+	BatchSize     int
+	FlushInterval time.Duration
+	PollInterval  time.Duration
+
+	pending   []Line
+	firstSeen time.Time
+	batches   int
+	linesSent uint64
+}
+
+func NewAgent(path string, submit SubmitFunc, lookup LookupFunc) (*Agent, error) {
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveIntent(checkpoint, lookup); err != nil {
+		return nil, err
+	}
+
+	tailer, err := NewTailer(path, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Agent{
+		Tailer:        tailer,
+		Checkpoint:    checkpoint,
+		Submit:        submit,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		PollInterval:  DefaultPollInterval,
+	}, nil
+}
+
+func (a *Agent) Run(stop <-chan struct{}) error {
+	defer a.Tailer.Close()
+
+	ticker := time.NewTicker(a.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if len(a.pending) < a.BatchSize*4 {
+			lines, err := a.Tailer.Poll()
+			if len(lines) > 0 && len(a.pending) == 0 {
+				a.firstSeen = time.Now()
+			}
+			a.pending = append(a.pending, lines...)
+			if err != nil {
+				fmt.Printf("Tail error: %v\n", err)
+			}
+		}
+
+		for len(a.pending) >= a.BatchSize {
+			if err := a.flush(a.BatchSize); err != nil {
+				break
+			}
+		}
+
+		if len(a.pending) > 0 && time.Since(a.firstSeen) >= a.FlushInterval {
+			a.flush(min(len(a.pending), a.BatchSize))
+		}
+
+		select {
+		case <-stop:
+			for len(a.pending) > 0 {
+				if err := a.flush(min(len(a.pending), a.BatchSize)); err != nil {
+					return fmt.Errorf("stopped with %d unsubmitted lines: %v", len(a.pending), err)
+				}
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// resolveIntent settles a batch that was being submitted when the agent
+// last stopped. If it reached the chain or the mempool the checkpoint moves
+// past its lines; otherwise they are read and submitted again.
+func resolveIntent(checkpoint *Checkpoint, lookup LookupFunc) error {
+	intent := checkpoint.Intent
+	if intent == nil {
+		return nil
+	}
+
+	if intent.TxID != "" && lookup(intent.TxID) {
+		fmt.Printf("Batch of %d lines was submitted before the agent stopped (%.16s...)\n", intent.Lines, intent.TxID)
+		checkpoint.commit(intent, intent.TxID)
+	} else {
+		fmt.Printf("Batch of %d lines was not submitted before the agent stopped; resending\n", intent.Lines)
+		checkpoint.Intent = nil
+	}
+	return checkpoint.Save()
+}
+
+// flush submits the first count pending lines. The intent is saved with
+// the sealed transaction's ID before it enters the mempool, so a crash
+// at any point either leaves the lines to be sent again or lets the next
+// start find the exact transaction they went into.
+func (a *Agent) flush(count int) error {
+	batch := a.pending[:count]
+
+	texts := make([]string, 0, len(batch))
+	for _, line := range batch {
+		texts = append(texts, line.Text)
+	}
+
+	last := batch[len(batch)-1]
+	intent := &Intent{
+		File:   last.File,
+		Offset: last.Offset,
+		Head:   a.Tailer.Head(last.File, last.Offset),
+		Lines:  uint64(len(batch)),
+	}
+	sealed := func(txID string) error {
+		intent.TxID = txID
+		a.Checkpoint.Intent = intent
+		if err := a.Checkpoint.Save(); err != nil {
+			return fmt.Errorf("could not save checkpoint: %v", err)
+		}
+		return nil
+	}
+
+	txID, err := a.Submit(texts, sealed)
+	if err != nil {
+		a.Checkpoint.Intent = nil
+		fmt.Printf("Submit failed, will retry %d lines: %v\n", len(batch), err)
+		return err
+	}
+
+	a.Checkpoint.commit(intent, txID)
+	if err := a.Checkpoint.Save(); err != nil {
+		fmt.Printf("Warning: Could not save checkpoint: %v\n", err)
+	}
+
+	a.pending = a.pending[count:]
+	a.firstSeen = time.Now()
+	a.batches++
+	a.linesSent += uint64(len(batch))
+	return nil
+}
+
+func (a *Agent) Stats() (int, uint64, int) {
+	return a.batches, a.linesSent, len(a.pending)
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"chainlog/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A batch whose lines repeat an earlier batch has the same Merkle root. If
+// the agent stops after sealing it but before it is stored, the next start
+// must still send its lines.
+func TestAgentResendsRepeatedBatch(t *testing.T) {
+	storage.DataDir = t.TempDir()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("retrying\nretrying\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	submitted := make(map[string][]string)
+	var sealedCount int
+	crash := false
+	submit := func(lines []string, sealed func(string) error) (string, error) {
+		sealedCount++
+		txID := fmt.Sprintf("tx-%d", sealedCount)
+		if err := sealed(txID); err != nil {
+			return "", err
+		}
+		if crash {
+			return "", fmt.Errorf("agent stopped")
+		}
+		submitted[txID] = lines
+		return txID, nil
+	}
+	lookup := func(txID string) bool {
+		_, found := submitted[txID]
+		return found
+	}
+
+	first, err := NewAgent(path, submit, lookup)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	first.BatchSize = 1
+	lines, err := first.Tailer.Poll()
+	if err != nil || len(lines) != 2 {
+		t.Fatalf("Poll returned %d lines, %v", len(lines), err)
+	}
+	first.pending = lines
+	if err := first.flush(1); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	crash = true
+	if err := first.flush(1); err == nil {
+		t.Fatal("flush succeeded despite the crash")
+	}
+	first.Tailer.Close()
+
+	// The crash left the second batch's intent behind.
+	crash = false
+	second, err := NewAgent(path, submit, lookup)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	defer second.Tailer.Close()
+	if second.Checkpoint.Lines != 1 {
+		t.Errorf("checkpoint covers %d lines after restart, want 1", second.Checkpoint.Lines)
+	}
+	lines, err = second.Tailer.Poll()
+	if err != nil || len(lines) != 1 || lines[0].Text != "retrying" {
+		t.Fatalf("after restart Poll returned %v, %v; want the second line", lines, err)
+	}
+}
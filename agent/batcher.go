@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultMaxPending = 100000
+
+type GroupSubmitFunc func(group string, entries []string) (string, error)
+
+type pendingGroup struct {
+	entries []string
+	first   time.Time
+}
+
+type Batcher struct {
+	Submit        GroupSubmitFunc
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxPending    int
+
+	mu      sync.Mutex
+	groups  map[string]*pendingGroup
+	pending int
+	dropped uint64
+	batches int
+	sent    uint64
+}
+
+func NewBatcher(submit GroupSubmitFunc) *Batcher {
+	return &Batcher{
+		Submit:        submit,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		MaxPending:    DefaultMaxPending,
+		groups:        make(map[string]*pendingGroup),
+	}
+}
+
+func (b *Batcher) Add(group string, entry string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pending >= b.MaxPending {
+		b.dropped++
+		return false
+	}
+
+	pending, exists := b.groups[group]
+	if !exists {
+		pending = &pendingGroup{first: time.Now()}
+		b.groups[group] = pending
+	}
+
+	pending.entries = append(pending.entries, entry)
+	b.pending++
+	return true
+}
+
+func (b *Batcher) Run(stop <-chan struct{}) error {
+	ticker := time.NewTicker(DefaultPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			b.Flush(true)
+			if _, _, pending, _ := b.Stats(); pending > 0 {
+				return fmt.Errorf("stopped with %d unsubmitted entries", pending)
+			}
+			return nil
+		case <-ticker.C:
+			b.Flush(false)
+		}
+	}
+}
+
+func (b *Batcher) Flush(all bool) {
+	for _, group := range b.readyGroups(all) {
+		for {
+			entries := b.take(group, all)
+			if len(entries) == 0 {
+				break
+			}
+
+			txID, err := b.Submit(group, entries)
+			if err != nil {
+				fmt.Printf("Submit failed, will retry %d entries: %v\n", len(entries), err)
+				b.restore(group, entries)
+				break
+			}
+
+			b.mu.Lock()
+			b.batches++
+			b.sent += uint64(len(entries))
+			b.mu.Unlock()
+
+			if txID != "" && len(group) > 0 {
+				fmt.Printf("Anchored %d entries for %s in batch %.16s\n", len(entries), group, txID)
+			} else if txID != "" {
+				fmt.Printf("Anchored %d entries in batch %.16s\n", len(entries), txID)
+			}
+		}
+	}
+}
+
+func (b *Batcher) readyGroups(all bool) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var ready []string
+	for name, group := range b.groups {
+		if all || len(group.entries) >= b.BatchSize || time.Since(group.first) >= b.FlushInterval {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+func (b *Batcher) take(name string, all bool) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, exists := b.groups[name]
+	if !exists || len(group.entries) == 0 {
+		return nil
+	}
+
+	expired := time.Since(group.first) >= b.FlushInterval
+	if !all && !expired && len(group.entries) < b.BatchSize {
+		return nil
+	}
+
+	count := min(len(group.entries), b.BatchSize)
+	entries := group.entries[:count:count]
+	group.entries = group.entries[count:]
+	b.pending -= count
+
+	if len(group.entries) == 0 {
+		delete(b.groups, name)
+	} else {
+		group.first = time.Now()
+	}
+	return entries
+}
+
+func (b *Batcher) restore(name string, entries []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, exists := b.groups[name]
+	if !exists {
+		group = &pendingGroup{first: time.Now()}
+		b.groups[name] = group
+	}
+
+	group.entries = append(append([]string(nil), entries...), group.entries...)
+	b.pending += len(entries)
+}
+
+func (b *Batcher) Stats() (int, uint64, int, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.batches, b.sent, b.pending, b.dropped
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"chainlog/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const CheckpointDir = "agent"
+
+type FileID struct {
+	Device uint64 `json:"device"`
+	Inode  uint64 `json:"inode"`
+}
+
+func (id FileID) IsZero() bool {
+	return id.Device == 0 && id.Inode == 0
+}
+
+type Checkpoint struct {
+	Path      string  `json:"path"`
+	File      FileID  `json:"file"`
+	Offset    int64   `json:"offset"`
+	Head      string  `json:"head,omitempty"`
+	Lines     uint64  `json:"lines"`
+	LastTx    string  `json:"last_tx,omitempty"`
+	Intent    *Intent `json:"intent,omitempty"`
+	UpdatedAt int64   `json:"updated_at"`
+}
+
+// Intent is a batch the agent is submitting. It is saved once the batch's
+// transaction is signed and before it enters the mempool, so that after a
+// crash the agent can tell whether that transaction made it and the lines
+// must not be sent again. Batches of identical lines share a Merkle root,
+// so the intent names the transaction rather than the root.
+type Intent struct {
+	TxID   string `json:"tx_id"`
+	File   FileID `json:"file"`
+	Offset int64  `json:"offset"`
+	Head   string `json:"head,omitempty"`
+	Lines  uint64 `json:"lines"`
+}
+
+// commit moves the checkpoint past the lines of intent.
+func (c *Checkpoint) commit(intent *Intent, txID string) {
+	c.File = intent.File
+	c.Offset = intent.Offset
+	c.Head = intent.Head
+	c.Lines += intent.Lines
+	c.LastTx = txID
+	c.Intent = nil
+}
+
+func checkpointFile(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return filepath.Join(CheckpointDir, hex.EncodeToString(hash[:8])+".json")
+}
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{Path: path}
+
+	filename := checkpointFile(path)
+	if !storage.FileExists(filename) {
+		return checkpoint, nil
+	}
+
+	if err := storage.LoadFromFile(checkpoint, filename); err != nil {
+		return nil, err
+	}
+
+	if checkpoint.Path != path {
+		return nil, fmt.Errorf("checkpoint %s belongs to %s", filename, checkpoint.Path)
+	}
+	return checkpoint, nil
+}
+
+func (c *Checkpoint) Save() error {
+	if err := os.MkdirAll(filepath.Join(storage.DataDir, CheckpointDir), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+
+	c.UpdatedAt = time.Now().Unix()
+	return storage.SaveToFile(c, checkpointFile(c.Path))
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package agent
+
+import "os"
+
+func fileIdentity(info os.FileInfo) FileID {
+	return FileID{}
+}
@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OTLPLogsPath      = "/v1/logs"
+	DefaultOTLPStream = "otel/unknown_service"
+
+	maxOTLPRequest = 16 * 1024 * 1024
+)
+
+var otlpSeverityNames = []string{
+	"", "TRACE", "TRACE2", "TRACE3", "TRACE4", "DEBUG", "DEBUG2", "DEBUG3", "DEBUG4",
+	"INFO", "INFO2", "INFO3", "INFO4", "WARN", "WARN2", "WARN3", "WARN4",
+	"ERROR", "ERROR2", "ERROR3", "ERROR4", "FATAL", "FATAL2", "FATAL3", "FATAL4",
+}
+
+var invalidStreamChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    json.RawMessage `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	BytesValue  *string         `json:"bytesValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKeyValues  `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []*otlpAnyValue `json:"values"`
+}
+
+type otlpKeyValue struct {
+	Key   string        `json:"key"`
+	Value *otlpAnyValue `json:"value"`
+}
+
+type otlpKeyValues struct {
+	Values []*otlpKeyValue `json:"values"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         json.RawMessage `json:"timeUnixNano"`
+	ObservedTimeUnixNano json.RawMessage `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 *otlpAnyValue   `json:"body"`
+	Attributes           []*otlpKeyValue `json:"attributes"`
+	TraceID              string          `json:"traceId"`
+	SpanID               string          `json:"spanId"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scope"`
+	LogRecords []*otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []*otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []*otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []*otlpResourceLogs `json:"resourceLogs"`
+}
+
+type OTLPHandler func(stream string, entry map[string]interface{}) bool
+
+type OTLPReceiver struct {
+	Address string
+	Handle  OTLPHandler
+
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewOTLPReceiver(address string, handle OTLPHandler) *OTLPReceiver {
+	return &OTLPReceiver{
+		Address: address,
+		Handle:  handle,
+	}
+}
+
+func (r *OTLPReceiver) Start() error {
+	listener, err := net.Listen("tcp", r.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", r.Address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OTLPLogsPath, r.handleLogs)
+
+	r.listener = listener
+	r.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+	}
+
+	go r.server.Serve(listener)
+	fmt.Printf("OTLP/HTTP logs endpoint: http://%s%s\n", listener.Addr(), OTLPLogsPath)
+	return nil
+}
+
+func (r *OTLPReceiver) Stop() error {
+	if r.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
+
+func (r *OTLPReceiver) handleLogs(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		http.Error(w, "only application/json OTLP payloads are supported", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, req.Body, maxOTLPRequest)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxOTLPRequest)
+	}
+
+	var request otlpLogsRequest
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid OTLP logs request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rejected := 0
+	for _, resourceLogs := range request.ResourceLogs {
+		if resourceLogs == nil {
+			continue
+		}
+
+		resource := keyValuesToMap(resourceLogs.Resource.Attributes)
+		stream := OTLPStreamName(resource)
+
+		for _, scopeLogs := range resourceLogs.ScopeLogs {
+			if scopeLogs == nil {
+				continue
+			}
+			for _, record := range scopeLogs.LogRecords {
+				if record == nil {
+					continue
+				}
+
+				entry := otlpEntry(record, resource, scopeLogs.Scope.Name)
+				if !r.Handle(stream, entry) {
+					rejected++
+				}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if rejected > 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"partialSuccess": map[string]interface{}{
+				"rejectedLogRecords": strconv.Itoa(rejected),
+				"errorMessage":       "some log records were not accepted for anchoring",
+			},
+		})
+		return
+	}
+	w.Write([]byte("{}"))
+}
+
+func OTLPStreamName(resource map[string]interface{}) string {
+	service, _ := resource["service.name"].(string)
+	if service == "" {
+		return DefaultOTLPStream
+	}
+
+	name := "otel/"
+	if namespace, _ := resource["service.namespace"].(string); namespace != "" {
+		name += sanitizeStreamPart(namespace) + "/"
+	}
+	name += sanitizeStreamPart(service)
+
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
+
+func sanitizeStreamPart(part string) string {
+	part = invalidStreamChars.ReplaceAllString(strings.TrimSpace(part), "-")
+	part = strings.Trim(part, "/")
+	if part == "" {
+		return "unknown"
+	}
+	return part
+}
+
+func otlpEntry(record *otlpLogRecord, resource map[string]interface{}, scope string) map[string]interface{} {
+	entry := keyValuesToMap(record.Attributes)
+
+	timestamp := parseUnixNano(record.TimeUnixNano)
+	if timestamp == 0 {
+		timestamp = parseUnixNano(record.ObservedTimeUnixNano)
+	}
+	if timestamp == 0 {
+		timestamp = time.Now().UnixNano()
+	}
+	entry["timestamp"] = time.Unix(0, timestamp).UTC().Format(time.RFC3339Nano)
+
+	severity := record.SeverityText
+	if severity == "" && record.SeverityNumber > 0 && record.SeverityNumber < len(otlpSeverityNames) {
+		severity = otlpSeverityNames[record.SeverityNumber]
+	}
+	if severity != "" {
+		entry["severity"] = severity
+	}
+	if record.SeverityNumber > 0 {
+		entry["severity_number"] = int64(record.SeverityNumber)
+	}
+
+	if record.Body != nil {
+		entry["body"] = record.Body.toValue()
+	}
+	if record.TraceID != "" {
+		entry["trace_id"] = record.TraceID
+	}
+	if record.SpanID != "" {
+		entry["span_id"] = record.SpanID
+	}
+	if scope != "" {
+		entry["scope"] = scope
+	}
+	if len(resource) > 0 {
+		entry["resource"] = resource
+	}
+
+	return entry
+}
+
+func parseUnixNano(raw json.RawMessage) int64 {
+	if len(raw) == 0 {
+		return 0
+	}
+
+	text := strings.Trim(string(raw), `"`)
+	value, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func keyValuesToMap(values []*otlpKeyValue) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for _, kv := range values {
+		if kv == nil || kv.Key == "" {
+			continue
+		}
+		result[kv.Key] = kv.Value.toValue()
+	}
+	return result
+}
+
+func (v *otlpAnyValue) toValue() interface{} {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case len(v.IntValue) > 0:
+		if value, err := strconv.ParseInt(strings.Trim(string(v.IntValue), `"`), 10, 64); err == nil {
+			return value
+		}
+		return nil
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BytesValue != nil:
+		if _, err := base64.StdEncoding.DecodeString(*v.BytesValue); err == nil {
+			return *v.BytesValue
+		}
+		return nil
+	case v.ArrayValue != nil:
+		values := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			values = append(values, item.toValue())
+		}
+		return values
+	case v.KvlistValue != nil:
+		return keyValuesToMap(v.KvlistValue.Values)
+	}
+	return nil
+}
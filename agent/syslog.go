@@ -0,0 +1,333 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatRFC3164 = "rfc3164"
+)
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var severityAliases = map[string]string{
+	"emergency":     "emerg",
+	"panic":         "emerg",
+	"critical":      "crit",
+	"error":         "err",
+	"warn":          "warning",
+	"informational": "info",
+}
+
+type SyslogMessage struct {
+	Facility       int
+	Severity       int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+	Format         string
+	Source         string
+}
+
+func FacilityName(facility int) string {
+	if facility < 0 || facility >= len(facilityNames) {
+		return strconv.Itoa(facility)
+	}
+	return facilityNames[facility]
+}
+
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(severityNames) {
+		return strconv.Itoa(severity)
+	}
+	return severityNames[severity]
+}
+
+func ParseFacility(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, facility := range facilityNames {
+		if facility == name {
+			return i, nil
+		}
+	}
+	if code, err := strconv.Atoi(name); err == nil && code >= 0 && code < len(facilityNames) {
+		return code, nil
+	}
+	return 0, fmt.Errorf("unknown syslog facility: %s", name)
+}
+
+func ParseSeverity(name string) (int, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, exists := severityAliases[name]; exists {
+		name = alias
+	}
+	for i, severity := range severityNames {
+		if severity == name {
+			return i, nil
+		}
+	}
+	if code, err := strconv.Atoi(name); err == nil && code >= 0 && code < len(severityNames) {
+		return code, nil
+	}
+	return 0, fmt.Errorf("unknown syslog severity: %s", name)
+}
+
+func ParseSyslog(data []byte, received time.Time) (*SyslogMessage, error) {
+	line := strings.TrimRight(string(data), "\r\n\x00")
+	if !strings.HasPrefix(line, "<") {
+		return nil, fmt.Errorf("missing priority")
+	}
+
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("malformed priority")
+	}
+
+	priority, err := strconv.Atoi(line[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return nil, fmt.Errorf("invalid priority: %s", line[1:end])
+	}
+
+	msg := &SyslogMessage{
+		Facility:  priority / 8,
+		Severity:  priority % 8,
+		Timestamp: received,
+	}
+
+	rest := line[end+1:]
+	if strings.HasPrefix(rest, "1 ") {
+		msg.Format = FormatRFC5424
+		return msg, parseRFC5424(msg, rest[2:])
+	}
+
+	msg.Format = FormatRFC3164
+	parseRFC3164(msg, rest, received)
+	return msg, nil
+}
+
+func parseRFC5424(msg *SyslogMessage, rest string) error {
+	fields := make([]string, 0, 5)
+	for len(fields) < 5 {
+		space := strings.IndexByte(rest, ' ')
+		if space < 0 {
+			return fmt.Errorf("truncated RFC 5424 header")
+		}
+		fields = append(fields, rest[:space])
+		rest = rest[space+1:]
+	}
+
+	if fields[0] != "-" {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %s", fields[0])
+		}
+		msg.Timestamp = timestamp
+	}
+
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.ProcID = nilValue(fields[3])
+	msg.MsgID = nilValue(fields[4])
+
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		data, remainder, err := parseStructuredData(rest)
+		if err != nil {
+			return err
+		}
+		msg.StructuredData = data
+		rest = remainder
+	}
+
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return nil
+}
+
+func parseStructuredData(rest string) (map[string]map[string]string, string, error) {
+	data := make(map[string]map[string]string)
+
+	for strings.HasPrefix(rest, "[") {
+		rest = rest[1:]
+
+		idEnd := strings.IndexAny(rest, " ]")
+		if idEnd <= 0 {
+			return nil, "", fmt.Errorf("malformed structured data element")
+		}
+		params := make(map[string]string)
+		data[rest[:idEnd]] = params
+		rest = rest[idEnd:]
+
+		for strings.HasPrefix(rest, " ") {
+			rest = rest[1:]
+
+			eq := strings.Index(rest, "=\"")
+			if eq <= 0 {
+				return nil, "", fmt.Errorf("malformed structured data parameter")
+			}
+			name := rest[:eq]
+			rest = rest[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(rest); i++ {
+				c := rest[i]
+				if c == '\\' && i+1 < len(rest) && strings.IndexByte("\"\\]", rest[i+1]) >= 0 {
+					value.WriteByte(rest[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					rest = rest[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, "", fmt.Errorf("unterminated structured data value")
+			}
+			params[name] = value.String()
+		}
+
+		if !strings.HasPrefix(rest, "]") {
+			return nil, "", fmt.Errorf("unterminated structured data element")
+		}
+		rest = rest[1:]
+	}
+
+	return data, rest, nil
+}
+
+func parseRFC3164(msg *SyslogMessage, rest string, received time.Time) {
+	if len(rest) >= 16 && rest[15] == ' ' {
+		if timestamp, err := time.ParseInLocation(time.Stamp, rest[:15], time.Local); err == nil {
+			year := received.Year()
+			if timestamp.Month() > received.Month() {
+				year--
+			}
+			msg.Timestamp = timestamp.AddDate(year, 0, 0)
+			rest = rest[16:]
+
+			if space := strings.IndexByte(rest, ' '); space > 0 && !strings.HasSuffix(rest[:space], ":") {
+				msg.Hostname = rest[:space]
+				rest = rest[space+1:]
+			}
+		}
+	}
+
+	tagEnd := strings.IndexAny(rest, ":[ ")
+	if tagEnd > 0 && tagEnd <= 48 && rest[tagEnd] != ' ' {
+		msg.AppName = rest[:tagEnd]
+		rest = rest[tagEnd:]
+
+		if strings.HasPrefix(rest, "[") {
+			if end := strings.IndexByte(rest, ']'); end > 0 {
+				msg.ProcID = rest[1:end]
+				rest = rest[end+1:]
+			}
+		}
+		rest = strings.TrimPrefix(rest, ":")
+	}
+
+	msg.Message = strings.TrimPrefix(rest, " ")
+}
+
+func nilValue(field string) string {
+	if field == "-" {
+		return ""
+	}
+	return field
+}
+
+func (m *SyslogMessage) Entry() map[string]interface{} {
+	entry := map[string]interface{}{
+		"facility":  FacilityName(m.Facility),
+		"severity":  SeverityName(m.Severity),
+		"timestamp": m.Timestamp.UTC().Format(time.RFC3339Nano),
+		"message":   m.Message,
+		"format":    m.Format,
+	}
+
+	optional := map[string]string{
+		"hostname": m.Hostname,
+		"app":      m.AppName,
+		"procid":   m.ProcID,
+		"msgid":    m.MsgID,
+		"source":   m.Source,
+	}
+	for name, value := range optional {
+		if value != "" {
+			entry[name] = value
+		}
+	}
+
+	if len(m.StructuredData) > 0 {
+		data := make(map[string]interface{}, len(m.StructuredData))
+		for id, params := range m.StructuredData {
+			values := make(map[string]interface{}, len(params))
+			for name, value := range params {
+				values[name] = value
+			}
+			data[id] = values
+		}
+		entry["structured_data"] = data
+	}
+
+	return entry
+}
+
+type SyslogFilter struct {
+	Facilities  map[int]bool
+	MaxSeverity int
+}
+
+func NewSyslogFilter(facilities string, severity string) (*SyslogFilter, error) {
+	filter := &SyslogFilter{
+		Facilities:  make(map[int]bool),
+		MaxSeverity: len(severityNames) - 1,
+	}
+
+	if facilities != "" {
+		for _, name := range strings.Split(facilities, ",") {
+			facility, err := ParseFacility(name)
+			if err != nil {
+				return nil, err
+			}
+			filter.Facilities[facility] = true
+		}
+	}
+
+	if severity != "" {
+		maxSeverity, err := ParseSeverity(severity)
+		if err != nil {
+			return nil, err
+		}
+		filter.MaxSeverity = maxSeverity
+	}
+
+	return filter, nil
+}
+
+func (f *SyslogFilter) Allows(msg *SyslogMessage) bool {
+	if msg.Severity > f.MaxSeverity {
+		return false
+	}
+	return len(f.Facilities) == 0 || f.Facilities[msg.Facility]
+}
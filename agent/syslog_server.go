@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxSyslogMessage = 64 * 1024
+
+type SyslogHandler func(msg *SyslogMessage)
+
+type SyslogServer struct {
+	UDPAddress string
+	TCPAddress string
+	Handle     SyslogHandler
+
+	udp      net.PacketConn
+	tcp      net.Listener
+	conns    map[net.Conn]bool
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	received uint64
+	invalid  uint64
+}
+
+func NewSyslogServer(udpAddress string, tcpAddress string, handle SyslogHandler) *SyslogServer {
+	return &SyslogServer{
+		UDPAddress: udpAddress,
+		TCPAddress: tcpAddress,
+		Handle:     handle,
+		conns:      make(map[net.Conn]bool),
+	}
+}
+
+func (s *SyslogServer) Start() error {
+	if s.UDPAddress == "" && s.TCPAddress == "" {
+		return fmt.Errorf("no syslog listen address configured")
+	}
+
+	if s.UDPAddress != "" {
+		conn, err := net.ListenPacket("udp", s.UDPAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on udp %s: %v", s.UDPAddress, err)
+		}
+		s.udp = conn
+		s.wg.Add(1)
+		go s.serveUDP()
+		fmt.Printf("Syslog listening on udp %s\n", conn.LocalAddr())
+	}
+
+	if s.TCPAddress != "" {
+		listener, err := net.Listen("tcp", s.TCPAddress)
+		if err != nil {
+			s.Stop()
+			return fmt.Errorf("failed to listen on tcp %s: %v", s.TCPAddress, err)
+		}
+		s.tcp = listener
+		s.wg.Add(1)
+		go s.serveTCP()
+		fmt.Printf("Syslog listening on tcp %s\n", listener.Addr())
+	}
+
+	return nil
+}
+
+func (s *SyslogServer) Stop() {
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	if s.tcp != nil {
+		s.tcp.Close()
+	}
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *SyslogServer) Stats() (uint64, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received, s.invalid
+}
+
+func (s *SyslogServer) serveUDP() {
+	defer s.wg.Done()
+
+	buffer := make([]byte, maxSyslogMessage)
+	for {
+		n, addr, err := s.udp.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		s.dispatch(buffer[:n], addr)
+	}
+}
+
+func (s *SyslogServer) serveTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+func (s *SyslogServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReaderSize(conn, maxSyslogMessage)
+	for {
+		frame, err := readSyslogFrame(reader)
+		if len(frame) > 0 {
+			s.dispatch(frame, conn.RemoteAddr())
+		}
+		if err != nil {
+			if err != io.EOF {
+				fmt.Printf("Syslog connection from %s closed: %v\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+func readSyslogFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := reader.ReadString(' ')
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := strconv.Atoi(strings.TrimSpace(prefix))
+		if err != nil || length <= 0 || length > maxSyslogMessage {
+			return nil, fmt.Errorf("invalid octet count: %q", prefix)
+		}
+
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	line, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("message exceeds %d bytes", maxSyslogMessage)
+	}
+	return append([]byte(nil), line...), err
+}
+
+func (s *SyslogServer) dispatch(data []byte, addr net.Addr) {
+	msg, err := ParseSyslog(data, time.Now())
+
+	s.mu.Lock()
+	s.received++
+	if err != nil {
+		s.invalid++
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		msg.Source = host
+	}
+	s.Handle(msg)
+}
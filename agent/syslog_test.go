@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSyslogRFC5424(t *testing.T) {
+	received := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want SyslogMessage
+	}{
+		{
+			name: "full header without structured data",
+			line: "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8",
+			want: SyslogMessage{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "su",
+				MsgID:     "ID47",
+				Message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name: "structured data with escapes",
+			line: `<165>1 2003-10-11T22:14:15.003Z host evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventID="10\"11\]"][origin ip="192.0.2.1"] An application event`,
+			want: SyslogMessage{
+				Facility:  20,
+				Severity:  5,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "host",
+				AppName:   "evntslog",
+				ProcID:    "1234",
+				MsgID:     "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventID": `10"11]`},
+					"origin":            {"ip": "192.0.2.1"},
+				},
+				Message: "An application event",
+			},
+		},
+		{
+			name: "nil values and a BOM",
+			line: "<14>1 - - - - - - \ufeffhello\r\n",
+			want: SyslogMessage{
+				Facility:  1,
+				Severity:  6,
+				Timestamp: received,
+				Message:   "hello",
+			},
+		},
+		{
+			name: "element without parameters",
+			line: "<0>1 - - - - - [empty] ",
+			want: SyslogMessage{
+				Timestamp:      received,
+				StructuredData: map[string]map[string]string{"empty": {}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseSyslog([]byte(tt.line), received)
+			if err != nil {
+				t.Fatalf("ParseSyslog: %v", err)
+			}
+			tt.want.Format = FormatRFC5424
+			if !msg.Timestamp.Equal(tt.want.Timestamp) {
+				t.Errorf("Timestamp = %v, want %v", msg.Timestamp, tt.want.Timestamp)
+			}
+			msg.Timestamp = tt.want.Timestamp
+			if !reflect.DeepEqual(*msg, tt.want) {
+				t.Errorf("ParseSyslog =\n%+v\nwant\n%+v", *msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyslogRFC3164(t *testing.T) {
+	received := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		line string
+		want SyslogMessage
+	}{
+		{
+			name: "timestamp, host and tag",
+			line: "<34>Mar  9 22:14:15 mymachine su: 'su root' failed",
+			want: SyslogMessage{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: time.Date(2024, 3, 9, 22, 14, 15, 0, time.Local),
+				Hostname:  "mymachine",
+				AppName:   "su",
+				Message:   "'su root' failed",
+			},
+		},
+		{
+			name: "tag with pid",
+			line: "<30>Mar 10 11:59:00 web nginx[812]: started",
+			want: SyslogMessage{
+				Facility:  3,
+				Severity:  6,
+				Timestamp: time.Date(2024, 3, 10, 11, 59, 0, 0, time.Local),
+				Hostname:  "web",
+				AppName:   "nginx",
+				ProcID:    "812",
+				Message:   "started",
+			},
+		},
+		{
+			name: "later month belongs to last year",
+			line: "<13>Dec 31 23:59:59 host cron: tick",
+			want: SyslogMessage{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2023, 12, 31, 23, 59, 59, 0, time.Local),
+				Hostname:  "host",
+				AppName:   "cron",
+				Message:   "tick",
+			},
+		},
+		{
+			name: "no hostname",
+			line: "<13>Mar 10 08:00:00 sshd[7]: accepted",
+			want: SyslogMessage{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 3, 10, 8, 0, 0, 0, time.Local),
+				AppName:   "sshd",
+				ProcID:    "7",
+				Message:   "accepted",
+			},
+		},
+		{
+			name: "bare message",
+			line: "<5>plain text only",
+			want: SyslogMessage{
+				Severity:  5,
+				Timestamp: received,
+				Message:   "plain text only",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseSyslog([]byte(tt.line), received)
+			if err != nil {
+				t.Fatalf("ParseSyslog: %v", err)
+			}
+			tt.want.Format = FormatRFC3164
+			if !msg.Timestamp.Equal(tt.want.Timestamp) {
+				t.Errorf("Timestamp = %v, want %v", msg.Timestamp, tt.want.Timestamp)
+			}
+			msg.Timestamp = tt.want.Timestamp
+			if !reflect.DeepEqual(*msg, tt.want) {
+				t.Errorf("ParseSyslog =\n%+v\nwant\n%+v", *msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyslogMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"empty", ""},
+		{"no priority", "hello"},
+		{"unclosed priority", "<34 hello"},
+		{"empty priority", "<>hello"},
+		{"long priority", "<0034>hello"},
+		{"non-numeric priority", "<ab>hello"},
+		{"priority out of range", "<192>hello"},
+		{"truncated header", "<34>1 2003-10-11T22:14:15Z host app"},
+		{"bad timestamp", "<34>1 yesterday host app - - - msg"},
+		{"unterminated element", "<34>1 - - - - - [id a=\"1\" msg"},
+		{"unterminated value", "<34>1 - - - - - [id a=\"1]"},
+		{"parameter without value", "<34>1 - - - - - [id a] msg"},
+		{"empty element id", "<34>1 - - - - - [] msg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg, err := ParseSyslog([]byte(tt.line), time.Now()); err == nil {
+				t.Errorf("ParseSyslog(%q) = %+v, want error", tt.line, msg)
+			}
+		})
+	}
+}
+
+func TestSyslogFilter(t *testing.T) {
+	filter, err := NewSyslogFilter("auth, local0", "warn")
+	if err != nil {
+		t.Fatalf("NewSyslogFilter: %v", err)
+	}
+
+	tests := []struct {
+		facility, severity int
+		want               bool
+	}{
+		{4, 3, true},
+		{16, 4, true},
+		{16, 5, false},
+		{1, 0, false},
+	}
+	for _, tt := range tests {
+		msg := &SyslogMessage{Facility: tt.facility, Severity: tt.severity}
+		if got := filter.Allows(msg); got != tt.want {
+			t.Errorf("Allows(%s.%s) = %v, want %v", FacilityName(tt.facility), SeverityName(tt.severity), got, tt.want)
+		}
+	}
+
+	for _, bad := range [][2]string{{"nosuch", ""}, {"", "loud"}, {"24", ""}} {
+		if _, err := NewSyslogFilter(bad[0], bad[1]); err == nil {
+			t.Errorf("NewSyslogFilter(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	maxLineLength = 1024 * 1024
+	headLength    = 256
+)
+
+type Line struct {
+	Text   string
+	File   FileID
+	Offset int64
+}
+
+type Tailer struct {
+	Path string
+
+	file    *os.File
+	info    os.FileInfo
+	id      FileID
+	offset  int64
+	partial []byte
+	next    bool
+}
+
+func NewTailer(path string, checkpoint *Checkpoint) (*Tailer, error) {
+	t := &Tailer{Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot tail %s: %v", path, err)
+	}
+
+	if checkpoint == nil || checkpoint.Offset == 0 {
+		return t, t.open(path, 0)
+	}
+
+	if matchesCheckpoint(path, info, checkpoint) {
+		return t, t.open(path, checkpoint.Offset)
+	}
+
+	rotated := path + ".1"
+	if rotatedInfo, err := os.Stat(rotated); err == nil && !checkpoint.File.IsZero() &&
+		matchesCheckpoint(rotated, rotatedInfo, checkpoint) {
+		fmt.Printf("%s was rotated while stopped, finishing %s first\n", path, rotated)
+		t.next = true
+		return t, t.open(rotated, checkpoint.Offset)
+	}
+
+	fmt.Printf("Warning: %s no longer matches its checkpoint, reading from the start\n", path)
+	return t, t.open(path, 0)
+}
+
+func matchesCheckpoint(path string, info os.FileInfo, checkpoint *Checkpoint) bool {
+	if fileIdentity(info) != checkpoint.File || info.Size() < checkpoint.Offset {
+		return false
+	}
+	if checkpoint.Head == "" {
+		return true
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	return headHash(file, checkpoint.Offset) == checkpoint.Head
+}
+
+func headHash(file *os.File, offset int64) string {
+	buffer := make([]byte, min(offset, headLength))
+	if _, err := file.ReadAt(buffer, 0); err != nil {
+		return ""
+	}
+
+	hash := sha256.Sum256(buffer)
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *Tailer) Head(id FileID, offset int64) string {
+	if t.file == nil || id != t.id {
+		return ""
+	}
+	return headHash(t.file, offset)
+}
+
+func (t *Tailer) open(path string, offset int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %v", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot stat %s: %v", path, err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot seek %s: %v", path, err)
+	}
+
+	if t.file != nil {
+		t.file.Close()
+	}
+
+	t.file = file
+	t.info = info
+	t.id = fileIdentity(info)
+	t.offset = offset
+	t.partial = nil
+	return nil
+}
+
+func (t *Tailer) Poll() ([]Line, error) {
+	lines, err := t.drain()
+	if err != nil {
+		return lines, err
+	}
+
+	current, err := os.Stat(t.Path)
+	if err != nil {
+		return lines, nil
+	}
+
+	switch {
+	case t.next || !os.SameFile(t.info, current):
+		if len(t.partial) > 0 {
+			lines = append(lines, t.emit(t.partial, t.offset+int64(len(t.partial))))
+		}
+
+		t.next = false
+		if err := t.open(t.Path, 0); err != nil {
+			return lines, err
+		}
+
+		more, err := t.drain()
+		return append(lines, more...), err
+
+	case current.Size() < t.offset+int64(len(t.partial)):
+		fmt.Printf("%s was truncated, reading from the start\n", t.Path)
+		if err := t.open(t.Path, 0); err != nil {
+			return lines, err
+		}
+
+		more, err := t.drain()
+		return append(lines, more...), err
+	}
+
+	return lines, nil
+}
+
+func (t *Tailer) drain() ([]Line, error) {
+	var lines []Line
+	buffer := make([]byte, 64*1024)
+
+	for {
+		n, err := t.file.Read(buffer)
+		if n > 0 {
+			t.partial = append(t.partial, buffer[:n]...)
+
+			for {
+				newline := bytes.IndexByte(t.partial, '\n')
+				if newline < 0 {
+					break
+				}
+
+				end := t.offset + int64(newline) + 1
+				lines = append(lines, t.emit(bytes.TrimSuffix(t.partial[:newline], []byte("\r")), end))
+				t.partial = t.partial[newline+1:]
+			}
+
+			if len(t.partial) > maxLineLength {
+				lines = append(lines, t.emit(t.partial, t.offset+int64(len(t.partial))))
+				t.partial = nil
+			}
+		}
+
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, fmt.Errorf("failed to read %s: %v", t.Path, err)
+		}
+	}
+}
+
+func (t *Tailer) emit(text []byte, end int64) Line {
+	line := Line{Text: string(text), File: t.id, Offset: end}
+	t.offset = end
+	return line
+}
+
+func (t *Tailer) Close() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Close()
+}
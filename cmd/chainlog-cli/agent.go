@@ -0,0 +1,278 @@
+package main
+
+import (
+	"chainlog/agent"
+	"chainlog/core"
+	"chainlog/crypto"
+	"chainlog/sdk"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+)
+
+func handleAgent() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli agent [tail|syslog]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  tail <path> [options]  - Follow a log file and anchor new lines in batches")
+		fmt.Println("  syslog [options]       - Receive syslog messages and anchor them in batches")
+		return
+	}
+
+	switch os.Args[2] {
+	case "tail":
+		handleAgentTail()
+	case "syslog":
+		handleAgentSyslog()
+	default:
+		fmt.Println("Usage: chainlog-cli agent [tail|syslog]")
+	}
+}
+
+func handleAgentTail() {
+	flags := flag.NewFlagSet("agent tail", flag.ContinueOnError)
+	batchSize := flags.Int("batch", agent.DefaultBatchSize, "maximum lines per batch transaction")
+	interval := flags.Duration("interval", agent.DefaultFlushInterval, "submit a partial batch after this long")
+	fee := flags.Uint64("fee", 1, "fee per batch transaction (1-5 LogCoins)")
+	walletAddress := flags.String("wallet", "", "wallet address used to sign batches")
+
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli agent tail <path> [options]")
+		fmt.Println("\nOptions:")
+		flags.SetOutput(os.Stdout)
+		flags.PrintDefaults()
+		return
+	}
+
+	if err := flags.Parse(os.Args[4:]); err != nil {
+		return
+	}
+
+	if *batchSize < 1 {
+		fmt.Println("Batch size must be at least 1")
+		return
+	}
+
+	path, err := filepath.Abs(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	wallet, err := loadSigningWallet(*walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	submit := func(lines []string, sealed func(string) error) (string, error) {
+		batch := sdk.NewBatch(path)
+		for _, line := range lines {
+			batch.Add(line)
+		}
+
+		txID, err := submitBatch(batch, wallet, *fee, sealed)
+		if err == nil {
+			fmt.Printf("Anchored %d lines in batch %s\n", len(lines), txID[:16])
+		}
+		return txID, err
+	}
+
+	lookup := func(txID string) bool {
+		if err := ledger.LoadBlockchain(); err != nil {
+			return false
+		}
+		tx, _ := bc.FindTransaction(txID)
+		return tx != nil
+	}
+
+	tail, err := agent.NewAgent(path, submit, lookup)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	tail.BatchSize = *batchSize
+	tail.FlushInterval = *interval
+
+	fmt.Printf("Tailing %s\n", path)
+	fmt.Printf("├─ Wallet: %s\n", wallet.GetAddressShort())
+	fmt.Printf("├─ Batch size: %d lines\n", tail.BatchSize)
+	fmt.Printf("├─ Flush interval: %s\n", tail.FlushInterval)
+	fmt.Printf("└─ Resuming at: offset %d (%d lines anchored)\n", tail.Checkpoint.Offset, tail.Checkpoint.Lines)
+	fmt.Println("Agent is running... (Ctrl+C to stop)")
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("\nStopping agent, flushing pending lines...")
+		close(stop)
+	}()
+
+	if err := tail.Run(stop); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+
+	batches, lines, pending := tail.Stats()
+	fmt.Printf("Agent stopped: %d lines in %d batches this session, %d unsubmitted\n", lines, batches, pending)
+}
+
+// submitBatch seals batch and adds it to the mempool. If sealed is not nil
+// it is called with the transaction's ID just before the transaction enters
+// the mempool.
+func submitBatch(batch *sdk.Batch, wallet *crypto.Wallet, fee uint64, sealed func(string) error) (string, error) {
+	if err := ledger.LoadBlockchain(); err != nil {
+		return "", fmt.Errorf("could not reload blockchain: %v", err)
+	}
+
+	if batch.Stream != "" {
+		batch.Sequence = bc.NextSequence(batch.Stream, wallet.GetAddress())
+	}
+
+	tx, err := batch.Seal(wallet, fee)
+	if err != nil {
+		return "", err
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		return "", fmt.Errorf("transaction rejected by validator")
+	}
+
+	if err := batch.Save(); err != nil {
+		return "", err
+	}
+
+	if sealed != nil {
+		if err := sealed(tx.ID); err != nil {
+			return "", err
+		}
+	}
+
+	bc.AddTransaction(tx)
+	if err := ledger.SaveBlockchain(); err != nil {
+		return "", err
+	}
+
+	queueBroadcast(tx.ID)
+	return tx.ID, nil
+}
+
+func handleAgentSyslog() {
+	flags := flag.NewFlagSet("agent syslog", flag.ContinueOnError)
+	udpAddress := flags.String("udp", ":5514", "UDP listen address (empty to disable)")
+	tcpAddress := flags.String("tcp", "", "TCP listen address (empty to disable)")
+	facilities := flags.String("facility", "", "comma-separated facilities to anchor (default: all)")
+	severity := flags.String("severity", "debug", "anchor messages at this severity or more severe")
+	schemaID := flags.String("schema", "", "registered schema the entries must match")
+	batchSize := flags.Int("batch", agent.DefaultBatchSize, "maximum entries per batch transaction")
+	interval := flags.Duration("interval", agent.DefaultFlushInterval, "submit a partial batch after this long")
+	fee := flags.Uint64("fee", 1, "fee per batch transaction (1-5 LogCoins)")
+	walletAddress := flags.String("wallet", "", "wallet address used to sign batches")
+
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		return
+	}
+
+	if *batchSize < 1 {
+		fmt.Println("Batch size must be at least 1")
+		return
+	}
+
+	filter, err := agent.NewSyslogFilter(*facilities, *severity)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	var schema *core.Schema
+	if *schemaID != "" {
+		schema, _, err = bc.GetSchema(*schemaID)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+
+	wallet, err := loadSigningWallet(*walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	batcher := agent.NewBatcher(func(group string, entries []string) (string, error) {
+		batch := sdk.NewBatch("syslog")
+		batch.SchemaID = *schemaID
+		for _, entry := range entries {
+			batch.Add(entry)
+		}
+		return submitBatch(batch, wallet, *fee, nil)
+	})
+	batcher.BatchSize = *batchSize
+	batcher.FlushInterval = *interval
+
+	var filtered, rejected uint64
+	server := agent.NewSyslogServer(*udpAddress, *tcpAddress, func(msg *agent.SyslogMessage) {
+		if !filter.Allows(msg) {
+			atomic.AddUint64(&filtered, 1)
+			return
+		}
+
+		entry := msg.Entry()
+		if schema != nil {
+			if err := schema.Validate(entry); err != nil {
+				fmt.Printf("Dropping message from %s: %v\n", msg.Source, err)
+				atomic.AddUint64(&rejected, 1)
+				return
+			}
+		}
+
+		data, err := core.EncodePayload(entry, core.EncodingJSON)
+		if err != nil {
+			atomic.AddUint64(&rejected, 1)
+			return
+		}
+		batcher.Add("", data)
+	})
+
+	if err := server.Start(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Syslog receiver started\n")
+	fmt.Printf("├─ Wallet: %s\n", wallet.GetAddressShort())
+	fmt.Printf("├─ Severity: %s and above\n", agent.SeverityName(filter.MaxSeverity))
+	if *facilities != "" {
+		fmt.Printf("├─ Facilities: %s\n", *facilities)
+	}
+	if schema != nil {
+		fmt.Printf("├─ Schema: %s\n", schema.ID)
+	}
+	fmt.Printf("└─ Batch size: %d entries every %s\n", batcher.BatchSize, batcher.FlushInterval)
+	fmt.Println("Agent is running... (Ctrl+C to stop)")
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Println("\nStopping syslog receiver, flushing pending entries...")
+		server.Stop()
+		close(stop)
+	}()
+
+	if err := batcher.Run(stop); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+
+	received, invalid := server.Stats()
+	batches, sent, pending, dropped := batcher.Stats()
+	fmt.Printf("Agent stopped: %d received, %d unparseable, %d filtered, %d rejected\n",
+		received, invalid, atomic.LoadUint64(&filtered), atomic.LoadUint64(&rejected))
+	fmt.Printf("   Anchored %d entries in %d batches, %d unsubmitted, %d dropped\n", sent, batches, pending, dropped)
+}
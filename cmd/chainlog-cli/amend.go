@@ -0,0 +1,181 @@
+package main
+
+import (
+	"chainlog/core"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+func handleAmend() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli amend <tx_id> <data> [fee] [wallet_address]")
+		fmt.Println("\nThe amendment supersedes the referenced entry. Only the original")
+		fmt.Println("sender or one of its delegates may amend an entry.")
+		return
+	}
+
+	referenced, block := bc.FindTransactionByPrefix(os.Args[2])
+	if referenced == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[2])
+		return
+	}
+
+	if block == nil {
+		fmt.Printf("Transaction %s... is still pending; only confirmed entries can be amended\n", referenced.ID[:16])
+		return
+	}
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewAmendmentTransaction(referenced.ID, os.Args[3], wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating amendment: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Amendment rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Amendment created successfully!\n\n")
+	fmt.Printf("Amendment Details:\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Amends: %s\n", referenced.ID)
+	fmt.Printf("├─ Data: %.50s\n", tx.Data)
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleHistory() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli history <tx_id>")
+		return
+	}
+
+	tx, _ := bc.FindTransactionByPrefix(os.Args[2])
+	if tx == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[2])
+		return
+	}
+
+	history, err := bc.ResolveRevisions(tx.ID)
+	if err != nil {
+		fmt.Printf("Error resolving revisions: %v\n", err)
+		return
+	}
+
+	current := history.Current()
+
+	fmt.Printf("\nREVISION HISTORY (%d revisions)\n", len(history.Revisions))
+	fmt.Println("═══════════════════════════════════════════════════")
+	displayRevision("Original", history.Original, current)
+	for i, revision := range history.Revisions {
+		fmt.Println("   │")
+		displayRevision(fmt.Sprintf("Revision %d", i+1), revision, current)
+	}
+
+	fmt.Printf("\nEffective version: %s\n", current.Transaction.ID)
+	fmt.Printf("Effective data: %s\n", current.Transaction.Data)
+}
+
+func displayRevision(label string, revision *core.Revision, current *core.Revision) {
+	tx := revision.Transaction
+
+	marker := ""
+	if revision == current {
+		marker = " [CURRENT]"
+	}
+
+	fmt.Printf("%s%s:\n", label, marker)
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ By: %s\n", tx.Sender)
+	fmt.Printf("├─ Data: %.50s\n", tx.Data)
+	if revision.Block != nil {
+		fmt.Printf("└─ Confirmed: block %d at %s\n", revision.Block.Index,
+			time.Unix(revision.Block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Printf("└─ Status: Pending\n")
+	}
+}
+
+func handleDelegate() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli delegate [grant|revoke] <address> [fee] [wallet_address]")
+		fmt.Println("\nA delegate may amend entries created by the delegating wallet.")
+		return
+	}
+
+	action := os.Args[2]
+	delegate := os.Args[3]
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewDelegationTransaction(delegate, action, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating delegation: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Delegation rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Delegation %s for %s... created (tx %s...)\n", action, delegate[:8], tx.ID[:16])
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func parseOptionalFee(argIndex int) (uint64, error) {
+	if len(os.Args) <= argIndex {
+		return 1, nil
+	}
+	return strconv.ParseUint(os.Args[argIndex], 10, 64)
+}
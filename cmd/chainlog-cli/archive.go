@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"chainlog/core"
+	"chainlog/sdk"
+	"chainlog/storage"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func handleChainExport() {
+	flags := flag.NewFlagSet("chain export", flag.ContinueOnError)
+	from := flags.Int64("from", 0, "first block height to export")
+	to := flags.Int64("to", -1, "last block height to export (default: tip)")
+	format := flags.String("format", sdk.ArchiveJSONL, "archive format: jsonl or cbor")
+	output := flags.String("output", "", "file to write (default: chainlog-<from>-<to>.<format>)")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		return
+	}
+
+	if *to < 0 {
+		*to = int64(bc.GetBlockCount() - 1)
+	}
+	archive, err := sdk.NewArchive(bc.Chain, *from, *to, *format)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := archive.Write(&buf); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("chainlog-%d-%d.%s", *from, *to, *format)
+	}
+	if err := storage.WriteFileAtomic(path, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("Error writing archive: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Chain archive written to %s (%d bytes)\n", path, buf.Len())
+	displayArchiveManifest(archive.Manifest)
+}
+
+func handleChainImport() {
+	flags := flag.NewFlagSet("chain import", flag.ContinueOnError)
+	verifyOnly := flags.Bool("verify-only", false, "check the archive without importing it")
+	genesis := flags.String("genesis", "", "hash of the archive's genesis block, to replace a local chain that has only its own genesis")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		return
+	}
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: chainlog-cli chain import [--verify-only] [--genesis hash] <file>")
+		return
+	}
+	if running, ok := storage.RunningNode(); ok && !*verifyOnly {
+		fmt.Printf("Stop the node first (PID %d) - an import changes its saved chain\n", running.PID)
+		return
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	archive, err := sdk.ReadArchive(file)
+	file.Close()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err := archive.Verify(); err != nil {
+		fmt.Printf("Archive verification failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Chain archive verified: every block and transaction checks out\n")
+	displayArchiveManifest(archive.Manifest)
+	if *verifyOnly {
+		return
+	}
+
+	bc = newBlockchain()
+	state = storage.NewStateManager()
+	ledger = storage.NewLedgerManager(bc)
+	ledger.AttachState(state)
+	ledger.LoadBlockchain()
+	state.LoadState()
+
+	added, err := importBlocks(archive, *genesis)
+	if err != nil {
+		fmt.Printf("Import failed: %v\n", err)
+		return
+	}
+	if added == 0 {
+		fmt.Println("Nothing to import: the local chain already has every block in the archive")
+		return
+	}
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Error saving blockchain: %v\n", err)
+		return
+	}
+	updateSearchIndex()
+	fmt.Printf("Imported %d blocks, chain height is now %d\n", added, bc.GetBlockCount())
+}
+
+// importBlocks appends the archived blocks the local chain doesn't have yet.
+// Blocks it already has must match. A chain that is only a locally created
+// genesis block is replaced by the archive's, but only if genesis names
+// the archive's genesis block: the archive alone cannot show which chain
+// it belongs to.
+func importBlocks(archive *sdk.Archive, genesis string) (int, error) {
+	m := archive.Manifest
+	if m.From > int64(bc.GetBlockCount()) {
+		return 0, fmt.Errorf("archive starts at block %d but the local chain ends at block %d", m.From, bc.GetBlockCount()-1)
+	}
+	if genesis != "" && genesis != m.GenesisHash {
+		return 0, fmt.Errorf("archive belongs to the chain with genesis %.16s..., not %.16s...", m.GenesisHash, genesis)
+	}
+	if m.From == 0 && bc.GetBlockCount() == 1 && bc.Chain[0].Hash != m.GenesisHash {
+		if genesis == "" {
+			return 0, fmt.Errorf("archive starts from another genesis block (%.16s...); pass --genesis with the hash you trust to replace the local one", m.GenesisHash)
+		}
+		fmt.Printf("Replacing the local genesis block with the archive's\n")
+		bc.Chain = []*core.Block{}
+	}
+
+	validator := core.NewValidator(bc)
+	confirmed := make(map[string]bool)
+	added := 0
+	for _, block := range archive.Blocks {
+		if block.Index < int64(bc.GetBlockCount()) {
+			if bc.Chain[block.Index].Hash != block.Hash {
+				return 0, fmt.Errorf("block %d in the archive differs from the local chain", block.Index)
+			}
+			continue
+		}
+		if block.Index > 0 && !validator.ValidateBlock(block) {
+			return 0, fmt.Errorf("block %d does not extend the local chain", block.Index)
+		}
+		if err := validateBlockTransactions(validator, block); err != nil {
+			return 0, err
+		}
+
+		bc.AppendBlock(block)
+		for _, tx := range block.Transactions {
+			confirmed[tx.ID] = true
+		}
+		added++
+	}
+
+	pending := bc.PendingTx[:0]
+	for _, tx := range bc.PendingTx {
+		if !confirmed[tx.ID] {
+			pending = append(pending, tx)
+		}
+	}
+	bc.PendingTx = pending
+	return added, nil
+}
+
+// validateBlockTransactions checks each transaction of block against the
+// rules of the chain so far, with the transactions before it in the block
+// standing in for the mempool. The node's mempool limits and its refusal of
+// new version 0 transactions are admission policy and do not apply.
+func validateBlockTransactions(validator *core.Validator, block *core.Block) error {
+	pending := bc.PendingTx
+	defer func() { bc.PendingTx = pending }()
+
+	bc.PendingTx = nil
+	for _, tx := range block.Transactions {
+		if tx.Type != core.RewardTx && !validator.ValidateTransaction(tx) {
+			return fmt.Errorf("transaction %.16s... in block %d is not valid", tx.ID, block.Index)
+		}
+		bc.PendingTx = append(bc.PendingTx, tx)
+	}
+	return nil
+}
+
+func displayArchiveManifest(m *sdk.ArchiveManifest) {
+	fmt.Printf("├─ Format: %s v%d (%s)\n", m.Format, m.Version, m.Encoding)
+	fmt.Printf("├─ Created: %s\n", time.Unix(m.CreatedAt, 0).Format("2006-01-02 15:04:05"))
+	fmt.Printf("├─ Genesis: %s\n", m.GenesisHash)
+	fmt.Printf("├─ Blocks: %d-%d (%d blocks)\n", m.From, m.To, m.Blocks)
+	fmt.Printf("├─ Transactions: %d\n", m.Transactions)
+	fmt.Printf("├─ Tip: %s\n", m.TipHash)
+	fmt.Printf("└─ Content Hash: %s\n", m.ContentHash)
+}
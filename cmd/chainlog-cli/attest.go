@@ -0,0 +1,135 @@
+package main
+
+import (
+	"chainlog/core"
+	"fmt"
+	"os"
+	"time"
+)
+
+func handleAttest() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli attest <tx_id> [note] [fee] [wallet_address]")
+		fmt.Println("\nExamples:")
+		fmt.Println("  chainlog-cli attest 3f9a2c1b")
+		fmt.Println("  chainlog-cli attest 3f9a2c1b \"Reviewed by compliance\" 2")
+		return
+	}
+
+	referenced, block := bc.FindTransactionByPrefix(os.Args[2])
+	if referenced == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[2])
+		return
+	}
+
+	if block == nil {
+		fmt.Printf("Transaction %s... is still pending; only confirmed entries can be attested\n", referenced.ID[:16])
+		return
+	}
+
+	note := ""
+	if len(os.Args) >= 4 {
+		note = os.Args[3]
+	}
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("   Create a wallet first: chainlog-cli wallet create")
+		return
+	}
+
+	tx, err := core.NewAttestationTransaction(referenced.ID, note, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating attestation: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Attestation rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Attestation created successfully!\n\n")
+	fmt.Printf("Attestation Details:\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Witness: %s\n", wallet.GetAddressShort())
+	fmt.Printf("├─ Attests: %s (block %d)\n", referenced.ID, block.Index)
+	if note != "" {
+		fmt.Printf("├─ Note: %s\n", note)
+	}
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleAttestations() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli attestations <tx_id>")
+		return
+	}
+
+	referenced, block := bc.FindTransactionByPrefix(os.Args[2])
+	if referenced == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[2])
+		return
+	}
+
+	attestations := bc.GetAttestations(referenced.ID)
+
+	fmt.Printf("Attestations for %s...\n", referenced.ID[:16])
+	if block != nil {
+		fmt.Printf("Entry confirmed in block %d at %s\n\n", block.Index,
+			time.Unix(block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Printf("Entry is still pending\n\n")
+	}
+
+	if len(attestations) == 0 {
+		fmt.Println("No attestations found")
+		return
+	}
+
+	for i, attestation := range attestations {
+		tx := attestation.Transaction
+		fmt.Printf("%d. %s\n", i+1, tx.ID)
+		fmt.Printf("   Witness: %s\n", tx.Sender)
+		if tx.Data != "" {
+			fmt.Printf("   Note: %.50s\n", tx.Data)
+		}
+
+		signature := "valid"
+		if err := tx.VerifySignature(); err != nil {
+			signature = "INVALID (" + err.Error() + ")"
+		}
+		fmt.Printf("   Signature: %s\n", signature)
+
+		if attestation.IsConfirmed() {
+			fmt.Printf("   Status: Confirmed in block %d\n", attestation.Block.Index)
+		} else {
+			fmt.Printf("   Status: Pending\n")
+		}
+
+		if i < len(attestations)-1 {
+			fmt.Println("   ──────────────────────────────────")
+		}
+	}
+}
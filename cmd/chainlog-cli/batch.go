@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"chainlog/core"
+	"chainlog/sdk"
+	"chainlog/storage"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func handleBatch() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli batch [submit|list|prove|verify]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  submit <file> [fee] [wallet_address]  - Anchor every line of a file in one transaction")
+		fmt.Println("  list                                  - List batches with locally stored leaves")
+		fmt.Println("  prove <tx_id> <line> [proof_file]     - Build an inclusion proof for one line")
+		fmt.Println("  verify <proof_file>                   - Check an inclusion proof against the chain")
+		return
+	}
+
+	switch os.Args[2] {
+	case "submit":
+		handleBatchSubmit()
+	case "list":
+		handleBatchList()
+	case "prove":
+		handleBatchProve()
+	case "verify":
+		handleBatchVerify()
+	default:
+		fmt.Println("Usage: chainlog-cli batch [submit|list|prove|verify]")
+	}
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return lines, nil
+}
+
+func handleBatchSubmit() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli batch submit <file> [fee] [wallet_address]")
+		fmt.Println("\nEach line becomes a leaf of a Merkle tree and only the root goes on-chain.")
+		fmt.Println("The leaves are kept locally so any line can be proven later.")
+		return
+	}
+
+	path := os.Args[3]
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	lines, err := readLines(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	batch := sdk.NewBatch(path)
+	for _, line := range lines {
+		batch.Add(line)
+	}
+
+	tx, err := batch.Seal(wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	if err := batch.Save(); err != nil {
+		fmt.Printf("Error saving batch leaves: %v\n", err)
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Batch created successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Source: %s\n", path)
+	fmt.Printf("├─ Lines: %d\n", batch.Len())
+	fmt.Printf("├─ Merkle Root: %s\n", batch.Root)
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleBatchList() {
+	ids, err := storage.GetAllBatchIDs()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(ids) == 0 {
+		fmt.Println("No batches found")
+		fmt.Println("   Submit one with: chainlog-cli batch submit <file>")
+		return
+	}
+
+	fmt.Printf("Batches (%d):\n\n", len(ids))
+	for _, id := range ids {
+		batch, err := sdk.LoadBatch(id)
+		if err != nil {
+			fmt.Printf("%s\n   Error: %v\n", id, err)
+			continue
+		}
+
+		status := "Pending"
+		if tx, block := bc.FindTransaction(id); tx == nil {
+			status = "Not found on chain"
+		} else if block != nil {
+			status = fmt.Sprintf("Confirmed in block %d", block.Index)
+		}
+
+		fmt.Printf("%s\n", id)
+		fmt.Printf("   Source: %s\n", batch.Source)
+		fmt.Printf("   Lines: %d\n", batch.Len())
+		fmt.Printf("   Status: %s\n", status)
+	}
+}
+
+func handleBatchProve() {
+	if len(os.Args) < 5 {
+		fmt.Println("Usage: chainlog-cli batch prove <tx_id> <line> [proof_file]")
+		fmt.Println("\nLines are numbered from 1, as in the submitted file.")
+		return
+	}
+
+	tx, _ := bc.FindTransactionByPrefix(os.Args[3])
+	if tx == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[3])
+		return
+	}
+
+	line, err := strconv.Atoi(os.Args[4])
+	if err != nil || line < 1 {
+		fmt.Printf("Invalid line number: %s\n", os.Args[4])
+		return
+	}
+
+	batch, err := sdk.LoadBatch(tx.ID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	proof, err := batch.Prove(line-1, bc)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := proof.Verify(bc.GetLastBlock().Hash); err != nil {
+		fmt.Printf("Generated proof failed verification: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Inclusion proof for line %d:\n\n", line)
+	fmt.Printf("├─ Entry: %s\n", proof.Entry)
+	fmt.Printf("├─ Leaf: %s\n", proof.EntryProof.Leaf)
+	fmt.Printf("├─ Batch Root: %s (%d steps)\n", tx.Data, len(proof.EntryProof.Path))
+	fmt.Printf("├─ Transaction: %s (%d steps)\n", tx.ID, len(proof.TxProof.Path))
+	fmt.Printf("├─ Block Merkle Root: %s\n", proof.Header.MerkleRoot)
+	fmt.Printf("├─ Block: %d (%s)\n", proof.Header.Index, proof.Header.Hash)
+	fmt.Printf("└─ Linked To: block %d (%d headers)\n", proof.Tip().Index, len(proof.BlockProof.Headers))
+
+	if len(os.Args) >= 6 {
+		if err := proof.SaveToFile(os.Args[5]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("\nProof written to: %s\n", os.Args[5])
+	}
+}
+
+func handleBatchVerify() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli batch verify <proof_file>")
+		return
+	}
+
+	proof, err := sdk.LoadProofFromFile(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tip := proof.Tip()
+	if tip == nil || tip.Index < 0 || tip.Index >= int64(len(bc.Chain)) {
+		fmt.Printf("INVALID: the proof does not reach a block in the local chain\n")
+		return
+	}
+
+	if err := proof.Verify(bc.Chain[tip.Index].Hash); err != nil {
+		fmt.Printf("INVALID: %v\n", err)
+		return
+	}
+	height := proof.Header.Index
+
+	fmt.Printf("VALID\n")
+	fmt.Printf("├─ Entry: %s\n", proof.Entry)
+	fmt.Printf("├─ Line: %d\n", proof.EntryIndex+1)
+	fmt.Printf("├─ Batch: %s\n", proof.Transaction.ID)
+	fmt.Printf("├─ Signed by: %s\n", proof.Transaction.Sender)
+	fmt.Printf("└─ Block: %d (%s)\n", height, proof.Header.Hash)
+}
@@ -1,19 +1,26 @@
 package main
 
 import (
+	"chainlog/agent"
 	"chainlog/core"
 	"chainlog/crypto"
 	"chainlog/network"
 	"chainlog/consensus"
 	"chainlog/economy"
 	"chainlog/storage"
+	"flag"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
+const nodeShutdownTimeout = 15 * time.Second
+
 var (
 	bc     *core.Blockchain
 	state  *storage.StateManager
@@ -22,20 +29,46 @@ var (
 )
 
 func startNode() {
-	port := "8080"
-	if len(os.Args) > 2 {
-		port = os.Args[2]
+	port := ""
+	args := os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		port = args[0]
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("start", flag.ContinueOnError)
+	cfg.BindFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
+	if port != "" {
+		host, _, _ := net.SplitHostPort(cfg.Node.Listen)
+		cfg.Node.Listen = net.JoinHostPort(host, port)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		return
 	}
+	_, port, _ = net.SplitHostPort(cfg.Node.Listen)
+	storage.SetPruning(cfg.Prune)
+
+	level, _ := network.ParseLogLevel(cfg.Log.Level)
+	network.SetLogLevel(level)
 
-	if err := storage.SaveNodeState(port); err != nil {
-		fmt.Printf("Warning: Could not save node state: %v\n", err)
+	lock, err := storage.AcquireNodeLock(port)
+	if err != nil {
+		fmt.Printf("Cannot start node: %v\n", err)
+		return
 	}
+	defer lock.Release()
 
-	fmt.Printf("Starting ChainLog node on port %s...\n", port)
+	fmt.Printf("Starting ChainLog node on %s...\n", cfg.Node.Listen)
 	
-	bc = core.NewBlockchain()
+	bc = newBlockchain()
 	state = storage.NewStateManager()
 	ledger = storage.NewLedgerManager(bc)
+	ledger.AttachState(state)
 
 	if err := ledger.LoadBlockchain(); err != nil {
 		fmt.Printf("Starting fresh blockchain: %v\n", err)
@@ -46,25 +79,162 @@ func startNode() {
 
 	wallet, err := loadOrCreateWallet()
 	if err != nil {
-		storage.DeleteNodeState() 
+		lock.Release()
 		panic(err)
 	}
 
-	node = network.NewNode("localhost:"+port, wallet, bc, true)
+	node = network.NewNode(cfg.Node.Listen, wallet, bc, cfg.Mining.Enabled)
 
 	go node.CheckBroadcastFile()
+
+	stop := make(chan struct{})
+	var workers []<-chan struct{}
+	var receiver *agent.OTLPReceiver
+
+	if cfg.RPC.OTLP != "" {
+		var done <-chan struct{}
+		receiver, done, err = startOTLPReceiver(cfg.RPC.OTLP, wallet, stop)
+		if err != nil {
+			fmt.Printf("Warning: Could not start OTLP receiver: %v\n", err)
+		} else {
+			workers = append(workers, done)
+		}
+	}
 	
 	if err := node.Start(); err != nil {
-		storage.DeleteNodeState()
+		lock.Release()
 		panic(err)
 	}
 
+	if len(cfg.Node.Bootstrap) > 0 {
+		go node.Bootstrap(cfg.Node.Bootstrap)
+	}
+	if cfg.Mining.Enabled {
+		done := make(chan struct{})
+		workers = append(workers, done)
+		go func() {
+			defer close(done)
+			runMiner(wallet, stop)
+		}()
+	}
+
 	fmt.Printf("Node started successfully! Address: %s\n", wallet.GetAddress())
 	fmt.Println("Node is running... (Ctrl+C to stop)")
 
-	defer storage.DeleteNodeState()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	fmt.Printf("\nShutting down node (waiting up to %s)...\n", nodeShutdownTimeout)
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		if receiver != nil {
+			receiver.Stop()
+		}
+		node.Stop()
+		close(stop)
+		for _, done := range workers {
+			<-done
+		}
+		node.WithChain(func() {
+			if err := flushNode(); err != nil {
+				fmt.Printf("Warning: Could not save node data: %v\n", err)
+			}
+		})
+	}()
+
+	select {
+	case <-finished:
+		fmt.Println("Node stopped")
+	case <-signals:
+		fmt.Println("Interrupted again, exiting without finishing shutdown")
+		lock.Release()
+		os.Exit(1)
+	case <-time.After(nodeShutdownTimeout):
+		fmt.Printf("Shutdown did not finish within %s, exiting\n", nodeShutdownTimeout)
+		lock.Release()
+		os.Exit(1)
+	}
+}
+
+// flushNode saves what only the node holds in memory: blocks received from
+// peers that extend the saved chain and transactions received into its
+// mempool. The saved chain is reloaded first, since other commands write
+// to it while the node runs. The caller holds the node's chain lock.
+func flushNode() error {
+	chain := bc.Chain
+	pending := bc.PendingTx
 
-	select {}
+	if err := ledger.LoadBlockchain(); err != nil {
+		fmt.Printf("Saving the node's chain: %v\n", err)
+	}
+
+	saved := len(bc.Chain)
+	if len(chain) > saved && chain[saved-1].Hash == bc.GetLastBlock().Hash {
+		for _, block := range chain[saved:] {
+			bc.AppendBlock(block)
+		}
+	}
+	for _, tx := range pending {
+		if found, _ := bc.FindTransaction(tx.ID); found == nil {
+			bc.PendingTx = append(bc.PendingTx, tx)
+		}
+	}
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		return err
+	}
+	return state.SaveState()
+}
+
+// runMiner mines whatever other processes have queued in the saved mempool
+// and announces each new block to the node's peers. It holds the node's
+// chain lock from the reload through the save.
+func runMiner(wallet *crypto.Wallet, stop <-chan struct{}) {
+	address := cfg.Mining.Address
+	if address == "" {
+		address = wallet.GetAddress()
+	}
+	fmt.Printf("Mining enabled, rewards go to %s\n", address)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if pending, err := ledger.PendingCount(); err != nil || pending == 0 {
+				continue
+			}
+
+			var block *core.Block
+			node.WithChain(func() {
+				if err := ledger.LoadBlockchain(); err != nil {
+					fmt.Printf("Miner: could not reload blockchain: %v\n", err)
+					return
+				}
+				if err := state.LoadState(); err != nil {
+					fmt.Printf("Miner: could not reload state: %v\n", err)
+					return
+				}
+
+				mined, err := mineBlock(wallet)
+				if err != nil {
+					fmt.Printf("Miner: %v\n", err)
+					return
+				}
+				block = mined
+			})
+			if block == nil {
+				continue
+			}
+			fmt.Printf("Mined block %d (%d transactions)\n", block.Index, len(block.Transactions))
+			node.BroadcastBlock(block)
+		}
+	}
 }
 
 func handleWallet() {
@@ -103,7 +273,7 @@ func handleWallet() {
 		fmt.Printf("Wallet created and saved successfully!\n\n")
 		wallet.Display()
 		fmt.Printf("\nLabel: %s\n", label)
-		fmt.Printf("Storage: %s\n", storage.DataDir+"/"+storage.WalletsFile)
+		fmt.Printf("Storage: %s\n", storage.Path(storage.WalletsFile))
 		fmt.Printf("Total wallets: %d\n", wm.WalletCount())
 
 	case "import":
@@ -187,7 +357,7 @@ func handleWallet() {
 		fmt.Printf("├─ Address: %s\n", wallet.Address)
 		fmt.Printf("├─ Label: %s\n", stored.Label)
 		fmt.Printf("├─ Created: %s\n", time.Unix(stored.CreatedAt, 0).Format("2006-01-02 15:04:05"))
-		fmt.Printf("└─ Public Key: %s...\n", stored.PublicKey[:16])
+		fmt.Printf("└─ Public Key: %s\n", crypto.PublicKeyToString(wallet.PublicKey))
 
 	case "default":
 		wallet, err := storage.GetDefaultWallet()
@@ -210,9 +380,12 @@ func handleWallet() {
 
 func handleTransaction() {
 	if len(os.Args) < 3 {
-		fmt.Println("Usage: chainlog-cli transaction [create|list|broadcast|status]")
+		fmt.Println("Usage: chainlog-cli transaction [create|structured|encrypt|read|list|broadcast|status]")
 		fmt.Println("\nCommands:")
 		fmt.Println("  create <data> <fee> [wallet_address] - Create new transaction")
+		fmt.Println("  structured <schema_id> <json> <fee>  - Create a schema-validated transaction")
+		fmt.Println("  encrypt <data> <fee> <recipients>    - Create a transaction readable only by recipients")
+		fmt.Println("  read <tx_id> [wallet_address]        - Show (and decrypt) a transaction's data")
 		fmt.Println("  list                                 - List pending transactions")
 		fmt.Println("  broadcast <tx_id>                    - Broadcast transaction")
 		fmt.Println("  status <tx_id>                       - Check transaction status")
@@ -222,6 +395,12 @@ func handleTransaction() {
 	switch os.Args[2] {
 	case "create":
 		handleTransactionCreate()
+	case "structured":
+		handleTransactionStructured()
+	case "encrypt":
+		handleTransactionEncrypt()
+	case "read":
+		handleTransactionRead()
 	case "list":
 		handleTransactionList()
 	case "broadcast":
@@ -229,7 +408,7 @@ func handleTransaction() {
 	case "status":
 		handleTransactionStatus()
 	default:
-		fmt.Println("Usage: chainlog-cli transaction [create|list|broadcast|status]")
+		fmt.Println("Usage: chainlog-cli transaction [create|structured|encrypt|read|list|broadcast|status]")
 	}
 }
 
@@ -273,6 +452,11 @@ func handleTransactionCreate() {
 		return
 	}
 
+	if err := bc.CheckMempoolLimits(tx); err != nil {
+		fmt.Printf("Transaction rejected: %v\n", err)
+		return
+	}
+
 	bc.AddTransaction(tx)
 
 	fmt.Printf("Transaction created successfully!\n\n")
@@ -290,12 +474,16 @@ func handleTransactionCreate() {
 		}
 	}
 
-	f, err := os.OpenFile("pending_broadcasts.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-if err == nil {
-    defer f.Close()
-    f.WriteString(tx.ID + "\n")
-    fmt.Printf("Transaction queued for broadcast\n")
+	queueBroadcast(tx.ID)
 }
+
+func queueBroadcast(txID string) {
+	f, err := os.OpenFile(storage.Path(storage.BroadcastQueueFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		defer f.Close()
+		f.WriteString(txID + "\n")
+		fmt.Printf("Transaction queued for broadcast\n")
+	}
 }
 
 func handleTransactionList() {
@@ -328,32 +516,26 @@ func handleTransactionStatus() {
 
 	txID := os.Args[3]
 	
-	pending := bc.GetPendingTransactions()
-	for _, tx := range pending {
-		if tx.ID == txID || strings.HasPrefix(tx.ID, txID) {
-			fmt.Printf("Transaction Status:\n")
-			fmt.Printf("├─ ID: %s...\n", tx.ID[:16])
-			fmt.Printf("├─ Status: Pending\n")
-			fmt.Printf("├─ Data: %.50s\n", tx.Data)
-			fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
-			fmt.Printf("└─ Created: %s\n", time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04:05"))
-			return
-		}
+	tx, block := bc.FindTransactionByPrefix(txID)
+	if tx != nil && block == nil {
+		fmt.Printf("Transaction Status:\n")
+		fmt.Printf("├─ ID: %s...\n", tx.ID[:16])
+		fmt.Printf("├─ Status: Pending\n")
+		fmt.Printf("├─ Data: %.50s\n", tx.Data)
+		fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+		fmt.Printf("└─ Created: %s\n", time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04:05"))
+		return
 	}
 
-	for _, block := range bc.Chain {
-		for _, tx := range block.Transactions {
-			if tx.ID == txID || strings.HasPrefix(tx.ID, txID) {
-				fmt.Printf("Transaction Status:\n")
-				fmt.Printf("├─ ID: %s...\n", tx.ID[:16])
-				fmt.Printf("├─ Status: Confirmed\n")
-				fmt.Printf("├─ Block: %d\n", block.Index)
-				fmt.Printf("├─ Data: %.50s\n", tx.Data)
-				fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
-				fmt.Printf("└─ Confirmed: %s\n", time.Unix(block.Timestamp, 0).Format("2006-01-02 15:04:05"))
-				return
-			}
-		}
+	if tx != nil {
+		fmt.Printf("Transaction Status:\n")
+		fmt.Printf("├─ ID: %s...\n", tx.ID[:16])
+		fmt.Printf("├─ Status: Confirmed\n")
+		fmt.Printf("├─ Block: %d\n", block.Index)
+		fmt.Printf("├─ Data: %.50s\n", tx.Data)
+		fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+		fmt.Printf("└─ Confirmed: %s\n", time.Unix(block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+		return
 	}
 
 	fmt.Printf("Transaction not found: %s\n", txID)
@@ -373,7 +555,7 @@ func handleTransactionBroadcast() {
 		return
 	}
 
-	f, err := os.OpenFile("pending_broadcasts.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(storage.Path(storage.BroadcastQueueFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		fmt.Printf("Error creating broadcast file: %v\n", err)
 		return
@@ -399,14 +581,30 @@ func handleMine() {
         return
     }
 
-    miner := consensus.NewMiner(wallet, bc)
-    block, err := miner.MineBlock()
+    block, err := mineBlock(wallet)
     if err != nil {
         fmt.Printf("Mining failed: %v\n", err)
         return
     }
 
-    bc.Chain = append(bc.Chain, block)
+    fmt.Printf("Successfully mined block %d!\n", block.Index)
+    fmt.Printf("Block hash: %s\n", block.Hash[:16])
+}
+
+// mineBlock mines the pending transactions into a new block, paying the
+// reward to the configured mining address or else the wallet, and saves it.
+func mineBlock(wallet *crypto.Wallet) (*core.Block, error) {
+    miner := consensus.NewMiner(wallet, bc)
+    if cfg.Mining.Address != "" {
+        miner.Address = cfg.Mining.Address
+    }
+
+    block, err := miner.MineBlock()
+    if err != nil {
+        return nil, err
+    }
+
+    bc.AppendBlock(block)
     bc.ClearPendingTransactions()
 
     if ledger != nil {
@@ -419,9 +617,9 @@ func handleMine() {
             fmt.Printf("Warning: Could not save state: %v\n", err)
         }
     }
+    updateSearchIndex()
 
-    fmt.Printf("Successfully mined block %d!\n", block.Index)
-    fmt.Printf("Block hash: %s\n", block.Hash[:16])
+    return block, nil
 }
 
 func handleStatus() {
@@ -429,6 +627,9 @@ func handleStatus() {
 	fmt.Printf("├─ Blocks: %d\n", bc.GetBlockCount())
 	fmt.Printf("├─ Pending Transactions: %d\n", len(bc.GetPendingTransactions()))
 	fmt.Printf("├─ Difficulty: %d\n", bc.Difficulty)
+	if pruned := bc.PrunedHeight(); pruned > 0 {
+		fmt.Printf("├─ Pruned: transactions kept from block %d\n", pruned)
+	}
 	fmt.Printf("└─ Valid: %t\n", core.NewValidator(bc).ValidateBlockchain())
 
 	if node != nil {
@@ -462,7 +663,8 @@ func handlePeers() {
     fmt.Println("Peer-to-peer networking not fully implemented yet")
 
 	case "list":
-    if storage.IsNodeRunning() {
+    if running, ok := storage.RunningNode(); ok {
+        fmt.Printf("Node running with PID %d on port %s.\n", running.PID, running.Port)
         fmt.Printf("Peers list unavailable across terminals as currently.\n")
         fmt.Printf("Check the node's terminal output or use the same terminal.\n")
     } else {
@@ -504,9 +706,25 @@ func loadOrCreateWallet() (*crypto.Wallet, error) {
 	return wallet, nil
 }
 
+func loadSigningWallet(address string) (*crypto.Wallet, error) {
+	if address != "" {
+		wallet, err := storage.LoadWalletFromStorage(address)
+		if err != nil {
+			return nil, fmt.Errorf("error loading wallet %.8s: %v", address, err)
+		}
+		return wallet, nil
+	}
+
+	wallet, err := storage.GetDefaultWallet()
+	if err != nil {
+		return nil, fmt.Errorf("no wallet found: %v", err)
+	}
+	return wallet, nil
+}
+
 func handleChain() {
 	if len(os.Args) < 3 {
-		fmt.Println("Usage: chainlog-cli chain [show|validate]")
+		fmt.Println("Usage: chainlog-cli chain [show|validate|export|import]")
 		return
 	}
 
@@ -515,8 +733,12 @@ func handleChain() {
 		handleChainShow()
 	case "validate":
 		handleChainValidate()
+	case "export":
+		handleChainExport()
+	case "import":
+		handleChainImport()
 	default:
-		fmt.Println("Usage: chainlog-cli chain [show|validate]")
+		fmt.Println("Usage: chainlog-cli chain [show|validate|export|import]")
 	}
 }
 
@@ -544,7 +766,11 @@ func handleChainShow() {
         }
         
         fmt.Printf("├─ Timestamp: %s\n", time.Unix(block.Timestamp, 0).Format("2006-01-02 15:04:05"))
-        fmt.Printf("├─ Transactions: %d\n", len(block.Transactions))
+        if block.Pruned {
+            fmt.Printf("├─ Transactions: (pruned)\n")
+        } else {
+            fmt.Printf("├─ Transactions: %d\n", len(block.Transactions))
+        }
         fmt.Printf("├─ Nonce: %d\n", block.Nonce)
         fmt.Printf("└─ Difficulty: %d\n", block.Difficulty)
         
@@ -706,6 +932,35 @@ func handleSave() {
 	}
 }
 
+func handleReindex() {
+	if ledger == nil {
+		ledger = storage.NewLedgerManager(bc)
+	}
+
+	fmt.Println("Rebuilding chain indexes...")
+
+	index, err := ledger.Reindex()
+	if err != nil {
+		fmt.Printf("Failed to rebuild chain index: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Chain index rebuilt:\n")
+	fmt.Printf("├─ Blocks: %d\n", len(index.Blocks))
+	fmt.Printf("├─ Transactions: %d\n", len(index.Transactions))
+	fmt.Printf("├─ Addresses: %d\n", len(index.Addresses))
+	fmt.Printf("└─ Streams: %d\n", len(index.Streams))
+
+	if storage.SearchIndexEnabled() {
+		search, err := storage.RebuildSearchIndex(bc)
+		if err != nil {
+			fmt.Printf("Failed to rebuild search index: %v\n", err)
+			return
+		}
+		fmt.Printf("Search index rebuilt: %d entries, %d terms\n", len(search.Documents), search.Terms())
+	}
+}
+
 func handleLoad() {
 	if ledger == nil {
 		ledger = storage.NewLedgerManager(bc)
@@ -750,8 +1005,8 @@ func handleSummary() {
 	fmt.Printf("└─ Tracked: %d\n", len(state.Accounts))
 	
 	fmt.Printf("Storage:\n")
-	fmt.Printf("├─ Blockchain File: %s\n", storage.BlocksFile)
-	fmt.Printf("├─ State File: %s\n", storage.StateFile)
+	fmt.Printf("├─ Block Store: %s/\n", storage.BlocksDir)
+	fmt.Printf("├─ State Store: %s\n", storage.StateDBFile)
 	fmt.Printf("└─ Wallets File: %s\n", storage.WalletsFile)
 	
 	staking := consensus.NewStakingManager()
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func handleConfig() {
+	if len(os.Args) < 3 || os.Args[2] != "show" {
+		fmt.Println("Usage: chainlog-cli config show")
+		fmt.Println("\nSettings are read from chainlog.toml, then CHAINLOG_* environment")
+		fmt.Println("variables, then flags. Run chainlog-cli start -h for the flags.")
+		return
+	}
+
+	source := "defaults only, no config file found"
+	if cfg.File != "" {
+		source = cfg.File
+	}
+	fmt.Printf("# Effective configuration (%s)\n", source)
+	fmt.Print(cfg.TOML())
+}
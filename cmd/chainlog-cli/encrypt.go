@@ -0,0 +1,188 @@
+package main
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"chainlog/storage"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func handleTransactionEncrypt() {
+	if len(os.Args) < 6 {
+		fmt.Println("Usage: chainlog-cli transaction encrypt <data> <fee> <recipient[,recipient...]> [wallet_address]")
+		fmt.Println("\nRecipients are public keys or stored wallet addresses. The sending")
+		fmt.Println("wallet is always added as a recipient so it can read the entry back.")
+		return
+	}
+
+	data := os.Args[3]
+	fee, err := strconv.ParseUint(os.Args[4], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 7 {
+		walletAddress = os.Args[6]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	recipients := []*ecdsa.PublicKey{wallet.PublicKey}
+	for _, arg := range strings.Split(os.Args[5], ",") {
+		publicKeyHex, err := resolvePublicKey(strings.TrimSpace(arg))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		publicKey, err := crypto.StringToPublicKey(publicKeyHex)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		recipients = append(recipients, publicKey)
+	}
+
+	tx, err := core.NewEncryptedTransaction(data, recipients, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	envelope, _ := tx.Envelope()
+
+	fmt.Printf("Encrypted transaction created successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Algorithm: %s\n", envelope.Algorithm)
+	fmt.Printf("├─ Recipients: %d\n", len(envelope.Recipients))
+	for _, keyID := range envelope.RecipientIDs() {
+		fmt.Printf("│  └─ %s\n", keyID)
+	}
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleTransactionRead() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli transaction read <tx_id> [wallet_address]")
+		return
+	}
+
+	tx, block := bc.FindTransactionByPrefix(os.Args[3])
+	if tx == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[3])
+		return
+	}
+
+	fmt.Printf("Transaction: %s\n", tx.ID)
+	if block != nil {
+		fmt.Printf("Block: %d\n", block.Index)
+	} else {
+		fmt.Printf("Status: Pending\n")
+	}
+
+	if !tx.IsEncrypted() {
+		fmt.Printf("Data: %s\n", tx.Data)
+		return
+	}
+
+	envelope, err := tx.Envelope()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if envelope.IsSubjectKeyed() {
+		readSubjectEntry(envelope)
+		return
+	}
+
+	var candidates []string
+	if len(os.Args) >= 5 {
+		candidates = []string{os.Args[4]}
+	} else {
+		wm := storage.GetWalletManager()
+		for _, keyID := range envelope.RecipientIDs() {
+			if _, exists := wm.GetWallet(keyID); exists {
+				candidates = append(candidates, keyID)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf("No local wallet is a recipient of this entry (%d recipients)\n", len(envelope.Recipients))
+		return
+	}
+
+	for _, address := range candidates {
+		wallet, err := storage.LoadWalletFromStorage(address)
+		if err != nil {
+			fmt.Printf("Error loading wallet %.8s: %v\n", address, err)
+			continue
+		}
+
+		plaintext, err := tx.Decrypt(wallet)
+		if err != nil {
+			fmt.Printf("Wallet %s cannot decrypt: %v\n", wallet.GetAddressShort(), err)
+			continue
+		}
+
+		fmt.Printf("Decrypted with: %s\n", wallet.GetAddressShort())
+		fmt.Printf("Data: %s\n", plaintext)
+		return
+	}
+
+	fmt.Println("Unable to decrypt this entry")
+}
+
+func readSubjectEntry(envelope *crypto.Envelope) {
+	record := bc.GetErasureRecord(envelope.SubjectKeyID)
+	if record.IsErased() {
+		fmt.Printf("Data: ERASED (subject key destroyed in block %d by %s)\n",
+			record.Erasure.Block.Index, record.Erasure.Transaction.ID)
+		return
+	}
+
+	subjectKey, key, exists := storage.GetKeyVault().GetKeyByID(envelope.SubjectKeyID)
+	if !exists {
+		fmt.Printf("Subject key %s is not in the local key vault\n", envelope.SubjectKeyID)
+		return
+	}
+
+	if key == nil {
+		fmt.Printf("Data: ERASED (subject key for %s destroyed locally)\n", subjectKey.Subject)
+		return
+	}
+
+	plaintext, err := envelope.OpenWithKey(key)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Subject: %s\n", subjectKey.Subject)
+	fmt.Printf("Data: %s\n", plaintext)
+}
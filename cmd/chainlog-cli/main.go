@@ -1,13 +1,27 @@
 package main
 
 import (
+	"chainlog/config"
 	"chainlog/core"
 	"chainlog/storage"
 	"fmt"
 	"os"
 )
 
+var cfg *config.Config
+
 func main() {
+	var args []string
+	var err error
+	cfg, args, err = config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	storage.SetDataDir(cfg.DataDir)
+	storage.SetPruning(cfg.Prune)
+	os.Args = append(os.Args[:1], args...)
+
 	if len(os.Args) < 2 {
 		printUsage()
 		return
@@ -18,24 +32,60 @@ func main() {
 		startNode()
 	case "wallet":
 		handleWallet()
+	case "multisig":
+		handleMultisig()
+	case "config":
+		handleConfig()
+	case "snapshot":
+		handleSnapshot()
+	case "chain":
+		// An import rewrites the saved chain, so it runs with the node
+		// stopped; everything else reads the running node's chain.
+		if len(os.Args) > 2 && os.Args[2] == "import" {
+			handleChainImport()
+		} else if loadChainState() {
+			handleChain()
+		}
 	case "help":
 		printUsage()
 	default:
-		if !storage.IsNodeRunning() {
-			fmt.Println("No node is currently running!")
-			fmt.Println("   Start a node first: chainlog-cli start [port]")
+		if !loadChainState() {
 			return
 		}
-		
-		bc = core.NewBlockchain()
-		state = storage.NewStateManager()
-		ledger = storage.NewLedgerManager(bc)
-		ledger.LoadBlockchain() 
-		state.LoadState()       
 
 		switch os.Args[1] {
 		case "transaction":
 			handleTransaction()
+		case "attest":
+			handleAttest()
+		case "attestations":
+			handleAttestations()
+		case "amend":
+			handleAmend()
+		case "history":
+			handleHistory()
+		case "delegate":
+			handleDelegate()
+		case "schema":
+			handleSchema()
+		case "stream":
+			handleStream()
+		case "notarize":
+			handleNotarize()
+		case "verify":
+			handleVerify()
+		case "blob":
+			handleBlob()
+		case "subject":
+			handleSubject()
+		case "batch":
+			handleBatch()
+		case "agent":
+			handleAgent()
+		case "query":
+			handleQuery()
+		case "search":
+			handleSearch()
 		case "mine":
 			handleMine()
 		case "status":
@@ -44,8 +94,6 @@ func main() {
 			handleBalance()
 		case "peers":
 			handlePeers()
-		case "chain":
-			handleChain()
 		case "fees":
 			handleFeesStats()
 		case "rewards":
@@ -58,6 +106,8 @@ func main() {
 			handleSave()
 		case "load":
 			handleLoad()
+		case "reindex":
+			handleReindex()
 		case "summary":
 			handleSummary()
 		default:
@@ -67,18 +117,73 @@ func main() {
 	}
 }
 
+func loadChainState() bool {
+	if !storage.IsNodeRunning() {
+		fmt.Println("No node is currently running!")
+		fmt.Println("   Start a node first: chainlog-cli start [port]")
+		return false
+	}
+
+	bc = newBlockchain()
+	state = storage.NewStateManager()
+	ledger = storage.NewLedgerManager(bc)
+	ledger.AttachState(state)
+	ledger.LoadBlockchain()
+	state.LoadState()
+	return true
+}
+
+func newBlockchain() *core.Blockchain {
+	chain := core.NewBlockchain()
+	chain.MaxPendingTx = cfg.Mempool.MaxTransactions
+	chain.MaxTxSize = cfg.Mempool.MaxTxSize
+	return chain
+}
+
 func printUsage() {
 	fmt.Println("ChainLog CLI")
 	fmt.Println("==================================")
 	fmt.Println("Commands:")
-	fmt.Println("  start [port]                 - Start a node (default: 8080)")
+	fmt.Println("  start [port] [options]        - Start a node (default: localhost:8080); see start -h for options")
 	fmt.Println("  wallet create                 - Create a new wallet")
 	fmt.Println("  wallet import <key>           - Import wallet from private key")
 	fmt.Println("  wallet list                   - List all wallets")
 	fmt.Println("  transaction create <data> <fee> - Create a transaction")
+	fmt.Println("  transaction structured <schema> <json> <fee> - Create a schema-validated transaction")
+	fmt.Println("  transaction encrypt <data> <fee> <recipients> - Create an encrypted transaction")
+	fmt.Println("  transaction read <tx_id>      - Show a transaction, decrypting it if possible")
 	fmt.Println("  transaction list              - List pending transactions")
 	fmt.Println("  transaction broadcast <tx_id> - Broadcast transaction")
 	fmt.Println("  transaction status <tx_id>    - Check transaction status")
+	fmt.Println("  query [options]               - Search confirmed entries by sender, stream, type, time or field")
+	fmt.Println("  search <terms...>             - Full-text search of entry data, ranked by relevance")
+	fmt.Println("  notarize <file> [fee]         - Anchor a file's hash on-chain, storing it off-chain")
+	fmt.Println("  verify <file>                 - Find the transaction and block time anchoring a file")
+	fmt.Println("  blob export <hash> <path>     - Copy a stored payload out of the blob store")
+	fmt.Println("  blob fetch <peer> <hash>      - Download an anchored payload from a peer")
+	fmt.Println("  batch submit <file> [fee]     - Anchor every line of a file with one Merkle root")
+	fmt.Println("  batch prove <tx_id> <line>    - Build an inclusion proof for one batched line")
+	fmt.Println("  batch verify <proof_file>     - Check a batch inclusion proof against the chain")
+	fmt.Println("  agent tail <path> [options]   - Follow a log file and anchor new lines in batches")
+	fmt.Println("  agent syslog [options]        - Receive syslog (RFC 5424/3164) and anchor it in batches")
+	fmt.Println("  subject record <subject> <data> - Record an entry under a per-subject key")
+	fmt.Println("  subject erase <subject>       - Destroy a subject's key and record the erasure")
+	fmt.Println("  subject verify <tx_id>        - Show that an entry existed and whether it was erased")
+	fmt.Println("  stream append <stream> <data> - Append the next sequenced entry to a stream")
+	fmt.Println("  stream list                   - List streams and their health")
+	fmt.Println("  stream show <stream>          - Show a stream and report sequence gaps")
+	fmt.Println("  schema register <file> [fee]  - Register a payload schema on-chain")
+	fmt.Println("  schema list                   - List registered schemas")
+	fmt.Println("  schema show <id>              - Show a registered schema")
+	fmt.Println("  attest <tx_id> [note] [fee]   - Countersign a confirmed entry as a witness")
+	fmt.Println("  attestations <tx_id>          - List witness attestations for an entry")
+	fmt.Println("  amend <tx_id> <data> [fee]    - Supersede an entry you created (or were delegated)")
+	fmt.Println("  history <tx_id>               - Show an entry's revisions and effective version")
+	fmt.Println("  delegate [grant|revoke] <addr> - Allow or revoke amendments by another wallet")
+	fmt.Println("  multisig create <m> <keys...> - Create an M-of-N multisig account")
+	fmt.Println("  multisig propose <addr> <data> <fee> - Create a partially signed transaction")
+	fmt.Println("  multisig sign <file>          - Add a signature to a partially signed transaction")
+	fmt.Println("  multisig finalize <file>      - Submit a fully signed multisig transaction")
 	fmt.Println("  mine                          - Mine pending transactions")
 	fmt.Println("  status                        - Show blockchain status")
 	fmt.Println("  balance <address>             - Check account balance")
@@ -86,6 +191,8 @@ func printUsage() {
 	fmt.Println("  peers list                    - List peers")
 	fmt.Println("  chain show                    - Display full blockchain")
 	fmt.Println("  chain validate                - Validate blockchain integrity")
+	fmt.Println("  chain export [options]        - Write blocks to a verifiable archive (--from, --to, --format jsonl|cbor)")
+	fmt.Println("  chain import [--verify-only] [--genesis hash] <file> - Verify an archive and add its new blocks (node must be stopped)")
 	fmt.Println("  fees                    - Show fee statistics")
 	fmt.Println("  rewards                 - Show reward statistics")
 	fmt.Println("  staking add <address> <amt>   - Stake LogCoins")
@@ -93,6 +200,14 @@ func printUsage() {
 	fmt.Println("  difficulty check              - Show current vs. recommended difficulty")
 	fmt.Println("  save                          - Save blockchain and state to disk")
 	fmt.Println("  load                          - Load blockchain and state from disk")
+	fmt.Println("  reindex                       - Rebuild the transaction, block and address indexes")
+	fmt.Println("  snapshot create [file]        - Write a compressed snapshot of the state at the chain tip")
+	fmt.Println("  snapshot restore [--trust hash] <file> - Check a snapshot's header chain against a trusted block and load it")
+	fmt.Println("  snapshot fetch [--trust hash] <peer> [file] - Download a snapshot from a running node")
 	fmt.Println("  summary                       - Print full system summary")
+	fmt.Println("  config show                   - Print the effective configuration")
 	fmt.Println("  help                          - Show this help message")
+	fmt.Println("\nGlobal options:")
+	fmt.Println("  --datadir <dir>               - Keep all node data in <dir> (default: ./chainlog-data, or $CHAINLOG_HOME)")
+	fmt.Println("  --config <file>               - Read settings from a config file (default: chainlog.toml in the data directory)")
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"chainlog/storage"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func handleMultisig() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli multisig [create|list|propose|sign|finalize]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  create <threshold> <key_or_wallet>...   - Create an M-of-N multisig account")
+		fmt.Println("  list                                    - List known multisig accounts")
+		fmt.Println("  propose <address> <data> <fee> [file]   - Create a partially signed transaction")
+		fmt.Println("  sign <file> [wallet_address]            - Add a signature to a transaction file")
+		fmt.Println("  finalize <file>                         - Verify and submit a fully signed transaction")
+		return
+	}
+
+	switch os.Args[2] {
+	case "create":
+		handleMultisigCreate()
+	case "list":
+		handleMultisigList()
+	case "propose":
+		handleMultisigPropose()
+	case "sign":
+		handleMultisigSign()
+	case "finalize":
+		handleMultisigFinalize()
+	default:
+		fmt.Println("Usage: chainlog-cli multisig [create|list|propose|sign|finalize]")
+	}
+}
+
+func handleMultisigCreate() {
+	if len(os.Args) < 5 {
+		fmt.Println("Usage: chainlog-cli multisig create <threshold> <key_or_wallet>...")
+		fmt.Println("\nEach signer is either a 128-character public key or the address")
+		fmt.Println("of a wallet stored locally.")
+		fmt.Println("\nExample:")
+		fmt.Println("  chainlog-cli multisig create 2 <officer1> <officer2> <officer3>")
+		return
+	}
+
+	threshold, err := strconv.Atoi(os.Args[3])
+	if err != nil {
+		fmt.Printf("Invalid threshold: %v\n", err)
+		return
+	}
+
+	var publicKeys []string
+	for _, arg := range os.Args[4:] {
+		publicKey, err := resolvePublicKey(arg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	account, err := crypto.NewMultisigAccount(threshold, publicKeys)
+	if err != nil {
+		fmt.Printf("Error creating multisig account: %v\n", err)
+		return
+	}
+
+	if err := storage.SaveMultisigAccount(account); err != nil {
+		fmt.Printf("Error saving multisig account: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Multisig account created and saved successfully!\n\n")
+	account.Display()
+}
+
+func handleMultisigList() {
+	accounts, err := storage.GetAllMultisigAccounts()
+	if err != nil {
+		fmt.Printf("Error loading multisig accounts: %v\n", err)
+		return
+	}
+
+	if len(accounts) == 0 {
+		fmt.Println("No multisig accounts found.")
+		fmt.Println("   Create one with: chainlog-cli multisig create <threshold> <keys...>")
+		return
+	}
+
+	fmt.Printf("Multisig Accounts (%d):\n\n", len(accounts))
+	for _, account := range accounts {
+		account.Display()
+		fmt.Println()
+	}
+}
+
+func handleMultisigPropose() {
+	if len(os.Args) < 6 {
+		fmt.Println("Usage: chainlog-cli multisig propose <multisig_address> <data> <fee> [file]")
+		return
+	}
+
+	account, err := storage.LoadMultisigAccount(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	data := os.Args[4]
+	fee, err := strconv.ParseUint(os.Args[5], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewMultisigDataTransaction(data, account, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	path := tx.ID[:16] + ".multisig.json"
+	if len(os.Args) >= 7 {
+		path = os.Args[6]
+	}
+
+	if err := storage.SavePartialTransaction(tx, path); err != nil {
+		fmt.Printf("Error saving transaction: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Partially signed transaction created!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ From: %s (%d-of-%d)\n", account.Address, account.Threshold, len(account.PublicKeys))
+	fmt.Printf("├─ Data: %.50s\n", tx.Data)
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ File: %s\n", path)
+	fmt.Println("\nShare the file with the signers: chainlog-cli multisig sign " + path)
+}
+
+func handleMultisigSign() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli multisig sign <file> [wallet_address]")
+		return
+	}
+
+	path := os.Args[3]
+	tx, err := storage.LoadPartialTransaction(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 5 {
+		walletAddress = os.Args[4]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := tx.AddSignature(wallet); err != nil {
+		fmt.Printf("Error signing transaction: %v\n", err)
+		return
+	}
+
+	if err := storage.SavePartialTransaction(tx, path); err != nil {
+		fmt.Printf("Error saving transaction: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Signed by %s: %d of %d required signatures\n",
+		wallet.GetAddressShort(), tx.CountValidSignatures(), tx.Multisig.Threshold)
+	if tx.IsFullySigned() {
+		fmt.Println("Transaction is fully signed: chainlog-cli multisig finalize " + path)
+	}
+}
+
+func handleMultisigFinalize() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli multisig finalize <file>")
+		return
+	}
+
+	tx, err := storage.LoadPartialTransaction(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := tx.VerifyMultisig(); err != nil {
+		fmt.Printf("Cannot finalize: %v\n", err)
+		return
+	}
+
+	if !loadChainState() {
+		return
+	}
+
+	validator := core.NewValidator(bc)
+	if !validator.AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	fmt.Printf("Multisig transaction %s... submitted with %d signatures\n",
+		tx.ID[:16], tx.CountValidSignatures())
+	queueBroadcast(tx.ID)
+}
+
+func resolvePublicKey(keyOrAddress string) (string, error) {
+	if len(keyOrAddress) == 128 {
+		if _, err := crypto.StringToPublicKey(keyOrAddress); err != nil {
+			return "", err
+		}
+		return keyOrAddress, nil
+	}
+
+	wallet, err := storage.LoadWalletFromStorage(keyOrAddress)
+	if err != nil {
+		return "", fmt.Errorf("%.16s is neither a public key nor a stored wallet", keyOrAddress)
+	}
+	return crypto.PublicKeyToString(wallet.PublicKey), nil
+}
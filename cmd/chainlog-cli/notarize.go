@@ -0,0 +1,202 @@
+package main
+
+import (
+	"chainlog/core"
+	"chainlog/network"
+	"chainlog/storage"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func handleNotarize() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli notarize <file> [fee] [wallet_address]")
+		fmt.Println("\nStores the file in the local blob store and anchors its hash,")
+		fmt.Println("size and media type on-chain. The file itself never enters a block.")
+		return
+	}
+
+	path := os.Args[2]
+
+	fee, err := parseOptionalFee(3)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 5 {
+		walletAddress = os.Args[4]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	hash, size, err := storage.StoreBlobFile(path)
+	if err != nil {
+		fmt.Printf("Error storing file: %v\n", err)
+		return
+	}
+
+	mediaType, err := detectMediaType(path)
+	if err != nil {
+		fmt.Printf("Error reading file: %v\n", err)
+		return
+	}
+
+	payload := &core.PayloadRef{Hash: hash, Size: size, MediaType: mediaType}
+	tx, err := core.NewNotarizeTransaction(payload, "notarized "+filepath.Base(path), wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("File notarized successfully!\n\n")
+	fmt.Printf("├─ Transaction: %s\n", tx.ID)
+	fmt.Printf("├─ SHA-256: %s\n", hash)
+	fmt.Printf("├─ Size: %d bytes\n", size)
+	fmt.Printf("├─ Media Type: %s\n", mediaType)
+	fmt.Printf("├─ Stored: %s\n", storage.BlobPath(hash))
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleVerify() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli verify <file>")
+		return
+	}
+
+	hash, size, err := storage.HashFile(os.Args[2])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	anchors := bc.FindPayloadAnchors(hash)
+
+	fmt.Printf("File: %s\n", os.Args[2])
+	fmt.Printf("SHA-256: %s\n", hash)
+	fmt.Printf("Size: %d bytes\n\n", size)
+
+	if len(anchors) == 0 {
+		fmt.Println("NOT ANCHORED: no transaction commits to this file's content")
+		return
+	}
+
+	for i, anchor := range anchors {
+		tx := anchor.Transaction
+		fmt.Printf("%d. Transaction %s\n", i+1, tx.ID)
+		fmt.Printf("   Anchored by: %s\n", tx.Sender)
+		if tx.Payload.Size != size {
+			fmt.Printf("   WARNING: anchored size %d differs from file size\n", tx.Payload.Size)
+		}
+		if anchor.Block != nil {
+			fmt.Printf("   Block: %d\n", anchor.Block.Index)
+			fmt.Printf("   Block Time: %s\n", time.Unix(anchor.Block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("   Status: Pending (not yet in a block)\n")
+		}
+	}
+
+	if anchors[0].Block != nil {
+		fmt.Printf("\nVERIFIED: file existed no later than %s\n",
+			time.Unix(anchors[0].Block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	}
+}
+
+func handleBlob() {
+	if len(os.Args) < 5 {
+		printBlobUsage()
+		return
+	}
+
+	switch os.Args[2] {
+	case "export":
+		handleBlobExport()
+	case "fetch":
+		handleBlobFetch()
+	default:
+		printBlobUsage()
+	}
+}
+
+func printBlobUsage() {
+	fmt.Println("Usage: chainlog-cli blob export <hash> <output_path>")
+	fmt.Println("       chainlog-cli blob fetch <peer> <hash>")
+}
+
+// handleBlobFetch downloads an anchored payload from a peer. The anchor
+// gives the size and hash the peer's copy must match.
+func handleBlobFetch() {
+	peer, hash := os.Args[3], os.Args[4]
+	if storage.HasBlob(hash) {
+		fmt.Printf("Blob %.16s... is already stored locally\n", hash)
+		return
+	}
+
+	anchors := bc.FindPayloadAnchors(hash)
+	if len(anchors) == 0 {
+		fmt.Printf("No transaction anchors blob %.16s...; only anchored payloads can be fetched\n", hash)
+		return
+	}
+
+	ref := anchors[0].Transaction.Payload
+	fmt.Printf("Fetching blob %.16s... (%d bytes) from %s...\n", hash, ref.Size, peer)
+	if err := network.RemoteBlob(peer, ref); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Stored blob %.16s... (%d bytes)\n", hash, ref.Size)
+}
+
+func handleBlobExport() {
+	content, err := storage.LoadBlob(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := os.WriteFile(os.Args[4], content, 0644); err != nil {
+		fmt.Printf("Error writing file: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Exported blob %.16s... (%d bytes) to %s\n", os.Args[3], len(content), os.Args[4])
+}
+
+func detectMediaType(path string) (string, error) {
+	if mediaType := mime.TypeByExtension(filepath.Ext(path)); mediaType != "" {
+		return mediaType, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := file.Read(head)
+	return http.DetectContentType(head[:n]), nil
+}
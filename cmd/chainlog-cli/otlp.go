@@ -0,0 +1,51 @@
+package main
+
+import (
+	"chainlog/agent"
+	"chainlog/core"
+	"chainlog/crypto"
+	"chainlog/sdk"
+	"fmt"
+)
+
+// startOTLPReceiver serves OTLP logs and anchors them in batches until stop
+// is closed. The returned channel closes once the last batch is submitted.
+// Batches are submitted under the chain lock of the running node.
+func startOTLPReceiver(address string, wallet *crypto.Wallet, stop <-chan struct{}) (*agent.OTLPReceiver, <-chan struct{}, error) {
+	batcher := agent.NewBatcher(func(stream string, entries []string) (string, error) {
+		batch := sdk.NewBatch("otlp")
+		batch.Stream = stream
+		for _, entry := range entries {
+			batch.Add(entry)
+		}
+
+		var txID string
+		var err error
+		node.WithChain(func() {
+			txID, err = submitBatch(batch, wallet, 1, nil)
+		})
+		return txID, err
+	})
+
+	receiver := agent.NewOTLPReceiver(address, func(stream string, entry map[string]interface{}) bool {
+		data, err := core.EncodePayload(entry, core.EncodingJSON)
+		if err != nil {
+			return false
+		}
+		return batcher.Add(stream, data)
+	})
+
+	if err := receiver.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := batcher.Run(stop); err != nil {
+			fmt.Printf("OTLP batcher: %v\n", err)
+		}
+	}()
+
+	return receiver, done, nil
+}
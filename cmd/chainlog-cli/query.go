@@ -0,0 +1,214 @@
+package main
+
+import (
+	"chainlog/core"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fieldFilters map[string]string
+
+func (f fieldFilters) String() string {
+	parts := make([]string, 0, len(f))
+	for name, value := range f {
+		parts = append(parts, name+"="+value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f fieldFilters) Set(value string) error {
+	name, expected, found := strings.Cut(value, "=")
+	if !found || name == "" {
+		return fmt.Errorf("field filters must look like name=value")
+	}
+	f[name] = expected
+	return nil
+}
+
+type queryRecord struct {
+	Height    int64  `json:"block_height"`
+	BlockHash string `json:"block_hash"`
+	BlockTime string `json:"block_time"`
+	Position  int    `json:"position"`
+	ID        string `json:"tx_id"`
+	Type      string `json:"type"`
+	Sender    string `json:"sender"`
+	Receiver  string `json:"receiver,omitempty"`
+	Stream    string `json:"stream,omitempty"`
+	Sequence  uint64 `json:"sequence,omitempty"`
+	SchemaID  string `json:"schema_id,omitempty"`
+	Fee       uint64 `json:"fee"`
+	Data      string `json:"data"`
+}
+
+func parseQueryTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration).Unix(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q: use RFC 3339, YYYY-MM-DD, unix seconds or a duration like 24h", value)
+}
+
+func handleQuery() {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	sender := flags.String("sender", "", "only entries signed by this address")
+	stream := flags.String("stream", "", "only entries in this stream")
+	types := flags.String("type", "", "comma-separated transaction types (e.g. DATA,BATCH)")
+	fromBlock := flags.Int64("from-block", 0, "first block height to search")
+	toBlock := flags.Int64("to-block", -1, "last block height to search (default: tip)")
+	since := flags.String("since", "", "only blocks at or after this time (RFC 3339, date, unix or duration ago)")
+	until := flags.String("until", "", "only blocks at or before this time")
+	offset := flags.Int("offset", 0, "skip this many matches")
+	limit := flags.Int("limit", core.DefaultQueryLimit, "maximum matches to return (0 for all)")
+	format := flags.String("format", "table", "output format: table, json or csv")
+	output := flags.String("output", "", "write json or csv results to this file instead of stdout")
+	fields := fieldFilters{}
+	flags.Var(fields, "field", "payload field filter name=value, repeatable (dotted paths reach nested fields)")
+
+	flags.Usage = func() {
+		fmt.Println("Usage: chainlog-cli query [options]")
+		fmt.Println("\nOptions:")
+		flags.SetOutput(os.Stdout)
+		flags.PrintDefaults()
+		fmt.Println("\nExample:")
+		fmt.Println("  chainlog-cli query --stream payments/api --since 24h --field status=failed --format csv")
+	}
+
+	if err := flags.Parse(os.Args[2:]); err != nil {
+		return
+	}
+
+	q := core.NewQuery()
+	q.Sender = *sender
+	q.Stream = *stream
+	q.FromHeight = *fromBlock
+	q.ToHeight = *toBlock
+	q.Offset = *offset
+	q.Limit = *limit
+	q.Fields = fields
+
+	if *types != "" {
+		for _, name := range strings.Split(*types, ",") {
+			t, err := core.ParseTransactionType(name)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			q.Types = append(q.Types, t)
+		}
+	}
+
+	var err error
+	if q.Since, err = parseQueryTime(*since); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if q.Until, err = parseQueryTime(*until); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	result, err := bc.Query(q)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	records := make([]*queryRecord, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		tx := match.Transaction
+		records = append(records, &queryRecord{
+			Height:    match.Location.Height,
+			BlockHash: match.Block.Hash,
+			BlockTime: time.Unix(match.Block.Timestamp, 0).UTC().Format(time.RFC3339),
+			Position:  match.Location.Position,
+			ID:        tx.ID,
+			Type:      tx.Type.String(),
+			Sender:    tx.Sender,
+			Receiver:  tx.Receiver,
+			Stream:    tx.Stream,
+			Sequence:  tx.Sequence,
+			SchemaID:  tx.SchemaID,
+			Fee:       tx.Fee,
+			Data:      tx.Data,
+		})
+	}
+
+	out := os.Stdout
+	if *output != "" && *format != "table" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		defer file.Close()
+		out = file
+		defer fmt.Printf("Wrote %d of %d matches to %s\n", len(records), result.Total, *output)
+	}
+
+	switch *format {
+	case "json":
+		page := struct {
+			Total   int            `json:"total"`
+			Offset  int            `json:"offset"`
+			Limit   int            `json:"limit"`
+			Results []*queryRecord `json:"results"`
+		}{result.Total, result.Offset, result.Limit, records}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(page)
+
+	case "csv":
+		writer := csv.NewWriter(out)
+		writer.Write([]string{"block_height", "block_time", "position", "tx_id", "type", "sender", "stream", "sequence", "schema_id", "data"})
+		for _, r := range records {
+			writer.Write([]string{
+				strconv.FormatInt(r.Height, 10), r.BlockTime, strconv.Itoa(r.Position), r.ID, r.Type,
+				r.Sender, r.Stream, strconv.FormatUint(r.Sequence, 10), r.SchemaID, r.Data,
+			})
+		}
+		writer.Flush()
+
+	case "table":
+		if result.Total == 0 {
+			fmt.Println("No matching entries")
+			return
+		}
+
+		fmt.Printf("Showing %d of %d matches (offset %d):\n\n", len(records), result.Total, result.Offset)
+		for _, r := range records {
+			fmt.Printf("%s\n", r.ID)
+			fmt.Printf("├─ Block: %d (%s)\n", r.Height, r.BlockTime)
+			fmt.Printf("├─ Type: %s\n", r.Type)
+			fmt.Printf("├─ From: %s\n", r.Sender)
+			if r.Stream != "" {
+				fmt.Printf("├─ Stream: %s #%d\n", r.Stream, r.Sequence)
+			}
+			fmt.Printf("└─ Data: %.80s\n", r.Data)
+		}
+
+		if next := result.Offset + len(records); next < result.Total {
+			fmt.Printf("\nMore results available: --offset %d\n", next)
+		}
+
+	default:
+		fmt.Printf("Unknown format: %s (use table, json or csv)\n", *format)
+	}
+}
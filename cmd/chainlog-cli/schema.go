@@ -0,0 +1,234 @@
+package main
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func handleSchema() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli schema [register|list|show]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  register <file> [fee] [wallet_address] - Register a schema definition on-chain")
+		fmt.Println("  list                                   - List registered schemas")
+		fmt.Println("  show <schema_id>                       - Show a registered schema")
+		return
+	}
+
+	switch os.Args[2] {
+	case "register":
+		handleSchemaRegister()
+	case "list":
+		handleSchemaList()
+	case "show":
+		handleSchemaShow()
+	default:
+		fmt.Println("Usage: chainlog-cli schema [register|list|show]")
+	}
+}
+
+func handleSchemaRegister() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli schema register <file> [fee] [wallet_address]")
+		fmt.Println("\nExample schema file:")
+		fmt.Println(`  {"id": "audit-event/v1", "fields": {`)
+		fmt.Println(`    "event":    {"type": "string", "required": true},`)
+		fmt.Println(`    "actor":    {"type": "string", "required": true},`)
+		fmt.Println(`    "severity": {"type": "string", "required": true, "enum": ["info", "warning", "critical"]}`)
+		fmt.Println(`  }}`)
+		return
+	}
+
+	definition, err := os.ReadFile(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error reading schema file: %v\n", err)
+		return
+	}
+
+	schema, err := core.ParseSchema(string(definition))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewSchemaTransaction(schema, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating schema registration: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Schema registration rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Schema registration created successfully!\n\n")
+	fmt.Printf("├─ Schema: %s\n", schema.ID)
+	fmt.Printf("├─ Fields: %s\n", strings.Join(schema.FieldNames(), ", "))
+	fmt.Printf("├─ Transaction: %s\n", tx.ID)
+	fmt.Printf("└─ Status: Pending (usable once mined)\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleSchemaList() {
+	registrations := bc.GetSchemas()
+	if len(registrations) == 0 {
+		fmt.Println("No schemas registered")
+		return
+	}
+
+	fmt.Printf("Registered Schemas (%d):\n\n", len(registrations))
+	for i, tx := range registrations {
+		schema, err := core.ParseSchema(tx.Data)
+		if err != nil {
+			fmt.Printf("%d. %s (invalid: %v)\n", i+1, tx.SchemaID, err)
+			continue
+		}
+
+		fmt.Printf("%d. %s\n", i+1, schema.ID)
+		if schema.Description != "" {
+			fmt.Printf("   Description: %s\n", schema.Description)
+		}
+		fmt.Printf("   Fields: %s\n", strings.Join(schema.FieldNames(), ", "))
+		fmt.Printf("   Registered by: %s\n", tx.Sender[:8])
+	}
+}
+
+func handleSchemaShow() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli schema show <schema_id>")
+		return
+	}
+
+	schema, tx, err := bc.GetSchema(os.Args[3])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Schema %s\n", schema.ID)
+	fmt.Printf("├─ Registered by: %s\n", tx.Sender)
+	fmt.Printf("├─ Registered at: %s\n", time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	fmt.Printf("├─ Strict: %t\n", schema.Strict)
+	fmt.Printf("└─ Fields:\n")
+	for _, name := range schema.FieldNames() {
+		field := schema.Fields[name]
+		required := ""
+		if field.Required {
+			required = " (required)"
+		}
+		enum := ""
+		if len(field.Enum) > 0 {
+			enum = " one of [" + strings.Join(field.Enum, ", ") + "]"
+		}
+		fmt.Printf("   %s: %s%s%s\n", name, field.Type, required, enum)
+	}
+}
+
+func handleTransactionStructured() {
+	args := []string{}
+	encoding := core.EncodingJSON
+	for _, arg := range os.Args[3:] {
+		if arg == "--cbor" {
+			encoding = core.EncodingCBOR
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	if len(args) < 3 {
+		fmt.Println("Usage: chainlog-cli transaction structured <schema_id> <json|@file> <fee> [wallet_address] [--cbor]")
+		fmt.Println("\nExample:")
+		fmt.Println(`  chainlog-cli transaction structured audit-event/v1 '{"event":"login","actor":"alice","severity":"info"}' 2`)
+		return
+	}
+
+	schemaID := args[0]
+	rawPayload := args[1]
+	if strings.HasPrefix(rawPayload, "@") {
+		data, err := os.ReadFile(rawPayload[1:])
+		if err != nil {
+			fmt.Printf("Error reading payload file: %v\n", err)
+			return
+		}
+		rawPayload = string(data)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(rawPayload), &payload); err != nil {
+		fmt.Printf("Payload must be a JSON object: %v\n", err)
+		return
+	}
+
+	fee, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(args) >= 4 {
+		walletAddress = args[3]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewStructuredTransaction(schemaID, payload, encoding, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Structured transaction created successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Schema: %s\n", tx.SchemaID)
+	fmt.Printf("├─ Encoding: %s (%d bytes)\n", tx.Encoding, len(tx.Data))
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
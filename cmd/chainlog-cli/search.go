@@ -0,0 +1,176 @@
+package main
+
+import (
+	"chainlog/core"
+	"chainlog/network"
+	"chainlog/storage"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func handleSearch() {
+	if len(os.Args) < 3 {
+		printSearchUsage()
+		return
+	}
+
+	switch os.Args[2] {
+	case "rebuild":
+		handleSearchRebuild()
+	case "status":
+		handleSearchStatus()
+	case "disable":
+		handleSearchDisable()
+	default:
+		handleSearchQuery()
+	}
+}
+
+func printSearchUsage() {
+	fmt.Println("Usage: chainlog-cli search [options] <terms...>")
+	fmt.Println("\nCommands:")
+	fmt.Println("  <terms...>  - Find entries containing every term, best matches first")
+	fmt.Println("  rebuild     - Build (or rebuild) the full-text index and keep it updated")
+	fmt.Println("  status      - Show whether the index is enabled and how current it is")
+	fmt.Println("  disable     - Delete the index and stop maintaining it")
+	fmt.Println("\nOptions:")
+	fmt.Println("  --limit <n>        - Maximum results to show (default 20)")
+	fmt.Println("  --node <host:port> - Ask a running node instead of the local index")
+	fmt.Println("  --format json      - Print results as JSON")
+	fmt.Println("\nExample:")
+	fmt.Println("  chainlog-cli search user=alice login failed")
+}
+
+func handleSearchQuery() {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := flags.Int("limit", core.DefaultSearchLimit, "maximum results to show")
+	nodeAddress := flags.String("node", "", "search on a running node")
+	format := flags.String("format", "table", "output format: table or json")
+	flags.Usage = printSearchUsage
+
+	var terms []string
+	args := os.Args[2:]
+	for {
+		if err := flags.Parse(args); err != nil {
+			return
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		terms = append(terms, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	query := strings.Join(terms, " ")
+	if strings.TrimSpace(query) == "" {
+		printSearchUsage()
+		return
+	}
+
+	var results *core.SearchResults
+	if *nodeAddress != "" {
+		var err error
+		results, err = network.RemoteSearch(*nodeAddress, query, *limit)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	} else {
+		index, err := storage.SyncSearchIndex(bc)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		results = index.Search(query, *limit)
+		bc.DescribeSearchHits(results.Hits)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+		return
+	}
+
+	if len(results.Terms) == 0 {
+		fmt.Println("Nothing to search for: the query has no searchable words")
+		return
+	}
+	if results.Total == 0 {
+		fmt.Printf("No entries match %q\n", results.Query)
+		return
+	}
+
+	fmt.Printf("Showing %d of %d entries matching %q:\n\n", len(results.Hits), results.Total, results.Query)
+	for _, hit := range results.Hits {
+		fmt.Printf("%s  (score %.3f)\n", hit.TxID, hit.Score)
+		fmt.Printf("├─ Block: %d (%.16s...)\n", hit.Height, hit.BlockHash)
+		if hit.Type != "" {
+			fmt.Printf("├─ Type: %s\n", hit.Type)
+			fmt.Printf("├─ From: %s\n", hit.Sender)
+		}
+		fmt.Printf("└─ Data: %s\n", hit.Snippet)
+	}
+}
+
+func handleSearchRebuild() {
+	index, err := storage.RebuildSearchIndex(bc)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Search index built:\n")
+	fmt.Printf("├─ Blocks: %d\n", index.Height)
+	fmt.Printf("├─ Entries: %d\n", len(index.Documents))
+	fmt.Printf("└─ Terms: %d\n", index.Terms())
+}
+
+func handleSearchStatus() {
+	if !storage.SearchIndexEnabled() {
+		fmt.Println("Search index: disabled")
+		fmt.Println("   Enable it with: chainlog-cli search rebuild")
+		return
+	}
+
+	index, err := storage.LoadSearchIndex()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	status := "current"
+	if int64(bc.GetBlockCount()) != index.Height || bc.GetLastBlock().Hash != index.TipHash() {
+		status = fmt.Sprintf("behind (chain has %d blocks, updated on next search or mine)", bc.GetBlockCount())
+	}
+
+	fmt.Printf("Search index: enabled\n")
+	fmt.Printf("├─ Blocks: %d\n", index.Height)
+	fmt.Printf("├─ Entries: %d\n", len(index.Documents))
+	fmt.Printf("├─ Terms: %d\n", index.Terms())
+	fmt.Printf("└─ Status: %s\n", status)
+}
+
+func handleSearchDisable() {
+	if err := storage.DeleteSearchIndex(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Println("Search index deleted; it will no longer be maintained")
+}
+
+func updateSearchIndex() {
+	if !storage.SearchIndexEnabled() {
+		return
+	}
+
+	index, err := storage.SyncSearchIndex(bc)
+	if err != nil {
+		fmt.Printf("Warning: Could not update search index: %v\n", err)
+		return
+	}
+	fmt.Printf("Search index updated: %d entries across %d blocks\n", len(index.Documents), index.Height)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"chainlog/network"
+	"chainlog/storage"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func handleSnapshot() {
+	if len(os.Args) < 3 {
+		printSnapshotUsage()
+		return
+	}
+
+	switch os.Args[2] {
+	case "create":
+		handleSnapshotCreate()
+	case "restore":
+		handleSnapshotRestore()
+	case "fetch":
+		handleSnapshotFetch()
+	default:
+		printSnapshotUsage()
+	}
+}
+
+func printSnapshotUsage() {
+	fmt.Println("Usage: chainlog-cli snapshot <command>")
+	fmt.Println("\nCommands:")
+	fmt.Println("  create [file]                      - Write the state at the chain tip to a compressed snapshot")
+	fmt.Println("  restore [--trust hash] <file>      - Check a snapshot's header chain and load it (node must be stopped)")
+	fmt.Println("  fetch [--trust hash] <peer> [file] - Download a snapshot from a running node")
+	fmt.Println("\nA snapshot's headers must lead to the local chain's tip or to the block given")
+	fmt.Println("with --trust, such as a checkpoint hash you got from a source you trust, no")
+	fmt.Printf("more than %d blocks below the snapshot's block. Headers do not commit to\n", storage.SnapshotTrustDistance)
+	fmt.Println("account state: restoring a snapshot trusts the node that created it.")
+}
+
+func snapshotFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("snapshot "+name, flag.ContinueOnError)
+	trusted := flags.String("trust", "", "hash of a block the snapshot's header chain must include (default: local tip)")
+	return flags, trusted
+}
+
+func snapshotFileName(snap *storage.Snapshot) string {
+	return fmt.Sprintf("chainlog-snapshot-%d.json.gz", snap.Height)
+}
+
+func handleSnapshotCreate() {
+	bc = newBlockchain()
+	ledger = storage.NewLedgerManager(bc)
+	if err := ledger.LoadBlockchain(); err != nil {
+		fmt.Printf("Error: no chain to snapshot: %v\n", err)
+		return
+	}
+
+	snap, err := ledger.CreateSnapshot()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	path := snapshotFileName(snap)
+	if len(os.Args) > 3 {
+		path = os.Args[3]
+	}
+	if err := storage.SaveSnapshotFile(path, snap); err != nil {
+		fmt.Printf("Error writing snapshot: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Snapshot written to %s\n", path)
+	displaySnapshot(snap)
+}
+
+func handleSnapshotRestore() {
+	flags, trusted := snapshotFlags("restore")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		return
+	}
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: chainlog-cli snapshot restore [--trust hash] <file>")
+		return
+	}
+	if running, ok := storage.RunningNode(); ok {
+		fmt.Printf("Stop the node first (PID %d) - a snapshot replaces its saved state\n", running.PID)
+		return
+	}
+
+	snap, err := storage.LoadSnapshotFile(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading snapshot: %v\n", err)
+		return
+	}
+
+	bc = newBlockchain()
+	state = storage.NewStateManager()
+	ledger = storage.NewLedgerManager(bc)
+	ledger.AttachState(state)
+
+	if err := ledger.RestoreSnapshot(snap, *trusted); err != nil {
+		fmt.Printf("Restore failed: %v\n", err)
+		return
+	}
+
+	fmt.Println("Snapshot restored")
+	displaySnapshot(snap)
+	fmt.Println("The header chain was verified; the account state and indexes are the snapshot creator's.")
+}
+
+func handleSnapshotFetch() {
+	flags, trusted := snapshotFlags("fetch")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		return
+	}
+	if flags.NArg() < 1 {
+		fmt.Println("Usage: chainlog-cli snapshot fetch [--trust hash] <peer> [file]")
+		return
+	}
+
+	fmt.Printf("Requesting snapshot from %s...\n", flags.Arg(0))
+	snap, err := network.RemoteSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if *trusted != "" {
+		if err := snap.Verify(*trusted); err != nil {
+			fmt.Printf("Error: snapshot from %s: %v\n", flags.Arg(0), err)
+			return
+		}
+	}
+
+	path := snapshotFileName(snap)
+	if flags.NArg() > 1 {
+		path = flags.Arg(1)
+	}
+	if err := storage.SaveSnapshotFile(path, snap); err != nil {
+		fmt.Printf("Error writing snapshot: %v\n", err)
+		return
+	}
+
+	restore := path
+	if *trusted != "" {
+		restore = "--trust " + *trusted + " " + path
+	}
+	fmt.Printf("Snapshot written to %s (restore it with: chainlog-cli snapshot restore %s)\n", path, restore)
+	displaySnapshot(snap)
+}
+
+func displaySnapshot(snap *storage.Snapshot) {
+	fmt.Printf("├─ Height: %d\n", snap.Height)
+	fmt.Printf("├─ Block: %s\n", snap.BlockHash)
+	fmt.Printf("├─ Created: %s\n", time.Unix(snap.CreatedAt, 0).Format("2006-01-02 15:04:05"))
+	fmt.Printf("├─ Accounts: %d\n", len(snap.State.Accounts))
+	fmt.Printf("├─ Index Entries: %d\n", len(snap.State.Index))
+	fmt.Printf("└─ State Checksum: %s\n", snap.StateHash)
+}
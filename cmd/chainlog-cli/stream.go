@@ -0,0 +1,167 @@
+package main
+
+import (
+	"chainlog/core"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func handleStream() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli stream [append|list|show]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  append <stream> <data> [fee] [wallet_address] - Append the next entry to a stream")
+		fmt.Println("  list                                          - List streams and their health")
+		fmt.Println("  show <stream>                                 - Show entries and report sequence gaps")
+		return
+	}
+
+	switch os.Args[2] {
+	case "append":
+		handleStreamAppend()
+	case "list":
+		handleStreamList()
+	case "show":
+		handleStreamShow()
+	default:
+		fmt.Println("Usage: chainlog-cli stream [append|list|show]")
+	}
+}
+
+func handleStreamAppend() {
+	if len(os.Args) < 5 {
+		fmt.Println("Usage: chainlog-cli stream append <stream> <data> [fee] [wallet_address]")
+		fmt.Println("\nExample:")
+		fmt.Println("  chainlog-cli stream append payments/api \"refund issued: order=1042\" 2")
+		return
+	}
+
+	stream := os.Args[3]
+	data := os.Args[4]
+
+	fee, err := parseOptionalFee(5)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 7 {
+		walletAddress = os.Args[6]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	sequence := bc.NextSequence(stream, wallet.GetAddress())
+	tx, err := core.NewStreamTransaction(stream, sequence, data, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Stream entry created successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Stream: %s\n", tx.Stream)
+	fmt.Printf("├─ Sequence: %d\n", tx.Sequence)
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleStreamList() {
+	names := bc.GetStreamNames()
+	if len(names) == 0 {
+		fmt.Println("No streams found")
+		fmt.Println("   Append to one with: chainlog-cli stream append <stream> <data>")
+		return
+	}
+
+	fmt.Printf("Streams (%d):\n\n", len(names))
+	for _, name := range names {
+		info := bc.GetStreamInfo(name)
+
+		status := "contiguous"
+		for _, sender := range info.Senders {
+			if !sender.IsContiguous() {
+				status = "GAPS DETECTED"
+				break
+			}
+		}
+
+		fmt.Printf("%s\n", name)
+		fmt.Printf("   Entries: %d from %d sender(s)\n", info.Entries, len(info.Senders))
+		fmt.Printf("   Status: %s\n", status)
+	}
+}
+
+func handleStreamShow() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli stream show <stream>")
+		return
+	}
+
+	stream := os.Args[3]
+	entries := bc.GetStreamEntries(stream)
+	if len(entries) == 0 {
+		fmt.Printf("Stream not found: %s\n", stream)
+		return
+	}
+
+	fmt.Printf("\nSTREAM %s (%d entries)\n", stream, len(entries))
+	fmt.Println("═══════════════════════════════════════════════════")
+	for _, entry := range entries {
+		tx := entry.Transaction
+		location := "pending"
+		if entry.Block != nil {
+			location = fmt.Sprintf("block %d, %s", entry.Block.Index,
+				time.Unix(entry.Block.Timestamp, 0).Format("2006-01-02 15:04:05"))
+		}
+		fmt.Printf("#%-6d %s... %s (%s)\n", tx.Sequence, tx.ID[:16], tx.Sender[:8], location)
+		fmt.Printf("        %.60s\n", tx.Data)
+	}
+
+	info := core.AnalyzeStream(stream, entries)
+	fmt.Printf("\nSequence report:\n")
+	for _, sender := range info.Senders {
+		fmt.Printf("├─ %s: %d entries, last sequence %d\n", sender.Address[:8], sender.Entries, sender.LastSequence)
+		if sender.IsContiguous() {
+			fmt.Printf("│  └─ No gaps or reordering\n")
+			continue
+		}
+		if len(sender.Gaps) > 0 {
+			var gaps []string
+			for _, gap := range sender.Gaps {
+				if gap.From == gap.To {
+					gaps = append(gaps, fmt.Sprintf("%d", gap.From))
+				} else {
+					gaps = append(gaps, fmt.Sprintf("%d-%d", gap.From, gap.To))
+				}
+			}
+			fmt.Printf("│  ├─ Missing: %s\n", strings.Join(gaps, ", "))
+		}
+		if len(sender.Reordered) > 0 {
+			fmt.Printf("│  ├─ Out of order: %v\n", sender.Reordered)
+		}
+		if len(sender.Duplicates) > 0 {
+			fmt.Printf("│  ├─ Duplicated: %v\n", sender.Duplicates)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"chainlog/core"
+	"chainlog/storage"
+	"fmt"
+	"os"
+	"time"
+)
+
+func handleSubject() {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: chainlog-cli subject [record|erase|verify|list]")
+		fmt.Println("\nCommands:")
+		fmt.Println("  record <subject> <data> [fee] [wallet_address] - Record an entry encrypted under the subject's key")
+		fmt.Println("  erase <subject> [fee] [wallet_address]         - Destroy the subject's key and record an erasure")
+		fmt.Println("  verify <tx_id>                                 - Show whether an entry existed and was erased")
+		fmt.Println("  list                                           - List subjects in the local key vault")
+		return
+	}
+
+	switch os.Args[2] {
+	case "record":
+		handleSubjectRecord()
+	case "erase":
+		handleSubjectErase()
+	case "verify":
+		handleSubjectVerify()
+	case "list":
+		handleSubjectList()
+	default:
+		fmt.Println("Usage: chainlog-cli subject [record|erase|verify|list]")
+	}
+}
+
+func handleSubjectRecord() {
+	if len(os.Args) < 5 {
+		fmt.Println("Usage: chainlog-cli subject record <subject> <data> [fee] [wallet_address]")
+		fmt.Println("\nExample:")
+		fmt.Println("  chainlog-cli subject record customer-1042 \"address changed to 12 High St\" 2")
+		return
+	}
+
+	subject := os.Args[3]
+	data := os.Args[4]
+
+	fee, err := parseOptionalFee(5)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 7 {
+		walletAddress = os.Args[6]
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	subjectKey, key, err := storage.GetKeyVault().GetOrCreateSubjectKey(subject)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewSubjectEncryptedTransaction(data, key, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	fmt.Printf("Subject entry created successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Subject: %s\n", subject)
+	fmt.Printf("├─ Key ID: %s\n", subjectKey.KeyID)
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleSubjectErase() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli subject erase <subject> [fee] [wallet_address]")
+		fmt.Println("\nThe subject's key is destroyed locally and a signed erasure is recorded")
+		fmt.Println("on-chain. Entries stay in their blocks but can no longer be decrypted.")
+		return
+	}
+
+	subject := os.Args[3]
+
+	fee, err := parseOptionalFee(4)
+	if err != nil {
+		fmt.Printf("Invalid fee: %v\n", err)
+		return
+	}
+
+	walletAddress := ""
+	if len(os.Args) >= 6 {
+		walletAddress = os.Args[5]
+	}
+
+	vault := storage.GetKeyVault()
+	subjectKey, exists := vault.GetSubjectKey(subject)
+	if !exists {
+		fmt.Printf("No key found for subject: %s\n", subject)
+		return
+	}
+
+	if subjectKey.DestroyedAt != 0 {
+		fmt.Printf("Subject %s was already erased (tx %s)\n", subject, subjectKey.ErasureTx)
+		return
+	}
+
+	wallet, err := loadSigningWallet(walletAddress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tx, err := core.NewErasureTransaction(subjectKey.KeyID, wallet, fee)
+	if err != nil {
+		fmt.Printf("Error creating transaction: %v\n", err)
+		return
+	}
+
+	if !core.NewValidator(bc).AdmitTransaction(tx) {
+		fmt.Println("Transaction rejected by validator")
+		fmt.Println("   The subject key was NOT destroyed")
+		return
+	}
+
+	if err := vault.DestroySubjectKey(subject, tx.ID); err != nil {
+		fmt.Printf("Error destroying subject key: %v\n", err)
+		return
+	}
+
+	bc.AddTransaction(tx)
+
+	record := bc.GetErasureRecord(subjectKey.KeyID)
+
+	fmt.Printf("Subject erased successfully!\n\n")
+	fmt.Printf("├─ ID: %s\n", tx.ID)
+	fmt.Printf("├─ Subject: %s\n", subject)
+	fmt.Printf("├─ Key ID: %s\n", subjectKey.KeyID)
+	fmt.Printf("├─ Entries made unreadable: %d\n", len(record.Entries))
+	fmt.Printf("├─ Fee: %d LogCoins\n", tx.Fee)
+	fmt.Printf("└─ Status: Pending\n")
+
+	if err := ledger.SaveBlockchain(); err != nil {
+		fmt.Printf("Warning: Could not save blockchain: %v\n", err)
+	}
+
+	queueBroadcast(tx.ID)
+}
+
+func handleSubjectVerify() {
+	if len(os.Args) < 4 {
+		fmt.Println("Usage: chainlog-cli subject verify <tx_id>")
+		return
+	}
+
+	tx, block := bc.FindTransactionByPrefix(os.Args[3])
+	if tx == nil {
+		fmt.Printf("Transaction not found: %s\n", os.Args[3])
+		return
+	}
+
+	keyID := tx.SubjectKeyID()
+	if tx.Type == core.ErasureTx {
+		keyID = tx.Data
+	}
+	if keyID == "" {
+		fmt.Printf("Transaction %s is not encrypted under a subject key\n", tx.ID)
+		return
+	}
+
+	record := bc.GetErasureRecord(keyID)
+
+	fmt.Printf("Transaction: %s\n", tx.ID)
+	if block != nil {
+		fmt.Printf("Block: %d\n", block.Index)
+	} else {
+		fmt.Printf("Status: Pending\n")
+	}
+	fmt.Printf("Subject key: %s\n\n", keyID)
+	fmt.Printf("Entries (%d):\n", len(record.Entries))
+	for _, entry := range record.Entries {
+		if entry.Block != nil {
+			fmt.Printf("├─ %s\n", entry.Transaction.ID)
+			fmt.Printf("│  └─ Block %d, %s, by %.8s...\n",
+				entry.Block.Index,
+				time.Unix(entry.Block.Timestamp, 0).Format(time.RFC3339),
+				entry.Transaction.Sender)
+		} else {
+			fmt.Printf("├─ %s (pending)\n", entry.Transaction.ID)
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case record.IsErased():
+		erasure := record.Erasure
+		fmt.Printf("Status: ERASED\n")
+		fmt.Printf("├─ Erasure: %s\n", erasure.Transaction.ID)
+		fmt.Printf("├─ Block: %d\n", erasure.Block.Index)
+		fmt.Printf("├─ Time: %s\n", time.Unix(erasure.Block.Timestamp, 0).Format(time.RFC3339))
+		fmt.Printf("└─ Signed by: %s\n", erasure.Transaction.Sender)
+	case record.Erasure != nil:
+		fmt.Printf("Status: ERASURE PENDING (%s)\n", record.Erasure.Transaction.ID)
+	default:
+		fmt.Printf("Status: ACTIVE (no erasure recorded)\n")
+	}
+}
+
+func handleSubjectList() {
+	keys := storage.GetKeyVault().GetAllSubjectKeys()
+	if len(keys) == 0 {
+		fmt.Println("No subjects found")
+		fmt.Println("   Record one with: chainlog-cli subject record <subject> <data>")
+		return
+	}
+
+	fmt.Printf("Subjects (%d):\n\n", len(keys))
+	for _, subjectKey := range keys {
+		record := bc.GetErasureRecord(subjectKey.KeyID)
+
+		status := "active"
+		if subjectKey.DestroyedAt != 0 {
+			status = fmt.Sprintf("erased %s", time.Unix(subjectKey.DestroyedAt, 0).Format(time.RFC3339))
+		}
+
+		fmt.Printf("%s\n", subjectKey.Subject)
+		fmt.Printf("   Key ID: %s\n", subjectKey.KeyID)
+		fmt.Printf("   Entries: %d\n", len(record.Entries))
+		fmt.Printf("   Status: %s\n", status)
+	}
+}
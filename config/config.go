@@ -0,0 +1,304 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultDataDir = "./chainlog-data"
+	FileName       = "chainlog.toml"
+	HomeEnv        = "CHAINLOG_HOME"
+)
+
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+var globalFlags = []string{"datadir", "config"}
+
+type Config struct {
+	DataDir string
+	Node    NodeConfig
+	Mining  MiningConfig
+	RPC     RPCConfig
+	Mempool MempoolConfig
+	Prune   PruneConfig
+	Log     LogConfig
+
+	// File is the config file the settings were read from, if any.
+	File string
+}
+
+type NodeConfig struct {
+	Listen    string
+	Bootstrap []string
+}
+
+type MiningConfig struct {
+	Enabled bool
+	Address string
+}
+
+type RPCConfig struct {
+	OTLP string
+}
+
+type MempoolConfig struct {
+	MaxTransactions int
+	MaxTxSize       int
+}
+
+// PruneConfig limits how many blocks keep their transactions. A node with
+// either limit set discards older transaction bodies and keeps only the
+// block headers and Merkle roots.
+type PruneConfig struct {
+	KeepBlocks int
+	MaxSizeMB  int
+}
+
+func (p PruneConfig) Enabled() bool {
+	return p.KeepBlocks > 0 || p.MaxSizeMB > 0
+}
+
+type LogConfig struct {
+	Level string
+}
+
+// setting ties one config field to its key in the file, its environment
+// variable and its command-line flag. An empty env or flag means the
+// setting can't be given that way.
+type setting struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	field func(c *Config) interface{}
+}
+
+var settings = []setting{
+	{"data_dir", HomeEnv, "", "directory for all node data",
+		func(c *Config) interface{} { return &c.DataDir }},
+	{"node.listen", "CHAINLOG_LISTEN", "listen", "P2P listen address (host:port)",
+		func(c *Config) interface{} { return &c.Node.Listen }},
+	{"node.bootstrap", "CHAINLOG_BOOTSTRAP", "bootstrap", "comma-separated peers to connect to on start",
+		func(c *Config) interface{} { return &c.Node.Bootstrap }},
+	{"mining.enabled", "CHAINLOG_MINING", "mine", "mine pending transactions in the node (--mine=false to turn off)",
+		func(c *Config) interface{} { return &c.Mining.Enabled }},
+	{"mining.address", "CHAINLOG_MINING_ADDRESS", "mining-address", "address that receives block rewards (default: node wallet)",
+		func(c *Config) interface{} { return &c.Mining.Address }},
+	{"rpc.otlp", "CHAINLOG_OTLP", "otlp", "serve OTLP/HTTP logs on this address (e.g. 127.0.0.1:4318)",
+		func(c *Config) interface{} { return &c.RPC.OTLP }},
+	{"mempool.max_transactions", "CHAINLOG_MEMPOOL_MAX_TX", "mempool-max-tx", "most pending transactions to hold (0 = no limit)",
+		func(c *Config) interface{} { return &c.Mempool.MaxTransactions }},
+	{"mempool.max_tx_size", "CHAINLOG_MEMPOOL_MAX_TX_SIZE", "mempool-max-tx-size", "largest transaction data accepted, in bytes (0 = no limit)",
+		func(c *Config) interface{} { return &c.Mempool.MaxTxSize }},
+	{"prune.keep_blocks", "CHAINLOG_PRUNE_KEEP_BLOCKS", "prune-keep-blocks", "keep transactions of only the newest N blocks (0 = keep all)",
+		func(c *Config) interface{} { return &c.Prune.KeepBlocks }},
+	{"prune.max_size_mb", "CHAINLOG_PRUNE_MAX_SIZE_MB", "prune-max-size-mb", "keep transactions of the newest blocks that fit in this many MB (0 = no limit)",
+		func(c *Config) interface{} { return &c.Prune.MaxSizeMB }},
+	{"log.level", "CHAINLOG_LOG_LEVEL", "log-level", "node log level: " + strings.Join(LogLevels, ", "),
+		func(c *Config) interface{} { return &c.Log.Level }},
+}
+
+func Default() *Config {
+	return &Config{
+		DataDir: DefaultDataDir,
+		Node: NodeConfig{
+			Listen: "localhost:8080",
+		},
+		Mining: MiningConfig{
+			Enabled: true,
+		},
+		Mempool: MempoolConfig{
+			MaxTransactions: 10000,
+			MaxTxSize:       64 * 1024,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the config file, the
+// environment and the --datadir flag, each overriding the one before.
+// Commands that take their own flags apply them on top with BindFlags.
+// The file is the one named by --config, or chainlog.toml in the data
+// directory given by --datadir or CHAINLOG_HOME, or else chainlog.toml in
+// the working directory. Global flags may appear anywhere in args; the
+// remaining arguments are returned in order.
+func Load(args []string) (*Config, []string, error) {
+	flags, rest, err := extractFlags(args, globalFlags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+
+	home := os.Getenv(HomeEnv)
+	if dir, exists := flags["datadir"]; exists {
+		home = dir
+	}
+
+	path := flags["config"]
+	if path == "" {
+		candidate := FileName
+		if home != "" {
+			candidate = filepath.Join(home, FileName)
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+		}
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+	if dir, exists := flags["datadir"]; exists {
+		cfg.DataDir = dir
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, rest, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for key, value := range values {
+		s := findSetting(key)
+		if s == nil {
+			return fmt.Errorf("%s: unknown setting %s", path, key)
+		}
+		if err := setValue(s.field(c), value); err != nil {
+			return fmt.Errorf("%s: %s %v", path, key, err)
+		}
+	}
+	c.File = path
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for _, s := range settings {
+		raw, exists := os.LookupEnv(s.env)
+		if s.env == "" || !exists || raw == "" {
+			continue
+		}
+		if err := parseInto(s.field(c), raw); err != nil {
+			return fmt.Errorf("%s: %v", s.env, err)
+		}
+	}
+	return nil
+}
+
+// BindFlags registers a flag for every setting that has one on fs. The
+// flags write straight into c, so parsing fs layers them over the file
+// and environment.
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+	for _, s := range settings {
+		if s.flag != "" {
+			fs.Var(&flagValue{field: s.field(c)}, s.flag, s.usage)
+		}
+	}
+}
+
+func (c *Config) Validate() error {
+	if c.DataDir == "" {
+		return fmt.Errorf("data_dir must not be empty")
+	}
+	if _, _, err := net.SplitHostPort(c.Node.Listen); err != nil {
+		return fmt.Errorf("node.listen: %v", err)
+	}
+	for _, peer := range c.Node.Bootstrap {
+		if _, _, err := net.SplitHostPort(peer); err != nil {
+			return fmt.Errorf("node.bootstrap: %v", err)
+		}
+	}
+	if c.Mempool.MaxTransactions < 0 || c.Mempool.MaxTxSize < 0 {
+		return fmt.Errorf("mempool limits must not be negative")
+	}
+	if c.Prune.KeepBlocks < 0 || c.Prune.MaxSizeMB < 0 {
+		return fmt.Errorf("prune limits must not be negative")
+	}
+	if !contains(LogLevels, c.Log.Level) {
+		return fmt.Errorf("log.level must be one of %s", strings.Join(LogLevels, ", "))
+	}
+	return nil
+}
+
+// TOML renders the effective configuration in the config file format.
+func (c *Config) TOML() string {
+	var b strings.Builder
+	section := ""
+	for _, s := range settings {
+		key := s.key
+		if dot := strings.Index(key, "."); dot >= 0 {
+			if key[:dot] != section {
+				section = key[:dot]
+				fmt.Fprintf(&b, "\n[%s]\n", section)
+			}
+			key = key[dot+1:]
+		}
+		fmt.Fprintf(&b, "%s = %s\n", key, formatValue(s.field(c)))
+	}
+	return b.String()
+}
+
+func findSetting(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// extractFlags pulls "--name value", "--name=value" and their single-dash
+// forms for the given names out of args, leaving everything else in place.
+func extractFlags(args []string, names []string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || !contains(names, name) {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+	return flags, rest, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
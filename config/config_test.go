@@ -0,0 +1,175 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTest loads a configuration from file, env and args the way a command
+// does, with its own flags parsed over the result.
+func loadTest(t *testing.T, file string, env map[string]string, args []string) (*Config, error) {
+	t.Helper()
+	for _, s := range settings {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	dir := t.TempDir()
+	if file != "" {
+		if err := os.WriteFile(filepath.Join(dir, FileName), []byte(file), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	cfg, rest, err := Load(append([]string{"--datadir", dir}, args...))
+	if err != nil {
+		return nil, err
+	}
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(new(strings.Builder))
+	cfg.BindFlags(flags)
+	if err := flags.Parse(rest); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := `
+[node]
+listen = "0.0.0.0:9000"
+bootstrap = ["a:1", "b:2"]
+
+[mempool]
+max_transactions = 50
+
+[log]
+level = "warn"
+`
+
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		check func(c *Config) (got, want interface{})
+	}{
+		{
+			name:  "defaults",
+			check: func(c *Config) (interface{}, interface{}) { return c.Node.Listen, "localhost:8080" },
+		},
+		{
+			name:  "file over defaults",
+			file:  file,
+			check: func(c *Config) (interface{}, interface{}) { return c.Mempool.MaxTransactions, 50 },
+		},
+		{
+			name:  "file keeps unset defaults",
+			file:  file,
+			check: func(c *Config) (interface{}, interface{}) { return c.Mempool.MaxTxSize, 64 * 1024 },
+		},
+		{
+			name:  "file list",
+			file:  file,
+			check: func(c *Config) (interface{}, interface{}) { return c.Node.Bootstrap, []string{"a:1", "b:2"} },
+		},
+		{
+			name:  "env over file",
+			file:  file,
+			env:   map[string]string{"CHAINLOG_LISTEN": "127.0.0.1:9100"},
+			check: func(c *Config) (interface{}, interface{}) { return c.Node.Listen, "127.0.0.1:9100" },
+		},
+		{
+			name:  "env list",
+			env:   map[string]string{"CHAINLOG_BOOTSTRAP": "c:3,d:4"},
+			check: func(c *Config) (interface{}, interface{}) { return c.Node.Bootstrap, []string{"c:3", "d:4"} },
+		},
+		{
+			name:  "flag over env",
+			file:  file,
+			env:   map[string]string{"CHAINLOG_LOG_LEVEL": "error"},
+			args:  []string{"--log-level", "debug"},
+			check: func(c *Config) (interface{}, interface{}) { return c.Log.Level, "debug" },
+		},
+		{
+			name:  "boolean flag",
+			args:  []string{"--mine=false"},
+			check: func(c *Config) (interface{}, interface{}) { return c.Mining.Enabled, false },
+		},
+		{
+			name:  "datadir flag over env",
+			env:   map[string]string{HomeEnv: "elsewhere"},
+			check: func(c *Config) (interface{}, interface{}) { return c.DataDir != "elsewhere", true },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTest(t, tt.file, tt.env, tt.args)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if got, want := tt.check(cfg); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown key", file: "[node]\nport = 1\n", want: "unknown setting node.port"},
+		{name: "wrong type in file", file: "[mempool]\nmax_transactions = \"many\"\n", want: "must be an integer"},
+		{name: "malformed file", file: "[node\n", want: "unterminated section header"},
+		{name: "duplicate key", file: "[log]\nlevel = \"info\"\nlevel = \"warn\"\n", want: "duplicate key"},
+		{name: "invalid env", env: map[string]string{"CHAINLOG_MINING": "sometimes"}, want: "CHAINLOG_MINING"},
+		{name: "negative limit", file: "[mempool]\nmax_tx_size = -1\n", want: "must not be negative"},
+		{name: "unknown log level", env: map[string]string{"CHAINLOG_LOG_LEVEL": "loud"}, want: "log.level"},
+		{name: "listen without port", args: []string{"--listen", "localhost"}, want: "node.listen"},
+		{name: "invalid flag value", args: []string{"--prune-keep-blocks", "few"}, want: "prune-keep-blocks"},
+		{name: "global flag without value", args: []string{"--config"}, want: "needs a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTest(t, tt.file, tt.env, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one about %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	cfg := Default()
+	cfg.Node.Bootstrap = []string{"a:1"}
+	cfg.Mining.Enabled = false
+	cfg.Prune.KeepBlocks = 10
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(cfg.TOML()), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	loaded := Default()
+	if err := loaded.loadFile(path); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	loaded.File = ""
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("config show output loads as %+v, want %+v", loaded, cfg)
+	}
+}
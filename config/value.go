@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// setValue stores a value parsed from the config file into field, which
+// is a pointer to one of the Config fields.
+func setValue(field interface{}, value interface{}) error {
+	switch target := field.(type) {
+	case *string:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		*target = s
+	case *bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("must be true or false")
+		}
+		*target = b
+	case *int:
+		n, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("must be an integer")
+		}
+		*target = int(n)
+	case *[]string:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("must be a list of strings")
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("must be a list of strings")
+			}
+			list = append(list, s)
+		}
+		*target = list
+	}
+	return nil
+}
+
+// parseInto stores a value given as text, from the environment or a flag,
+// into field. Lists are comma-separated.
+func parseInto(field interface{}, raw string) error {
+	switch target := field.(type) {
+	case *string:
+		*target = raw
+	case *bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		*target = b
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		*target = n
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target = list
+	}
+	return nil
+}
+
+func formatValue(field interface{}) string {
+	switch target := field.(type) {
+	case *string:
+		return strconv.Quote(*target)
+	case *bool:
+		return strconv.FormatBool(*target)
+	case *int:
+		return strconv.Itoa(*target)
+	case *[]string:
+		quoted := make([]string, len(*target))
+		for i, item := range *target {
+			quoted[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return ""
+}
+
+type flagValue struct {
+	field interface{}
+}
+
+// String shows the value as it would be typed on the command line; the
+// flag package also uses it to decide whether to print a default.
+func (v *flagValue) String() string {
+	switch target := v.field.(type) {
+	case *string:
+		return *target
+	case *[]string:
+		return strings.Join(*target, ",")
+	case *bool:
+		if !*target {
+			return ""
+		}
+	case *int:
+		if *target == 0 {
+			return ""
+		}
+	}
+	if v.field == nil {
+		return ""
+	}
+	return formatValue(v.field)
+}
+
+func (v *flagValue) Set(raw string) error {
+	return parseInto(v.field, raw)
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	_, ok := v.field.(*bool)
+	return ok
+}
@@ -48,7 +48,7 @@ func (dm *DifficultyManager) CalculateNewDifficulty() int {
 	if averageBlockTime < int64(dm.TargetBlockTime.Seconds())/2 {
 		return currentDifficulty + 1
 	} else if averageBlockTime > int64(dm.TargetBlockTime.Seconds())*2 {
-		if currentDifficulty > 1 {
+		if currentDifficulty > core.MinDifficulty {
 			return currentDifficulty - 1
 		}
 	}
@@ -65,4 +65,4 @@ func (dm *DifficultyManager) UpdateDifficulty() {
 	}
 }
 
-const initialDifficulty = 2
+const initialDifficulty = core.MinDifficulty
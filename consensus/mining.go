@@ -33,14 +33,18 @@ func (m *Miner) MineBlock() (*core.Block, error) {
 	
 	lastBlock := m.Blockchain.GetLastBlock()
 	
+	rewardTx := m.createRewardTransaction()
+	transactions := append([]*core.Transaction{rewardTx}, m.Blockchain.PendingTx...)
+	
 	newBlock := &core.Block{
 		Index:        lastBlock.Index + 1,
 		Timestamp:    time.Now().Unix(),
-		Transactions: m.Blockchain.PendingTx,
+		Transactions: transactions,
 		PrevHash:     lastBlock.Hash,
 		Difficulty:   m.Blockchain.Difficulty,
 		Miner:        m.Address,
 	}
+	newBlock.MerkleRoot = newBlock.ComputeMerkleRoot()
 	
 	pow := NewProofOfWork(newBlock, m.Blockchain.Difficulty)
 	nonce, hash := pow.Run()
@@ -52,9 +56,6 @@ func (m *Miner) MineBlock() (*core.Block, error) {
 	newBlock.Nonce = nonce
 	newBlock.Hash = hash
 	
-	rewardTx := m.createRewardTransaction()
-	newBlock.Transactions = append([]*core.Transaction{rewardTx}, newBlock.Transactions...)
-	
 	return newBlock, nil
 }
 
@@ -87,7 +88,7 @@ func (m *Miner) StartMining() {
 				if len(m.Blockchain.PendingTx) > 0 {
 					block, err := m.MineBlock()
 					if err == nil {
-						m.Blockchain.Chain = append(m.Blockchain.Chain, block)
+						m.Blockchain.AppendBlock(block)
 						m.Blockchain.ClearPendingTransactions()
 						currentHeight := int64(m.Blockchain.GetBlockCount() - 1)
 						fmt.Printf("Mined block %d! Reward: %d LogCoins\n", 
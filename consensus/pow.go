@@ -3,7 +3,6 @@ package consensus
 import (
 	"chainlog/core"
 	_ "crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"math/big"
 	_ "strings"
@@ -52,10 +51,7 @@ func (pow *ProofOfWork) Run() (int64, string) {
 }
 
 func (pow *ProofOfWork) ValidateHash(hash string) bool {
-	hashBytes, _ := hex.DecodeString(hash)
-	hashInt := new(big.Int).SetBytes(hashBytes)
-	
-	return hashInt.Cmp(pow.Target) == -1 
+	return core.MeetsDifficulty(hash, pow.Difficulty)
 }
 
 func (pow *ProofOfWork) Validate() bool {
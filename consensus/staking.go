@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"chainlog/storage"
 	"fmt"
 	"sync"
 	"encoding/json"
@@ -75,11 +76,11 @@ func (sm *StakingManager) SaveStakingData() error {
 		return fmt.Errorf("failed to marshal staking data: %v", err)
 	}
 	
-	if err := os.MkdirAll("chainlog-data", 0755); err != nil {
+	if err := os.MkdirAll(storage.DataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %v", err)
 	}
 	
-	if err := os.WriteFile("chainlog-data/staking.json", data, 0644); err != nil {
+	if err := storage.WriteFileAtomic(storage.Path(storage.StakingFile), data, 0644); err != nil {
 		return fmt.Errorf("failed to save staking data: %v", err)
 	}
 	
@@ -91,7 +92,7 @@ func (sm *StakingManager) LoadStakingData() error {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 	
-	data, err := os.ReadFile("chainlog-data/staking.json")
+	data, err := os.ReadFile(storage.Path(storage.StakingFile))
 	if err != nil {
 		if os.IsNotExist(err) {
 			fmt.Println("No existing staking data found, starting fresh")
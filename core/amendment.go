@@ -0,0 +1,190 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"fmt"
+)
+
+const (
+	DelegationGrant  = "grant"
+	DelegationRevoke = "revoke"
+)
+
+type Revision struct {
+	Transaction *Transaction
+	Block       *Block
+}
+
+type RevisionHistory struct {
+	Original  *Revision
+	Revisions []*Revision
+}
+
+func NewAmendmentTransaction(reference string, data string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("amendment must reference a transaction")
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:      AmendmentTx,
+		Data:      data,
+		Sender:    wallet.GetAddress(),
+		Fee:       fee,
+		Reference: reference,
+	}, wallet)
+}
+
+func NewDelegationTransaction(delegate string, action string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if action != DelegationGrant && action != DelegationRevoke {
+		return nil, fmt.Errorf("invalid delegation action: %s", action)
+	}
+
+	if delegate == wallet.GetAddress() {
+		return nil, fmt.Errorf("cannot delegate to self")
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     DelegationTx,
+		Data:     action,
+		Sender:   wallet.GetAddress(),
+		Receiver: delegate,
+		Fee:      fee,
+	}, wallet)
+}
+
+func (bc *Blockchain) IsDelegate(owner string, delegate string) bool {
+	return bc.isDelegateAt(owner, delegate, bc.GetLastBlock().Index)
+}
+
+func (bc *Blockchain) isDelegateAt(owner string, delegate string, height int64) bool {
+	granted := false
+	index := bc.Index()
+	for _, txID := range index.Types[DelegationTx] {
+		tx, block := bc.TransactionAt(index.Transactions[txID])
+		if tx == nil {
+			continue
+		}
+		if block.Index > height {
+			break
+		}
+		if tx.Sender == owner && tx.Receiver == delegate {
+			granted = tx.Data == DelegationGrant
+		}
+	}
+	return granted
+}
+
+func (bc *Blockchain) findRoot(tx *Transaction) (*Transaction, *Block, error) {
+	seen := make(map[string]bool)
+	current := tx
+	for current.Type == AmendmentTx {
+		if seen[current.ID] {
+			return nil, nil, fmt.Errorf("amendment cycle detected at %.16s...", current.ID)
+		}
+		seen[current.ID] = true
+
+		parent, _ := bc.FindReference(current.Reference)
+		if parent == nil {
+			return nil, nil, fmt.Errorf("referenced transaction %.16s... not found", current.Reference)
+		}
+		current = parent
+	}
+
+	root, block := bc.FindReference(current.ID)
+	return root, block, nil
+}
+
+func (bc *Blockchain) ValidateAmendment(tx *Transaction) error {
+	if tx.Type != AmendmentTx {
+		return fmt.Errorf("transaction is not an amendment")
+	}
+
+	if tx.Reference == "" {
+		return fmt.Errorf("amendment has no reference")
+	}
+
+	if tx.PublicKey == "" && tx.Multisig == nil {
+		return fmt.Errorf("amendment must be signed")
+	}
+
+	referenced, block := bc.FindReference(tx.Reference)
+	if referenced == nil {
+		return fmt.Errorf("referenced transaction %.16s... not found", tx.Reference)
+	}
+
+	if block == nil {
+		return fmt.Errorf("referenced transaction %.16s... is not confirmed yet", tx.Reference)
+	}
+
+	root, _, err := bc.findRoot(referenced)
+	if err != nil {
+		return err
+	}
+
+	if root.Type != DataTx {
+		return fmt.Errorf("only data entries can be amended, got %s", root.Type)
+	}
+
+	if tx.Sender != root.Sender && !bc.IsDelegate(root.Sender, tx.Sender) {
+		return fmt.Errorf("%.8s... is neither the original sender nor a delegate", tx.Sender)
+	}
+
+	return nil
+}
+
+func (bc *Blockchain) ResolveRevisions(txID string) (*RevisionHistory, error) {
+	tx, _ := bc.FindReference(txID)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found: %s", txID)
+	}
+
+	root, rootBlock, err := bc.findRoot(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &RevisionHistory{
+		Original: &Revision{Transaction: root, Block: rootBlock},
+	}
+
+	members := map[string]bool{root.ID: true}
+	collect := func(candidate *Transaction, block *Block) {
+		if candidate.Type != AmendmentTx || !members[candidate.Reference] {
+			return
+		}
+
+		if candidate.Sender != root.Sender {
+			height := bc.GetLastBlock().Index
+			if block != nil {
+				height = block.Index
+			}
+			if !bc.isDelegateAt(root.Sender, candidate.Sender, height) {
+				return
+			}
+		}
+
+		members[candidate.ID] = true
+		history.Revisions = append(history.Revisions, &Revision{Transaction: candidate, Block: block})
+	}
+
+	for _, block := range bc.Chain {
+		for _, candidate := range block.Transactions {
+			collect(candidate, block)
+		}
+	}
+
+	for _, candidate := range bc.PendingTx {
+		collect(candidate, nil)
+	}
+
+	return history, nil
+}
+
+func (rh *RevisionHistory) Current() *Revision {
+	for i := len(rh.Revisions) - 1; i >= 0; i-- {
+		if rh.Revisions[i].Block != nil {
+			return rh.Revisions[i]
+		}
+	}
+	return rh.Original
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"fmt"
+)
+
+type Attestation struct {
+	Transaction *Transaction
+	Block       *Block
+}
+
+func NewAttestationTransaction(reference string, note string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if reference == "" {
+		return nil, fmt.Errorf("attestation must reference a transaction")
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:      AttestationTx,
+		Data:      note,
+		Sender:    wallet.GetAddress(),
+		Fee:       fee,
+		Reference: reference,
+	}, wallet)
+}
+
+func (bc *Blockchain) ValidateAttestation(tx *Transaction) error {
+	if tx.Type != AttestationTx {
+		return fmt.Errorf("transaction is not an attestation")
+	}
+
+	if tx.Reference == "" {
+		return fmt.Errorf("attestation has no reference")
+	}
+
+	if tx.Reference == tx.ID {
+		return fmt.Errorf("attestation cannot reference itself")
+	}
+
+	if tx.PublicKey == "" && tx.Multisig == nil {
+		return fmt.Errorf("attestation must be signed by the witness")
+	}
+
+	referenced, block := bc.FindReference(tx.Reference)
+	if referenced == nil {
+		return fmt.Errorf("referenced transaction %.16s... not found", tx.Reference)
+	}
+
+	if block == nil {
+		return fmt.Errorf("referenced transaction %.16s... is not confirmed yet", tx.Reference)
+	}
+
+	return nil
+}
+
+func (bc *Blockchain) GetAttestations(txID string) []*Attestation {
+	var attestations []*Attestation
+
+	index := bc.Index()
+	for _, attestationID := range index.Types[AttestationTx] {
+		tx, block := bc.TransactionAt(index.Transactions[attestationID])
+		if tx != nil && tx.Reference == txID {
+			attestations = append(attestations, &Attestation{Transaction: tx, Block: block})
+		}
+	}
+
+	for _, tx := range bc.PendingTx {
+		if tx.Type == AttestationTx && tx.Reference == txID {
+			attestations = append(attestations, &Attestation{Transaction: tx})
+		}
+	}
+
+	return attestations
+}
+
+func (a *Attestation) IsConfirmed() bool {
+	return a.Block != nil
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"encoding/hex"
+	"fmt"
+)
+
+type BatchOptions struct {
+	SchemaID string
+	Stream   string
+	Sequence uint64
+}
+
+func NewBatchTransaction(root string, options *BatchOptions, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if err := validateMerkleRoot(root); err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		Type:   BatchTx,
+		Data:   root,
+		Sender: wallet.GetAddress(),
+		Fee:    fee,
+	}
+
+	if options != nil {
+		if options.SchemaID != "" {
+			tx.SchemaID = options.SchemaID
+			tx.Encoding = EncodingJSON
+		}
+		if options.Stream != "" {
+			if err := ValidateStreamName(options.Stream); err != nil {
+				return nil, err
+			}
+			tx.Stream = options.Stream
+			tx.Sequence = options.Sequence
+		}
+	}
+
+	return newSignedTransaction(tx, wallet)
+}
+
+func (bc *Blockchain) ValidateBatch(tx *Transaction) error {
+	if tx.Type != BatchTx {
+		return fmt.Errorf("transaction is not a batch")
+	}
+
+	if tx.PublicKey == "" && tx.Multisig == nil {
+		return fmt.Errorf("batch must be signed")
+	}
+
+	if err := validateMerkleRoot(tx.Data); err != nil {
+		return err
+	}
+
+	if tx.SchemaID != "" {
+		if tx.Encoding != EncodingJSON {
+			return fmt.Errorf("structured batch leaves must be %s encoded", EncodingJSON)
+		}
+		if _, _, err := bc.GetSchema(tx.SchemaID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateMerkleRoot(root string) error {
+	if len(root) != 64 {
+		return fmt.Errorf("batch root must be 64 hex characters")
+	}
+	if _, err := hex.DecodeString(root); err != nil {
+		return fmt.Errorf("batch root is not valid hexadecimal")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"time"
 )
 
@@ -17,6 +18,35 @@ type Block struct {
 	Nonce        int64         
 	Difficulty   int           
 	Miner        string        
+	MerkleRoot   string `json:",omitempty"`
+
+	// Pruned blocks keep their header and Merkle root but not their
+	// transactions, so only the hash chain can be checked for them.
+	Pruned bool `json:",omitempty"`
+	// Kept holds the positions the transactions a pruned block still
+	// carries had before it was pruned.
+	Kept []int `json:",omitempty"`
+	// Dropped records the transactions a pruned block no longer carries.
+	Dropped []*DroppedTx `json:",omitempty"`
+}
+
+// DroppedTx is what a pruned block keeps of a transaction it dropped:
+// enough to find it by ID and to check transactions that refer to it.
+type DroppedTx struct {
+	ID       string          `json:"id"`
+	Type     TransactionType `json:"type"`
+	Sender   string          `json:"sender"`
+	Position int             `json:"position"`
+}
+
+type BlockHeader struct {
+	Index      int64  `json:"index"`
+	Timestamp  int64  `json:"timestamp"`
+	Data       string `json:"data"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+	Nonce      int64  `json:"nonce"`
+	MerkleRoot string `json:"merkle_root,omitempty"`
 }
 
 func NewBlock(index int64, data string, prevHash string) *Block {
@@ -35,12 +65,140 @@ func NewBlock(index int64, data string, prevHash string) *Block {
 }
 
 func (b *Block) CalculateHash() string {
+	return calculateBlockHash(b.Index, b.Timestamp, b.Data, b.PrevHash, b.Nonce, b.MerkleRoot)
+}
+
+func (b *Block) Header() *BlockHeader {
+	return &BlockHeader{
+		Index:      b.Index,
+		Timestamp:  b.Timestamp,
+		Data:       b.Data,
+		PrevHash:   b.PrevHash,
+		Hash:       b.Hash,
+		Nonce:      b.Nonce,
+		MerkleRoot: b.MerkleRoot,
+	}
+}
+
+// NewPrunedBlock rebuilds a block from its header alone.
+func NewPrunedBlock(h *BlockHeader) *Block {
+	return &Block{
+		Index:      h.Index,
+		Timestamp:  h.Timestamp,
+		Data:       h.Data,
+		PrevHash:   h.PrevHash,
+		Hash:       h.Hash,
+		Nonce:      h.Nonce,
+		MerkleRoot: h.MerkleRoot,
+		Pruned:     true,
+	}
+}
+
+// Prune drops the block's transactions, keeping what its hash covers and
+// the transactions that newer ones are validated against.
+func (b *Block) Prune() {
+	if b.Pruned {
+		return
+	}
+
+	var kept []*Transaction
+	var positions []int
+	for position, tx := range b.Transactions {
+		if tx.KeepsState() {
+			kept = append(kept, tx)
+			positions = append(positions, position)
+		} else {
+			b.Dropped = append(b.Dropped, &DroppedTx{ID: tx.ID, Type: tx.Type, Sender: tx.Sender, Position: position})
+		}
+	}
+	b.Transactions = kept
+	b.Kept = positions
+	b.Pruned = true
+}
+
+// Position returns where the i-th transaction of the block was before the
+// block was pruned, which is where the index locates it.
+func (b *Block) Position(i int) int {
+	if b.Pruned && i < len(b.Kept) {
+		return b.Kept[i]
+	}
+	return i
+}
+
+// KeepsState reports whether pruning must keep the transaction: schemas,
+// delegations, stream entries and subject-keyed entries and erasures are
+// what later transactions are checked against, amendments link revisions
+// to their original and batches are what inclusion proofs end in.
+func (tx *Transaction) KeepsState() bool {
+	switch tx.Type {
+	case SchemaTx, DelegationTx, ErasureTx, AmendmentTx, BatchTx:
+		return true
+	}
+	return tx.Stream != "" || tx.SubjectKeyID() != ""
+}
+
+// VerifyHeaders checks that headers form a chain from the genesis block:
+// heights run from zero, every header hashes to its own hash, carries the
+// proof of work and points to the one before it. It says nothing about
+// which chain the headers belong to; the caller must check that against a
+// block hash it trusts.
+func VerifyHeaders(headers []*BlockHeader) error {
+	for i, header := range headers {
+		if header.Index != int64(i) {
+			return fmt.Errorf("header %d has height %d", i, header.Index)
+		}
+		if header.CalculateHash() != header.Hash {
+			return fmt.Errorf("header %d does not hash to %.16s...", i, header.Hash)
+		}
+		if !header.HasValidWork() {
+			return fmt.Errorf("header %d does not meet the proof of work", i)
+		}
+		if i == 0 && header.PrevHash != "" {
+			return fmt.Errorf("genesis header has a previous hash")
+		}
+		if i > 0 && header.PrevHash != headers[i-1].Hash {
+			return fmt.Errorf("header %d does not point to header %d", i, i-1)
+		}
+	}
+	return nil
+}
+
+// MinDifficulty is the proof of work every block after genesis carries:
+// the number of leading zero bits its hash must have. The genesis block is
+// created, not mined, and is exempt.
+const MinDifficulty = 2
+
+// MeetsDifficulty reports whether hash is below the proof-of-work target
+// for the given difficulty.
+func MeetsDifficulty(hash string, difficulty int) bool {
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return false
+	}
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-difficulty))
+	return new(big.Int).SetBytes(hashBytes).Cmp(target) < 0
+}
+
+// HasValidWork reports whether the header meets MinDifficulty.
+func (h *BlockHeader) HasValidWork() bool {
+	return h.Index == 0 || MeetsDifficulty(h.Hash, MinDifficulty)
+}
+
+func (h *BlockHeader) CalculateHash() string {
+	return calculateBlockHash(h.Index, h.Timestamp, h.Data, h.PrevHash, h.Nonce, h.MerkleRoot)
+}
+
+func calculateBlockHash(index int64, timestamp int64, data string, prevHash string, nonce int64, merkleRoot string) string {
 	blockData := fmt.Sprintf("%d%d%s%s%d", 
-		b.Index, 
-		b.Timestamp, 
-		b.Data, 
-		b.PrevHash, 
-		b.Nonce)
+		index, 
+		timestamp, 
+		data, 
+		prevHash, 
+		nonce)
+	
+	if merkleRoot != "" {
+		blockData += merkleRoot
+	}
 	
 	hash := sha256.Sum256([]byte(blockData))
 	
@@ -66,6 +224,10 @@ func (b *Block) Display() {
 	
 	fmt.Printf("│ Nonce: %d\n", b.Nonce)
 	
+	if b.MerkleRoot != "" {
+		fmt.Printf("│ Merkle Root: %s...\n", b.MerkleRoot[:16])
+	}
+	
 	if b.Miner == "" {
 		fmt.Printf("│ Miner: [UNKNOWN]\n")
 	} else {
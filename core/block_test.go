@@ -0,0 +1,99 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMeetsDifficulty(t *testing.T) {
+	tests := []struct {
+		hash       string
+		difficulty int
+		want       bool
+	}{
+		{"3f" + strings.Repeat("f", 62), 2, true},
+		{"40" + strings.Repeat("0", 62), 2, false},
+		{"0f" + strings.Repeat("f", 62), 4, true},
+		{"10" + strings.Repeat("0", 62), 4, false},
+		{strings.Repeat("f", 64), 0, true},
+		{strings.Repeat("0", 63), 2, false},
+		{"not hex", 2, false},
+	}
+	for _, tt := range tests {
+		if got := MeetsDifficulty(tt.hash, tt.difficulty); got != tt.want {
+			t.Errorf("MeetsDifficulty(%.8s..., %d) = %v, want %v", tt.hash, tt.difficulty, got, tt.want)
+		}
+	}
+}
+
+// testHeaders mines a header chain of n blocks on top of a genesis block.
+func testHeaders(n int) []*BlockHeader {
+	genesis := NewBlock(0, "genesis", "")
+	genesis.Hash = genesis.CalculateHash()
+	headers := []*BlockHeader{genesis.Header()}
+
+	for i := 1; i <= n; i++ {
+		block := NewBlock(int64(i), "", headers[i-1].Hash)
+		for block.Hash = block.CalculateHash(); !MeetsDifficulty(block.Hash, MinDifficulty); block.Hash = block.CalculateHash() {
+			block.Nonce++
+		}
+		headers = append(headers, block.Header())
+	}
+	return headers
+}
+
+func TestVerifyHeaders(t *testing.T) {
+	if err := VerifyHeaders(testHeaders(4)); err != nil {
+		t.Fatalf("VerifyHeaders: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(headers []*BlockHeader) []*BlockHeader
+		want   string
+	}{
+		{
+			name:   "missing genesis",
+			tamper: func(headers []*BlockHeader) []*BlockHeader { return headers[1:] },
+			want:   "has height",
+		},
+		{
+			name: "altered header",
+			tamper: func(headers []*BlockHeader) []*BlockHeader {
+				headers[2].Timestamp++
+				return headers
+			},
+			want: "does not hash",
+		},
+		{
+			name: "no proof of work",
+			tamper: func(headers []*BlockHeader) []*BlockHeader {
+				h := headers[3]
+				for h.Hash = h.CalculateHash(); MeetsDifficulty(h.Hash, MinDifficulty); h.Hash = h.CalculateHash() {
+					h.Nonce++
+				}
+				return headers
+			},
+			want: "proof of work",
+		},
+		{
+			name: "broken link",
+			tamper: func(headers []*BlockHeader) []*BlockHeader {
+				h := headers[3]
+				h.PrevHash = headers[1].Hash
+				for h.Hash = h.CalculateHash(); !MeetsDifficulty(h.Hash, MinDifficulty); h.Hash = h.CalculateHash() {
+					h.Nonce++
+				}
+				return headers
+			},
+			want: "does not point",
+		},
+	}
+
+	for _, tt := range tests {
+		err := VerifyHeaders(tt.tamper(testHeaders(4)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: VerifyHeaders error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
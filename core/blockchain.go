@@ -2,6 +2,8 @@ package core
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 )
 
 type Blockchain struct {
@@ -9,6 +11,13 @@ type Blockchain struct {
 	PendingTx   []*Transaction  
 	Difficulty  int             
 	BlockReward uint64          
+
+	// Mempool limits; zero means unlimited.
+	MaxPendingTx int
+	MaxTxSize    int
+	
+	index   *ChainIndex
+	indexMu sync.Mutex
 }
 
 func NewBlockchain() *Blockchain {
@@ -33,7 +42,7 @@ func (bc *Blockchain) AddBlock(data string) {
 	prevBlock := bc.Chain[len(bc.Chain)-1] 
 	newBlock := NewBlock(prevBlock.Index+1, data, prevBlock.Hash)
 	
-	bc.Chain = append(bc.Chain, newBlock)
+	bc.AppendBlock(newBlock)
 	fmt.Printf("Added Block %d to chain\n", newBlock.Index)
 }
 
@@ -65,6 +74,11 @@ func (bc *Blockchain) IsValid() bool {
 			return false
 		}
 		
+		if !currentBlock.Pruned && currentBlock.MerkleRoot != "" && currentBlock.MerkleRoot != currentBlock.ComputeMerkleRoot() {
+			fmt.Printf("Block %d transactions do not match its merkle root!\n", currentBlock.Index)
+			return false
+		}
+		
 		if currentBlock.PrevHash != previousBlock.Hash {
 			fmt.Printf("Block %d points to wrong previous hash!\n", currentBlock.Index)
 			return false
@@ -79,6 +93,17 @@ func (bc *Blockchain) GetBlockCount() int {
 	return len(bc.Chain)
 }
 
+// PrunedHeight returns the height from which every block still has its
+// transactions, or zero if none have been pruned.
+func (bc *Blockchain) PrunedHeight() int64 {
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		if bc.Chain[i].Pruned {
+			return int64(i + 1)
+		}
+	}
+	return 0
+}
+
 func (bc *Blockchain) GetPendingTransactions() []*Transaction {
 	return bc.PendingTx
 }
@@ -86,3 +111,46 @@ func (bc *Blockchain) GetPendingTransactions() []*Transaction {
 func (bc *Blockchain) ClearPendingTransactions() {
 	bc.PendingTx = []*Transaction{}
 }
+
+func (bc *Blockchain) CheckMempoolLimits(tx *Transaction) error {
+	if bc.MaxTxSize > 0 && len(tx.Data) > bc.MaxTxSize {
+		return fmt.Errorf("transaction data is %d bytes, limit is %d", len(tx.Data), bc.MaxTxSize)
+	}
+	if bc.MaxPendingTx > 0 && len(bc.PendingTx) >= bc.MaxPendingTx {
+		return fmt.Errorf("mempool is full (%d pending transactions)", len(bc.PendingTx))
+	}
+	return nil
+}
+
+func (bc *Blockchain) FindTransaction(txID string) (*Transaction, *Block) {
+	if location, exists := bc.Index().Transactions[txID]; exists {
+		return bc.TransactionAt(location)
+	}
+
+	for _, tx := range bc.PendingTx {
+		if tx.ID == txID {
+			return tx, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (bc *Blockchain) FindTransactionByPrefix(prefix string) (*Transaction, *Block) {
+	if prefix == "" {
+		return nil, nil
+	}
+
+	index := bc.Index()
+	if txID, found := index.FindByPrefix(prefix); found {
+		return bc.TransactionAt(index.Transactions[txID])
+	}
+
+	for _, tx := range bc.PendingTx {
+		if strings.HasPrefix(tx.ID, prefix) {
+			return tx, nil
+		}
+	}
+
+	return nil, nil
+}
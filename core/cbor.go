@@ -0,0 +1,293 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+
+	cborMaxDepth = 64
+)
+
+func EncodeCBOR(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeCBORValue(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeCBORHead(buf *bytes.Buffer, major byte, length uint64) {
+	switch {
+	case length < 24:
+		buf.WriteByte(major<<5 | byte(length))
+	case length <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	case length <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, length)
+	}
+}
+
+func encodeCBORValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case int:
+		encodeCBORInt(buf, int64(v))
+	case int64:
+		encodeCBORInt(buf, v)
+	case uint64:
+		encodeCBORHead(buf, cborUnsigned, v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			encodeCBORInt(buf, int64(v))
+			return nil
+		}
+		buf.WriteByte(cborSimple<<5 | 27)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case string:
+		encodeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		encodeCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case []interface{}:
+		encodeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBORValue(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		encodeCBORHead(buf, cborMap, uint64(len(v)))
+		for _, key := range keys {
+			encodeCBORHead(buf, cborText, uint64(len(key)))
+			buf.WriteString(key)
+			if err := encodeCBORValue(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", value)
+	}
+	return nil
+}
+
+func encodeCBORInt(buf *bytes.Buffer, v int64) {
+	if v >= 0 {
+		encodeCBORHead(buf, cborUnsigned, uint64(v))
+	} else {
+		encodeCBORHead(buf, cborNegative, uint64(-(v + 1)))
+	}
+}
+
+func DecodeCBOR(data []byte) (interface{}, error) {
+	decoder := &cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.pos != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(data)-decoder.pos)
+	}
+	return value, nil
+}
+
+// SplitCBORSequence splits a CBOR sequence (RFC 8742), a plain
+// concatenation of encoded items, into the bytes of each item.
+func SplitCBORSequence(data []byte) ([][]byte, error) {
+	decoder := &cborDecoder{data: data}
+	var items [][]byte
+	for decoder.pos < len(data) {
+		start := decoder.pos
+		if _, err := decoder.decode(0); err != nil {
+			return nil, fmt.Errorf("cbor: item %d: %v", len(items), err)
+		}
+		items = append(items, data[start:decoder.pos])
+	}
+	return items, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	chunk := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return chunk, nil
+}
+
+func (d *cborDecoder) readHead() (byte, byte, uint64, error) {
+	initial, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major := initial[0] >> 5
+	info := initial[0] & 0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		raw, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var value uint64
+		for _, b := range raw {
+			value = value<<8 | uint64(b)
+		}
+		return major, info, value, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: indefinite lengths are not supported")
+	}
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("cbor: nesting too deep")
+	}
+
+	major, info, arg, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer overflow")
+		}
+		return -1 - int64(arg), nil
+	case cborBytes:
+		raw, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, raw...), nil
+	case cborText:
+		raw, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	case cborArray:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("cbor: array length exceeds data")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, fmt.Errorf("cbor: map length exceeds data")
+		}
+		entries := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("cbor: map keys must be text strings")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			entries[keyString] = value
+		}
+		return entries, nil
+	case cborTag:
+		return d.decode(depth + 1)
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return float16ToFloat64(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		default:
+			return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+}
+
+func float16ToFloat64(bits uint16) float64 {
+	sign := 1.0
+	if bits&0x8000 != 0 {
+		sign = -1.0
+	}
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(mantissa+1024, exponent-25)
+	}
+}
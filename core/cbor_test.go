@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"zero", int64(0), "00"},
+		{"largest immediate", int64(23), "17"},
+		{"one byte", int64(24), "1818"},
+		{"two bytes", int64(1000), "1903e8"},
+		{"four bytes", int64(1000000), "1a000f4240"},
+		{"eight bytes", uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{"negative", int64(-1), "20"},
+		{"negative two bytes", int64(-1000), "3903e7"},
+		{"integral float", 3.0, "03"},
+		{"float", 1.1, "fb3ff199999999999a"},
+		{"false", false, "f4"},
+		{"true", true, "f5"},
+		{"null", nil, "f6"},
+		{"text", "a", "6161"},
+		{"bytes", []byte{1, 2}, "420102"},
+		{"empty array", []interface{}{}, "80"},
+		{"array", []interface{}{int64(1), int64(2), int64(3)}, "83010203"},
+		{"map with sorted keys", map[string]interface{}{"b": []interface{}{int64(2), int64(3)}, "a": int64(1)}, "a26161016162820203"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeCBOR(tt.value)
+			if err != nil {
+				t.Fatalf("EncodeCBOR: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("EncodeCBOR = %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeCBORUnsupportedType(t *testing.T) {
+	if _, err := EncodeCBOR(struct{}{}); err == nil {
+		t.Error("EncodeCBOR accepted a struct")
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	values := []interface{}{
+		int64(0),
+		int64(math.MaxInt64),
+		int64(math.MinInt64),
+		uint64(math.MaxUint64),
+		1.5,
+		-0.25,
+		"",
+		"chainlog ✓",
+		[]byte{},
+		[]byte{0, 255},
+		true,
+		nil,
+		[]interface{}{int64(1), "two", []interface{}{nil}},
+		map[string]interface{}{
+			"level":  "error",
+			"count":  int64(42),
+			"nested": map[string]interface{}{"ok": false},
+			"tags":   []interface{}{"a", "b"},
+		},
+	}
+
+	for _, value := range values {
+		data, err := EncodeCBOR(value)
+		if err != nil {
+			t.Fatalf("EncodeCBOR(%#v): %v", value, err)
+		}
+		got, err := DecodeCBOR(data)
+		if err != nil {
+			t.Fatalf("DecodeCBOR(%x): %v", data, err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("round trip of %#v gave %#v", value, got)
+		}
+	}
+}
+
+func TestDecodeCBORFloats(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"f93c00", 1.0},
+		{"f9c400", -4.0},
+		{"f90001", 5.960464477539063e-8},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.input)
+		got, err := DecodeCBOR(data)
+		if err != nil {
+			t.Fatalf("DecodeCBOR(%s): %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("DecodeCBOR(%s) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	data, _ := hex.DecodeString("f97e00")
+	got, err := DecodeCBOR(data)
+	if err != nil {
+		t.Fatalf("DecodeCBOR(f97e00): %v", err)
+	}
+	if f, ok := got.(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("DecodeCBOR(f97e00) = %v, want NaN", got)
+	}
+}
+
+func TestDecodeCBORSkipsTags(t *testing.T) {
+	data, _ := hex.DecodeString("c11a514b67b0")
+	got, err := DecodeCBOR(data)
+	if err != nil {
+		t.Fatalf("DecodeCBOR: %v", err)
+	}
+	if got != int64(1363896240) {
+		t.Errorf("DecodeCBOR = %v, want the tagged integer", got)
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "unexpected end"},
+		{"truncated text", "6261", "unexpected end"},
+		{"truncated head", "19", "unexpected end"},
+		{"array longer than data", "9affffffff", "array length exceeds data"},
+		{"map longer than data", "bbffffffffffffffff", "map length exceeds data"},
+		{"integer map key", "a10101", "map keys must be text strings"},
+		{"trailing bytes", "0000", "trailing bytes"},
+		{"indefinite length", "5f", "indefinite lengths"},
+		{"negative overflow", "3bffffffffffffffff", "negative integer overflow"},
+		{"unassigned simple value", "f0", "unsupported simple value"},
+		{"nesting too deep", strings.Repeat("81", cborMaxDepth+2) + "00", "nesting too deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.input)
+			if err != nil {
+				t.Fatalf("bad test input: %v", err)
+			}
+			_, err = DecodeCBOR(data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("DecodeCBOR(%s) error = %v, want %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitCBORSequence(t *testing.T) {
+	data, _ := hex.DecodeString("0061618201a161620203")
+	items, err := SplitCBORSequence(data)
+	if err != nil {
+		t.Fatalf("SplitCBORSequence: %v", err)
+	}
+
+	want := []string{"00", "6161", "8201a1616202", "03"}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, item := range items {
+		if hex.EncodeToString(item) != want[i] {
+			t.Errorf("item %d = %x, want %s", i, item, want[i])
+		}
+	}
+
+	if !bytes.Equal(bytes.Join(items, nil), data) {
+		t.Error("items do not cover the whole sequence")
+	}
+
+	if _, err := SplitCBORSequence(data[:len(data)-3]); err == nil {
+		t.Error("SplitCBORSequence accepted a truncated item")
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"crypto/ecdsa"
+	"fmt"
+)
+
+const EncodingEnvelope = "envelope"
+
+func NewEncryptedTransaction(plaintext string, recipients []*ecdsa.PublicKey, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	envelope, err := crypto.SealEnvelope([]byte(plaintext), recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     DataTx,
+		Data:     envelope.String(),
+		Sender:   wallet.GetAddress(),
+		Fee:      fee,
+		Encoding: EncodingEnvelope,
+	}, wallet)
+}
+
+func (tx *Transaction) IsEncrypted() bool {
+	return tx.Encoding == EncodingEnvelope
+}
+
+func (tx *Transaction) Envelope() (*crypto.Envelope, error) {
+	if !tx.IsEncrypted() {
+		return nil, fmt.Errorf("transaction payload is not encrypted")
+	}
+	return crypto.ParseEnvelope(tx.Data)
+}
+
+func (tx *Transaction) Decrypt(wallet *crypto.Wallet) (string, error) {
+	envelope, err := tx.Envelope()
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := envelope.Open(wallet)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func ValidateEncryptedPayload(tx *Transaction) error {
+	if !tx.IsEncrypted() {
+		return nil
+	}
+
+	if tx.SchemaID != "" {
+		return fmt.Errorf("encrypted payloads cannot be validated against a schema")
+	}
+
+	_, err := tx.Envelope()
+	return err
+}
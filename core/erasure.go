@@ -0,0 +1,153 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"fmt"
+)
+
+// ErasureRecord collects what the chain holds for one subject key. The
+// controller is the sender of the first entry under the key: the key ID
+// is a hash of a secret key and only appears once that entry is made, so
+// nobody else can claim it first.
+type ErasureRecord struct {
+	KeyID      string
+	Controller string
+	Entries    []*Anchor
+	Erasure    *Anchor
+}
+
+func NewSubjectEncryptedTransaction(plaintext string, subjectKey []byte, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	envelope, err := crypto.SealWithSubjectKey([]byte(plaintext), subjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     DataTx,
+		Data:     envelope.String(),
+		Sender:   wallet.GetAddress(),
+		Fee:      fee,
+		Encoding: EncodingEnvelope,
+	}, wallet)
+}
+
+func NewErasureTransaction(keyID string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if len(keyID) != 40 {
+		return nil, fmt.Errorf("invalid subject key id: %s", keyID)
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:   ErasureTx,
+		Data:   keyID,
+		Sender: wallet.GetAddress(),
+		Fee:    fee,
+	}, wallet)
+}
+
+func (tx *Transaction) SubjectKeyID() string {
+	if !tx.IsEncrypted() {
+		return ""
+	}
+
+	envelope, err := tx.Envelope()
+	if err != nil || !envelope.IsSubjectKeyed() {
+		return ""
+	}
+	return envelope.SubjectKeyID
+}
+
+// SubjectKey returns the subject key ID a transaction is filed under: the
+// key an entry is encrypted with, or the key an erasure destroys.
+func (tx *Transaction) SubjectKey() string {
+	switch tx.Type {
+	case ErasureTx:
+		return tx.Data
+	case DataTx:
+		return tx.SubjectKeyID()
+	}
+	return ""
+}
+
+func (bc *Blockchain) GetErasureRecord(keyID string) *ErasureRecord {
+	record := &ErasureRecord{KeyID: keyID}
+
+	index := bc.Index()
+	for _, txID := range index.Subjects[keyID] {
+		tx, block := bc.TransactionAt(index.Transactions[txID])
+		switch {
+		case tx == nil:
+		case tx.Type == ErasureTx && record.Erasure == nil:
+			record.Erasure = &Anchor{Transaction: tx, Block: block}
+		case tx.Type == DataTx:
+			record.Entries = append(record.Entries, &Anchor{Transaction: tx, Block: block})
+		}
+	}
+
+	for _, tx := range bc.PendingTx {
+		switch {
+		case tx.Type == ErasureTx && tx.Data == keyID && record.Erasure == nil:
+			record.Erasure = &Anchor{Transaction: tx}
+		case tx.Type == DataTx && tx.SubjectKeyID() == keyID:
+			record.Entries = append(record.Entries, &Anchor{Transaction: tx})
+		}
+	}
+
+	if len(record.Entries) > 0 {
+		record.Controller = record.Entries[0].Transaction.Sender
+	}
+	return record
+}
+
+// ValidateSubjectEntry keeps a subject key bound to its controller: once
+// an entry uses a key ID, entries from any other sender are rejected.
+func (bc *Blockchain) ValidateSubjectEntry(tx *Transaction) error {
+	keyID := tx.SubjectKeyID()
+	if keyID == "" {
+		return nil
+	}
+
+	record := bc.GetErasureRecord(keyID)
+	if record.Controller != "" && record.Controller != tx.Sender {
+		return fmt.Errorf("subject key %.16s... belongs to %.8s...", keyID, record.Controller)
+	}
+	if record.Erasure != nil {
+		return fmt.Errorf("subject key %.16s... has been erased", keyID)
+	}
+	return nil
+}
+
+func (bc *Blockchain) ValidateErasure(tx *Transaction) error {
+	if tx.Type != ErasureTx {
+		return fmt.Errorf("transaction is not an erasure")
+	}
+
+	if tx.PublicKey == "" && tx.Multisig == nil {
+		return fmt.Errorf("erasure must be signed")
+	}
+
+	record := bc.GetErasureRecord(tx.Data)
+	if record.Erasure != nil && record.Erasure.Transaction.ID != tx.ID {
+		return fmt.Errorf("subject key %.16s... has already been erased", tx.Data)
+	}
+
+	if record.Controller != "" && record.Controller != tx.Sender {
+		return fmt.Errorf("only the controller of subject key %.16s... can erase it", tx.Data)
+	}
+
+	confirmed := 0
+	for _, entry := range record.Entries {
+		if entry.Block != nil && entry.Transaction.Sender == tx.Sender {
+			confirmed++
+		}
+	}
+
+	if confirmed == 0 {
+		return fmt.Errorf("no confirmed entries are encrypted under subject key %.16s...", tx.Data)
+	}
+
+	return nil
+}
+
+func (er *ErasureRecord) IsErased() bool {
+	return er.Erasure != nil && er.Erasure.Block != nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"testing"
+)
+
+func appendTestBlock(bc *Blockchain, data string, txs ...*Transaction) {
+	tip := bc.Chain[len(bc.Chain)-1]
+	block := NewBlock(tip.Index+1, data, tip.Hash)
+	block.Transactions = txs
+	block.MerkleRoot = block.ComputeMerkleRoot()
+	block.Hash = block.CalculateHash()
+	bc.AppendBlock(block)
+}
+
+// Erasure records come from the chain index, so they must follow the chain
+// as blocks are added and unwound.
+func TestErasureRecordFollowsIndex(t *testing.T) {
+	wallet := testWallets(t, 1)[0]
+	subjectKey, err := crypto.GenerateSubjectKey()
+	if err != nil {
+		t.Fatalf("GenerateSubjectKey: %v", err)
+	}
+	otherKey, err := crypto.GenerateSubjectKey()
+	if err != nil {
+		t.Fatalf("GenerateSubjectKey: %v", err)
+	}
+	keyID := crypto.SubjectKeyID(subjectKey)
+
+	entry, err := NewSubjectEncryptedTransaction("alice", subjectKey, wallet, 1)
+	if err != nil {
+		t.Fatalf("NewSubjectEncryptedTransaction: %v", err)
+	}
+	other, err := NewSubjectEncryptedTransaction("bob", otherKey, wallet, 1)
+	if err != nil {
+		t.Fatalf("NewSubjectEncryptedTransaction: %v", err)
+	}
+	erasure, err := NewErasureTransaction(keyID, wallet, 1)
+	if err != nil {
+		t.Fatalf("NewErasureTransaction: %v", err)
+	}
+
+	bc := NewBlockchain()
+	appendTestBlock(bc, "entries", entry, other)
+	appendTestBlock(bc, "erasure", erasure)
+
+	record := bc.GetErasureRecord(keyID)
+	if len(record.Entries) != 1 || record.Entries[0].Transaction.ID != entry.ID {
+		t.Fatalf("record has %d entries, want only %.16s", len(record.Entries), entry.ID)
+	}
+	if record.Controller != wallet.GetAddress() {
+		t.Errorf("controller = %s, want %s", record.Controller, wallet.GetAddress())
+	}
+	if !record.IsErased() {
+		t.Error("confirmed erasure is not in the record")
+	}
+
+	// A reorg replaces the block holding the erasure.
+	bc.Chain = bc.Chain[:2]
+	appendTestBlock(bc, "replacement")
+	if record := bc.GetErasureRecord(keyID); record.Erasure != nil || len(record.Entries) != 1 {
+		t.Errorf("after the reorg the record has erasure %v and %d entries, want none and 1", record.Erasure, len(record.Entries))
+	}
+}
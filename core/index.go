@@ -0,0 +1,301 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+type TxLocation struct {
+	Height   int64 `json:"height"`
+	Position int   `json:"position"`
+}
+
+type ChainIndex struct {
+	Height       int64                        `json:"height"`
+	TipHash      string                       `json:"tip_hash"`
+	Transactions map[string]TxLocation        `json:"transactions"`
+	Blocks       map[string]int64             `json:"blocks"`
+	Addresses    map[string][]string          `json:"addresses"`
+	Streams      map[string][]string          `json:"streams"`
+	Subjects     map[string][]string          `json:"subjects"`
+	Types        map[TransactionType][]string `json:"types"`
+
+	sortedIDs []string
+	sorted    bool
+}
+
+func NewChainIndex() *ChainIndex {
+	return &ChainIndex{
+		Transactions: make(map[string]TxLocation),
+		Blocks:       make(map[string]int64),
+		Addresses:    make(map[string][]string),
+		Streams:      make(map[string][]string),
+		Subjects:     make(map[string][]string),
+		Types:        make(map[TransactionType][]string),
+	}
+}
+
+func (idx *ChainIndex) AddBlock(block *Block) {
+	height := idx.Height
+	idx.Blocks[block.Hash] = height
+
+	for i, tx := range block.Transactions {
+		idx.Transactions[tx.ID] = TxLocation{Height: height, Position: block.Position(i)}
+
+		idx.Addresses[tx.Sender] = append(idx.Addresses[tx.Sender], tx.ID)
+		if tx.Receiver != "" && tx.Receiver != tx.Sender {
+			idx.Addresses[tx.Receiver] = append(idx.Addresses[tx.Receiver], tx.ID)
+		}
+		if tx.Stream != "" {
+			idx.Streams[tx.Stream] = append(idx.Streams[tx.Stream], tx.ID)
+		}
+		if keyID := tx.SubjectKey(); keyID != "" {
+			idx.Subjects[keyID] = append(idx.Subjects[keyID], tx.ID)
+		}
+		idx.Types[tx.Type] = append(idx.Types[tx.Type], tx.ID)
+	}
+	for _, dropped := range block.Dropped {
+		idx.Transactions[dropped.ID] = TxLocation{Height: height, Position: dropped.Position}
+	}
+
+	idx.sorted = false
+	idx.Height = height + 1
+	idx.TipHash = block.Hash
+}
+
+func (idx *ChainIndex) IsCurrent(chain []*Block) bool {
+	return chainHasTip(chain, idx.Height, idx.TipHash)
+}
+
+// ForkPoint returns the height of the first indexed block that is no longer
+// part of chain. Everything below it can be kept when the chain reorganizes.
+func (idx *ChainIndex) ForkPoint(chain []*Block) int64 {
+	height := idx.Height
+	if height > int64(len(chain)) {
+		height = int64(len(chain))
+	}
+
+	for height > 0 {
+		if indexed, exists := idx.Blocks[chain[height-1].Hash]; exists && indexed == height-1 {
+			break
+		}
+		height--
+	}
+	return height
+}
+
+// Rewind drops every block at or above height from the index. Transaction
+// lists are kept in chain order, so only their tails need trimming.
+func (idx *ChainIndex) Rewind(height int64) {
+	if height >= idx.Height {
+		return
+	}
+
+	for address, ids := range idx.Addresses {
+		if ids = idx.trimTail(ids, height); len(ids) == 0 {
+			delete(idx.Addresses, address)
+		} else {
+			idx.Addresses[address] = ids
+		}
+	}
+	for stream, ids := range idx.Streams {
+		if ids = idx.trimTail(ids, height); len(ids) == 0 {
+			delete(idx.Streams, stream)
+		} else {
+			idx.Streams[stream] = ids
+		}
+	}
+	for keyID, ids := range idx.Subjects {
+		if ids = idx.trimTail(ids, height); len(ids) == 0 {
+			delete(idx.Subjects, keyID)
+		} else {
+			idx.Subjects[keyID] = ids
+		}
+	}
+	for txType, ids := range idx.Types {
+		if ids = idx.trimTail(ids, height); len(ids) == 0 {
+			delete(idx.Types, txType)
+		} else {
+			idx.Types[txType] = ids
+		}
+	}
+
+	for txID, location := range idx.Transactions {
+		if location.Height >= height {
+			delete(idx.Transactions, txID)
+		}
+	}
+
+	idx.TipHash = ""
+	for hash, blockHeight := range idx.Blocks {
+		if blockHeight >= height {
+			delete(idx.Blocks, hash)
+		} else if blockHeight == height-1 {
+			idx.TipHash = hash
+		}
+	}
+
+	idx.Height = height
+	idx.sorted = false
+}
+
+// Forget drops pruned transactions from the address, stream, subject and
+// type lists. They keep their location, so they can still be found by ID.
+func (idx *ChainIndex) Forget(txs []*Transaction) {
+	dropped := make(map[string]bool)
+	for _, tx := range txs {
+		dropped[tx.ID] = true
+	}
+
+	without := func(ids []string) []string {
+		var kept []string
+		for _, id := range ids {
+			if !dropped[id] {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+	for _, tx := range txs {
+		for _, address := range []string{tx.Sender, tx.Receiver} {
+			if ids, exists := idx.Addresses[address]; exists {
+				if ids = without(ids); len(ids) == 0 {
+					delete(idx.Addresses, address)
+				} else {
+					idx.Addresses[address] = ids
+				}
+			}
+		}
+		if ids, exists := idx.Streams[tx.Stream]; exists {
+			if ids = without(ids); len(ids) == 0 {
+				delete(idx.Streams, tx.Stream)
+			} else {
+				idx.Streams[tx.Stream] = ids
+			}
+		}
+		if ids, exists := idx.Subjects[tx.SubjectKey()]; exists {
+			if ids = without(ids); len(ids) == 0 {
+				delete(idx.Subjects, tx.SubjectKey())
+			} else {
+				idx.Subjects[tx.SubjectKey()] = ids
+			}
+		}
+		if ids, exists := idx.Types[tx.Type]; exists {
+			if ids = without(ids); len(ids) == 0 {
+				delete(idx.Types, tx.Type)
+			} else {
+				idx.Types[tx.Type] = ids
+			}
+		}
+	}
+	idx.sorted = false
+}
+
+func (idx *ChainIndex) trimTail(ids []string, height int64) []string {
+	end := len(ids)
+	for end > 0 && idx.Transactions[ids[end-1]].Height >= height {
+		end--
+	}
+	return ids[:end]
+}
+
+func (idx *ChainIndex) FindByPrefix(prefix string) (string, bool) {
+	if _, exists := idx.Transactions[prefix]; exists {
+		return prefix, true
+	}
+
+	if !idx.sorted {
+		idx.sortedIDs = idx.sortedIDs[:0]
+		for txID := range idx.Transactions {
+			idx.sortedIDs = append(idx.sortedIDs, txID)
+		}
+		sort.Strings(idx.sortedIDs)
+		idx.sorted = true
+	}
+
+	i := sort.SearchStrings(idx.sortedIDs, prefix)
+	if i < len(idx.sortedIDs) && strings.HasPrefix(idx.sortedIDs[i], prefix) {
+		return idx.sortedIDs[i], true
+	}
+	return "", false
+}
+
+// Index returns the transaction index, bringing it up to date with the chain
+// first. Blocks that were replaced by a reorg are unwound back to the fork
+// point before the new blocks are added.
+func (bc *Blockchain) Index() *ChainIndex {
+	bc.indexMu.Lock()
+	defer bc.indexMu.Unlock()
+
+	if bc.index == nil {
+		bc.index = NewChainIndex()
+	}
+	if !bc.index.IsCurrent(bc.Chain) {
+		bc.index.Rewind(bc.index.ForkPoint(bc.Chain))
+	}
+
+	for bc.index.Height < int64(len(bc.Chain)) {
+		bc.index.AddBlock(bc.Chain[bc.index.Height])
+	}
+	return bc.index
+}
+
+func (bc *Blockchain) SetIndex(index *ChainIndex) {
+	bc.indexMu.Lock()
+	defer bc.indexMu.Unlock()
+	bc.index = index
+}
+
+func (bc *Blockchain) AppendBlock(block *Block) {
+	bc.Chain = append(bc.Chain, block)
+	bc.Index()
+}
+
+func (bc *Blockchain) TransactionAt(location TxLocation) (*Transaction, *Block) {
+	if location.Height < 0 || location.Height >= int64(len(bc.Chain)) {
+		return nil, nil
+	}
+
+	block := bc.Chain[location.Height]
+	if block.Pruned {
+		for i, position := range block.Kept {
+			if position == location.Position && i < len(block.Transactions) {
+				return block.Transactions[i], block
+			}
+		}
+		return nil, nil
+	}
+	if location.Position < 0 || location.Position >= len(block.Transactions) {
+		return nil, nil
+	}
+	return block.Transactions[location.Position], block
+}
+
+// FindReference finds the confirmed transaction another one refers to. If
+// its block has been pruned, it returns what the block kept of it: its ID,
+// type and sender.
+func (bc *Blockchain) FindReference(txID string) (*Transaction, *Block) {
+	if tx, block := bc.FindTransaction(txID); tx != nil {
+		return tx, block
+	}
+
+	location, exists := bc.Index().Transactions[txID]
+	if !exists || location.Height < 0 || location.Height >= int64(len(bc.Chain)) {
+		return nil, nil
+	}
+	block := bc.Chain[location.Height]
+	for _, dropped := range block.Dropped {
+		if dropped.ID == txID {
+			return &Transaction{ID: dropped.ID, Type: dropped.Type, Sender: dropped.Sender}, block
+		}
+	}
+	return nil, nil
+}
+
+func (bc *Blockchain) GetBlockByHash(hash string) *Block {
+	height, exists := bc.Index().Blocks[hash]
+	if !exists {
+		return nil
+	}
+	return bc.Chain[height]
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+type MerkleProof struct {
+	Leaf  string        `json:"leaf"`
+	Index int           `json:"index"`
+	Path  []*MerkleStep `json:"path"`
+}
+
+func HashMerkleLeaf(data []byte) string {
+	hash := sha256.Sum256(append([]byte{0x00}, data...))
+	return hex.EncodeToString(hash[:])
+}
+
+func hashMerkleNode(left string, right string) string {
+	leftBytes, _ := hex.DecodeString(left)
+	rightBytes, _ := hex.DecodeString(right)
+
+	data := append([]byte{0x01}, leftBytes...)
+	data = append(data, rightBytes...)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func MerkleRoot(leaves []string) string {
+	if len(leaves) == 0 {
+		return ""
+	}
+
+	level := append([]string(nil), leaves...)
+	for len(level) > 1 {
+		level = nextMerkleLevel(level)
+	}
+	return level[0]
+}
+
+func BuildMerkleProof(leaves []string, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range (0-%d)", index, len(leaves)-1)
+	}
+
+	proof := &MerkleProof{Leaf: leaves[index], Index: index}
+
+	level := append([]string(nil), leaves...)
+	position := index
+	for len(level) > 1 {
+		sibling := position ^ 1
+		if sibling < len(level) {
+			proof.Path = append(proof.Path, &MerkleStep{
+				Hash: level[sibling],
+				Left: sibling < position,
+			})
+		}
+
+		level = nextMerkleLevel(level)
+		position /= 2
+	}
+
+	return proof, nil
+}
+
+func (mp *MerkleProof) ComputeRoot() string {
+	hash := mp.Leaf
+	for _, step := range mp.Path {
+		if step.Left {
+			hash = hashMerkleNode(step.Hash, hash)
+		} else {
+			hash = hashMerkleNode(hash, step.Hash)
+		}
+	}
+	return hash
+}
+
+func (mp *MerkleProof) Verify(root string) bool {
+	return root != "" && mp.ComputeRoot() == root
+}
+
+func nextMerkleLevel(level []string) []string {
+	next := make([]string, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, hashMerkleNode(level[i], level[i+1]))
+	}
+	return next
+}
+
+func (b *Block) TransactionLeaves() []string {
+	leaves := make([]string, 0, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		leaves = append(leaves, HashMerkleLeaf([]byte(tx.ID)))
+	}
+	return leaves
+}
+
+func (b *Block) ComputeMerkleRoot() string {
+	return MerkleRoot(b.TransactionLeaves())
+}
+
+func (b *Block) TransactionProof(txID string) (*MerkleProof, error) {
+	for i, tx := range b.Transactions {
+		if tx.ID == txID {
+			return BuildMerkleProof(b.TransactionLeaves(), i)
+		}
+	}
+	return nil, fmt.Errorf("transaction %.16s... is not in block %d", txID, b.Index)
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) []string {
+	leaves := make([]string, n)
+	for i := range leaves {
+		leaves[i] = HashMerkleLeaf([]byte(fmt.Sprintf("line %d", i)))
+	}
+	return leaves
+}
+
+func TestMerkleRoot(t *testing.T) {
+	if root := MerkleRoot(nil); root != "" {
+		t.Errorf("MerkleRoot(nil) = %q, want empty", root)
+	}
+
+	leaves := testLeaves(3)
+	tests := []struct {
+		name   string
+		leaves []string
+		want   string
+	}{
+		{"single leaf", leaves[:1], leaves[0]},
+		{"two leaves", leaves[:2], hashMerkleNode(leaves[0], leaves[1])},
+		{"odd leaf carried up", leaves, hashMerkleNode(hashMerkleNode(leaves[0], leaves[1]), leaves[2])},
+	}
+
+	for _, tt := range tests {
+		if got := MerkleRoot(tt.leaves); got != tt.want {
+			t.Errorf("%s: MerkleRoot = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMerkleLeafAndNodeDomainsDiffer(t *testing.T) {
+	leaves := testLeaves(2)
+	node := hashMerkleNode(leaves[0], leaves[1])
+	if HashMerkleLeaf([]byte(leaves[0]+leaves[1])) == node {
+		t.Error("a leaf can be made to hash like an inner node")
+	}
+}
+
+func TestMerkleProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root := MerkleRoot(leaves)
+
+		for i := 0; i < n; i++ {
+			proof, err := BuildMerkleProof(leaves, i)
+			if err != nil {
+				t.Fatalf("n=%d i=%d: BuildMerkleProof: %v", n, i, err)
+			}
+			if !proof.Verify(root) {
+				t.Errorf("n=%d i=%d: proof does not verify", n, i)
+			}
+			if proof.Verify("") {
+				t.Errorf("n=%d i=%d: proof verifies against an empty root", n, i)
+			}
+
+			forged := *proof
+			forged.Leaf = HashMerkleLeaf([]byte("forged"))
+			if forged.Verify(root) {
+				t.Errorf("n=%d i=%d: proof for a different leaf verifies", n, i)
+			}
+
+			if len(proof.Path) > 0 {
+				step := *proof.Path[0]
+				step.Left = !step.Left
+				flipped := *proof
+				flipped.Path = append([]*MerkleStep{&step}, proof.Path[1:]...)
+				if flipped.Verify(root) {
+					t.Errorf("n=%d i=%d: proof with a flipped step verifies", n, i)
+				}
+			}
+		}
+	}
+}
+
+func TestBuildMerkleProofOutOfRange(t *testing.T) {
+	leaves := testLeaves(4)
+	for _, index := range []int{-1, 4} {
+		if _, err := BuildMerkleProof(leaves, index); err == nil {
+			t.Errorf("BuildMerkleProof accepted index %d", index)
+		}
+	}
+	if _, err := BuildMerkleProof(nil, 0); err == nil {
+		t.Error("BuildMerkleProof accepted an empty tree")
+	}
+}
+
+func TestTransactionProof(t *testing.T) {
+	block := NewBlock(1, "", "prev")
+	for i := 0; i < 5; i++ {
+		block.Transactions = append(block.Transactions, &Transaction{ID: fmt.Sprintf("tx-%d", i)})
+	}
+	block.MerkleRoot = block.ComputeMerkleRoot()
+
+	for _, tx := range block.Transactions {
+		proof, err := block.TransactionProof(tx.ID)
+		if err != nil {
+			t.Fatalf("TransactionProof(%s): %v", tx.ID, err)
+		}
+		if proof.Leaf != HashMerkleLeaf([]byte(tx.ID)) || !proof.Verify(block.MerkleRoot) {
+			t.Errorf("proof for %s does not verify", tx.ID)
+		}
+	}
+
+	if _, err := block.TransactionProof("missing"); err == nil {
+		t.Error("TransactionProof found a transaction that is not in the block")
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"fmt"
+	"time"
+)
+
+type MultisigSignature struct {
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+func NewMultisigDataTransaction(data string, account *crypto.MultisigAccount, fee uint64) (*Transaction, error) {
+	if err := account.Verify(); err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		Version:   TxVersion,
+		Type:      DataTx,
+		Data:      data,
+		Sender:    account.Address,
+		Fee:       fee,
+		Timestamp: time.Now().Unix(),
+		Nonce:     generateNonce(),
+		Multisig:  account,
+	}
+
+	tx.ID = tx.CalculateID()
+
+	return tx, nil
+}
+
+func (tx *Transaction) AddSignature(wallet *crypto.Wallet) error {
+	if tx.Multisig == nil {
+		return fmt.Errorf("transaction is not a multisig transaction")
+	}
+
+	publicKey := crypto.PublicKeyToString(wallet.PublicKey)
+	if !tx.Multisig.HasKey(publicKey) {
+		return fmt.Errorf("wallet %s is not a signer of %s", wallet.GetAddressShort(), tx.Sender)
+	}
+
+	for _, sig := range tx.Signatures {
+		if crypto.CanonicalPublicKey(sig.PublicKey) == publicKey {
+			return fmt.Errorf("wallet %s has already signed", wallet.GetAddressShort())
+		}
+	}
+
+	signature, err := crypto.SignString(wallet.PrivateKey, tx.ID)
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction: %v", err)
+	}
+
+	tx.Signatures = append(tx.Signatures, &MultisigSignature{
+		PublicKey: publicKey,
+		Signature: signature,
+	})
+
+	return nil
+}
+
+func (tx *Transaction) CountValidSignatures() int {
+	if tx.Multisig == nil {
+		return 0
+	}
+
+	// Signers are counted by their canonical key, so one signer cannot
+	// count twice by spelling the key in another letter case.
+	valid := 0
+	seen := make(map[string]bool)
+	for _, sig := range tx.Signatures {
+		publicKey, err := crypto.StringToPublicKey(sig.PublicKey)
+		if err != nil {
+			continue
+		}
+
+		key := crypto.PublicKeyToString(publicKey)
+		if seen[key] || !tx.Multisig.HasKey(key) {
+			continue
+		}
+
+		if crypto.VerifyStringSignature(publicKey, tx.ID, sig.Signature) {
+			seen[key] = true
+			valid++
+		}
+	}
+
+	return valid
+}
+
+func (tx *Transaction) IsFullySigned() bool {
+	return tx.Multisig != nil && tx.CountValidSignatures() >= tx.Multisig.Threshold
+}
+
+func (tx *Transaction) VerifyMultisig() error {
+	if tx.Multisig == nil {
+		return fmt.Errorf("transaction is not a multisig transaction")
+	}
+
+	if err := tx.Multisig.Verify(); err != nil {
+		return err
+	}
+
+	if tx.Multisig.Address != tx.Sender {
+		return fmt.Errorf("multisig account does not match sender address")
+	}
+
+	valid := tx.CountValidSignatures()
+	if valid < tx.Multisig.Threshold {
+		return fmt.Errorf("not enough signatures: have %d, need %d", valid, tx.Multisig.Threshold)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"strings"
+	"testing"
+)
+
+func testWallets(t *testing.T, n int) []*crypto.Wallet {
+	t.Helper()
+	wallets := make([]*crypto.Wallet, n)
+	for i := range wallets {
+		wallet, err := crypto.NewWallet()
+		if err != nil {
+			t.Fatalf("NewWallet: %v", err)
+		}
+		wallets[i] = wallet
+	}
+	return wallets
+}
+
+// signAs adds a signature by wallet under the given spelling of its key,
+// bypassing AddSignature's checks the way a forged transaction would.
+func signAs(t *testing.T, tx *Transaction, wallet *crypto.Wallet, key string) {
+	t.Helper()
+	signature, err := crypto.SignString(wallet.PrivateKey, tx.ID)
+	if err != nil {
+		t.Fatalf("SignString: %v", err)
+	}
+	tx.Signatures = append(tx.Signatures, &MultisigSignature{PublicKey: key, Signature: signature})
+}
+
+func TestMultisigSignatures(t *testing.T) {
+	wallets := testWallets(t, 4)
+	members, outsider := wallets[:3], wallets[3]
+	keyOf := func(w *crypto.Wallet) string { return crypto.PublicKeyToString(w.PublicKey) }
+
+	tests := []struct {
+		name      string
+		threshold int
+		sign      func(t *testing.T, tx *Transaction)
+		valid     int
+	}{
+		{
+			name:      "unsigned",
+			threshold: 1,
+			sign:      func(t *testing.T, tx *Transaction) {},
+			valid:     0,
+		},
+		{
+			name:      "threshold met",
+			threshold: 2,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, members[1], keyOf(members[1]))
+			},
+			valid: 2,
+		},
+		{
+			name:      "one short of threshold",
+			threshold: 3,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, members[2], keyOf(members[2]))
+			},
+			valid: 2,
+		},
+		{
+			name:      "all members",
+			threshold: 3,
+			sign: func(t *testing.T, tx *Transaction) {
+				for _, member := range members {
+					signAs(t, tx, member, keyOf(member))
+				}
+			},
+			valid: 3,
+		},
+		{
+			name:      "duplicate signer",
+			threshold: 2,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, members[0], keyOf(members[0]))
+			},
+			valid: 1,
+		},
+		{
+			name:      "duplicate signer in another case",
+			threshold: 2,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, members[0], strings.ToUpper(keyOf(members[0])))
+			},
+			valid: 1,
+		},
+		{
+			name:      "non-member",
+			threshold: 2,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, outsider, keyOf(outsider))
+			},
+			valid: 1,
+		},
+		{
+			name:      "member key with another signer's signature",
+			threshold: 2,
+			sign: func(t *testing.T, tx *Transaction) {
+				signAs(t, tx, members[0], keyOf(members[0]))
+				signAs(t, tx, outsider, keyOf(members[1]))
+			},
+			valid: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := make([]string, len(members))
+			for i, member := range members {
+				keys[i] = keyOf(member)
+			}
+			account, err := crypto.NewMultisigAccount(tt.threshold, keys)
+			if err != nil {
+				t.Fatalf("NewMultisigAccount: %v", err)
+			}
+			tx, err := NewMultisigDataTransaction("payload", account, 1)
+			if err != nil {
+				t.Fatalf("NewMultisigDataTransaction: %v", err)
+			}
+
+			tt.sign(t, tx)
+			if got := tx.CountValidSignatures(); got != tt.valid {
+				t.Errorf("CountValidSignatures = %d, want %d", got, tt.valid)
+			}
+			if err := tx.VerifyMultisig(); (err == nil) != (tt.valid >= tt.threshold) {
+				t.Errorf("VerifyMultisig = %v with %d of %d signatures", err, tt.valid, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestMultisigAddSignature(t *testing.T) {
+	wallets := testWallets(t, 3)
+	account, err := crypto.NewMultisigAccount(2, []string{
+		crypto.PublicKeyToString(wallets[0].PublicKey),
+		crypto.PublicKeyToString(wallets[1].PublicKey),
+	})
+	if err != nil {
+		t.Fatalf("NewMultisigAccount: %v", err)
+	}
+	tx, err := NewMultisigDataTransaction("payload", account, 1)
+	if err != nil {
+		t.Fatalf("NewMultisigDataTransaction: %v", err)
+	}
+
+	if err := tx.AddSignature(wallets[2]); err == nil {
+		t.Error("a non-member could sign")
+	}
+	if err := tx.AddSignature(wallets[0]); err != nil {
+		t.Fatalf("AddSignature: %v", err)
+	}
+	tx.Signatures[0].PublicKey = strings.ToUpper(tx.Signatures[0].PublicKey)
+	if err := tx.AddSignature(wallets[0]); err == nil {
+		t.Error("the same member signed twice")
+	}
+	if tx.IsFullySigned() {
+		t.Error("fully signed with one of two signatures")
+	}
+	if err := tx.AddSignature(wallets[1]); err != nil {
+		t.Fatalf("AddSignature: %v", err)
+	}
+	if !tx.IsFullySigned() {
+		t.Error("not fully signed with both signatures")
+	}
+}
+
+func TestMultisigAccountVerify(t *testing.T) {
+	wallets := testWallets(t, 2)
+	key := crypto.PublicKeyToString(wallets[0].PublicKey)
+	other := crypto.PublicKeyToString(wallets[1].PublicKey)
+
+	tests := []struct {
+		name      string
+		threshold int
+		keys      []string
+		wantErr   bool
+	}{
+		{name: "valid", threshold: 2, keys: []string{key, other}},
+		{name: "threshold zero", threshold: 0, keys: []string{key, other}, wantErr: true},
+		{name: "threshold above keys", threshold: 3, keys: []string{key, other}, wantErr: true},
+		{name: "upper-case key", threshold: 1, keys: []string{strings.ToUpper(key), other}, wantErr: true},
+		{name: "same key twice", threshold: 2, keys: []string{key, strings.ToUpper(key)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &crypto.MultisigAccount{
+				Threshold:  tt.threshold,
+				PublicKeys: tt.keys,
+				Address:    crypto.MultisigAddress(tt.threshold, tt.keys),
+			}
+			if err := account.Verify(); (err != nil) != tt.wantErr {
+				t.Errorf("Verify = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"encoding/hex"
+	"fmt"
+)
+
+type PayloadRef struct {
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+	MediaType string `json:"media_type"`
+}
+
+type Anchor struct {
+	Transaction *Transaction
+	Block       *Block
+}
+
+func (p *PayloadRef) Validate() error {
+	if len(p.Hash) != 64 {
+		return fmt.Errorf("payload hash must be 64 hex characters")
+	}
+	if _, err := hex.DecodeString(p.Hash); err != nil {
+		return fmt.Errorf("payload hash is not valid hexadecimal")
+	}
+	if p.Size < 0 {
+		return fmt.Errorf("payload size cannot be negative")
+	}
+	if p.MediaType == "" {
+		return fmt.Errorf("payload media type is required")
+	}
+	return nil
+}
+
+func NewNotarizeTransaction(payload *PayloadRef, note string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
+
+	if note == "" {
+		note = "notarized " + payload.Hash[:16]
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:    DataTx,
+		Data:    note,
+		Sender:  wallet.GetAddress(),
+		Fee:     fee,
+		Payload: payload,
+	}, wallet)
+}
+
+func (bc *Blockchain) FindPayloadAnchors(hash string) []*Anchor {
+	var anchors []*Anchor
+
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.Payload != nil && tx.Payload.Hash == hash {
+				anchors = append(anchors, &Anchor{Transaction: tx, Block: block})
+			}
+		}
+	}
+
+	for _, tx := range bc.PendingTx {
+		if tx.Payload != nil && tx.Payload.Hash == hash {
+			anchors = append(anchors, &Anchor{Transaction: tx})
+		}
+	}
+
+	return anchors
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const DefaultQueryLimit = 50
+
+type Query struct {
+	Sender     string
+	Stream     string
+	Types      []TransactionType
+	FromHeight int64
+	ToHeight   int64
+	Since      int64
+	Until      int64
+	Fields     map[string]string
+	Offset     int
+	Limit      int
+}
+
+type QueryMatch struct {
+	Transaction *Transaction
+	Block       *Block
+	Location    TxLocation
+}
+
+type QueryResult struct {
+	Total   int
+	Offset  int
+	Limit   int
+	Matches []*QueryMatch
+}
+
+func NewQuery() *Query {
+	return &Query{
+		FromHeight: 0,
+		ToHeight:   -1,
+		Fields:     make(map[string]string),
+		Limit:      DefaultQueryLimit,
+	}
+}
+
+func (bc *Blockchain) Query(q *Query) (*QueryResult, error) {
+	if q.Offset < 0 || q.Limit < 0 || q.FromHeight < 0 {
+		return nil, fmt.Errorf("offset, limit and block range cannot be negative")
+	}
+
+	index := bc.Index()
+
+	toHeight := q.ToHeight
+	if toHeight < 0 || toHeight >= index.Height {
+		toHeight = index.Height - 1
+	}
+	if q.FromHeight > toHeight {
+		return &QueryResult{Offset: q.Offset, Limit: q.Limit}, nil
+	}
+
+	var locations []TxLocation
+	if candidates, indexed := q.candidates(index); indexed {
+		for _, txID := range candidates {
+			location := index.Transactions[txID]
+			if location.Height >= q.FromHeight && location.Height <= toHeight {
+				locations = append(locations, location)
+			}
+		}
+		sort.Slice(locations, func(i, j int) bool {
+			if locations[i].Height != locations[j].Height {
+				return locations[i].Height < locations[j].Height
+			}
+			return locations[i].Position < locations[j].Position
+		})
+	} else {
+		for height := q.FromHeight; height <= toHeight; height++ {
+			block := bc.Chain[height]
+			for i := range block.Transactions {
+				locations = append(locations, TxLocation{Height: height, Position: block.Position(i)})
+			}
+		}
+	}
+
+	result := &QueryResult{Offset: q.Offset, Limit: q.Limit}
+	for _, location := range locations {
+		tx, block := bc.TransactionAt(location)
+		if tx == nil || !q.matches(tx, block) {
+			continue
+		}
+
+		if result.Total >= q.Offset && (q.Limit == 0 || len(result.Matches) < q.Limit) {
+			result.Matches = append(result.Matches, &QueryMatch{Transaction: tx, Block: block, Location: location})
+		}
+		result.Total++
+	}
+
+	return result, nil
+}
+
+func (q *Query) candidates(index *ChainIndex) ([]string, bool) {
+	var best []string
+	indexed := false
+
+	consider := func(ids []string) {
+		if !indexed || len(ids) < len(best) {
+			best = ids
+			indexed = true
+		}
+	}
+
+	if q.Sender != "" {
+		consider(index.Addresses[q.Sender])
+	}
+	if q.Stream != "" {
+		consider(index.Streams[q.Stream])
+	}
+	if len(q.Types) > 0 {
+		var ids []string
+		seen := make(map[TransactionType]bool)
+		for _, t := range q.Types {
+			if !seen[t] {
+				seen[t] = true
+				ids = append(ids, index.Types[t]...)
+			}
+		}
+		consider(ids)
+	}
+
+	return best, indexed
+}
+
+func (q *Query) matches(tx *Transaction, block *Block) bool {
+	if q.Sender != "" && tx.Sender != q.Sender {
+		return false
+	}
+	if q.Stream != "" && tx.Stream != q.Stream {
+		return false
+	}
+
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if tx.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.Since != 0 && block.Timestamp < q.Since {
+		return false
+	}
+	if q.Until != 0 && block.Timestamp > q.Until {
+		return false
+	}
+
+	if len(q.Fields) > 0 {
+		if tx.SchemaID == "" || tx.Type != DataTx {
+			return false
+		}
+
+		payload, err := tx.DecodePayload()
+		if err != nil {
+			return false
+		}
+
+		for path, expected := range q.Fields {
+			value, exists := lookupField(payload, path)
+			if !exists || fmt.Sprint(value) != expected {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func lookupField(payload map[string]interface{}, path string) (interface{}, bool) {
+	if value, exists := payload[path]; exists {
+		return value, true
+	}
+
+	parts := strings.Split(path, ".")
+	var current interface{} = payload
+	for _, part := range parts {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
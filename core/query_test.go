@@ -0,0 +1,40 @@
+package core
+
+import "testing"
+
+func TestQueryTypes(t *testing.T) {
+	wallet := testWallets(t, 1)[0]
+	bc := NewBlockchain()
+	for _, data := range []string{"first", "second"} {
+		tx, err := NewDataTransaction(data, wallet, 1)
+		if err != nil {
+			t.Fatalf("NewDataTransaction: %v", err)
+		}
+		appendTestBlock(bc, data, tx)
+	}
+
+	tests := []struct {
+		name  string
+		types []TransactionType
+		total int
+	}{
+		{name: "one type", types: []TransactionType{DataTx}, total: 2},
+		{name: "repeated type", types: []TransactionType{DataTx, DataTx}, total: 2},
+		{name: "two types", types: []TransactionType{DataTx, SchemaTx}, total: 2},
+		{name: "no matches", types: []TransactionType{SchemaTx}, total: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery()
+			q.Types = tt.types
+			result, err := bc.Query(q)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if result.Total != tt.total || len(result.Matches) != tt.total {
+				t.Errorf("got %d matches of %d, want %d", len(result.Matches), result.Total, tt.total)
+			}
+		})
+	}
+}
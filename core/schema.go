@@ -0,0 +1,289 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	EncodingJSON = "json"
+	EncodingCBOR = "cbor"
+)
+
+type SchemaField struct {
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+}
+
+type Schema struct {
+	ID          string                  `json:"id"`
+	Description string                  `json:"description,omitempty"`
+	Fields      map[string]*SchemaField `json:"fields"`
+	Strict      bool                    `json:"strict,omitempty"`
+}
+
+var schemaFieldTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"object":  true,
+	"array":   true,
+}
+
+func ParseSchema(data string) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema definition: %v", err)
+	}
+
+	if schema.ID == "" {
+		return nil, fmt.Errorf("schema must have an id")
+	}
+
+	if len(schema.Fields) == 0 {
+		return nil, fmt.Errorf("schema %s declares no fields", schema.ID)
+	}
+
+	for name, field := range schema.Fields {
+		if field == nil || !schemaFieldTypes[field.Type] {
+			return nil, fmt.Errorf("schema %s: field %q has unknown type", schema.ID, name)
+		}
+		if len(field.Enum) > 0 && field.Type != "string" {
+			return nil, fmt.Errorf("schema %s: enum is only supported on string fields", schema.ID)
+		}
+	}
+
+	return &schema, nil
+}
+
+func (s *Schema) FieldNames() []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Schema) Validate(payload map[string]interface{}) error {
+	for name, field := range s.Fields {
+		value, exists := payload[name]
+		if !exists || value == nil {
+			if field.Required {
+				return fmt.Errorf("missing required field %q", name)
+			}
+			continue
+		}
+
+		if !matchesFieldType(value, field.Type) {
+			return fmt.Errorf("field %q must be of type %s", name, field.Type)
+		}
+
+		if len(field.Enum) > 0 {
+			allowed := false
+			for _, option := range field.Enum {
+				if value == option {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return fmt.Errorf("field %q must be one of %v", name, field.Enum)
+			}
+		}
+	}
+
+	if s.Strict {
+		for name := range payload {
+			if _, declared := s.Fields[name]; !declared {
+				return fmt.Errorf("field %q is not declared in schema %s", name, s.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchesFieldType(value interface{}, fieldType string) bool {
+	switch fieldType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, int64, uint64:
+			return true
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int64, uint64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return false
+}
+
+func EncodePayload(payload map[string]interface{}, encoding string) (string, error) {
+	switch encoding {
+	case EncodingJSON:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode payload: %v", err)
+		}
+		return string(data), nil
+	case EncodingCBOR:
+		data, err := EncodeCBOR(payload)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode payload: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	default:
+		return "", fmt.Errorf("unsupported payload encoding: %s", encoding)
+	}
+}
+
+func (tx *Transaction) DecodePayload() (map[string]interface{}, error) {
+	var decoded interface{}
+
+	switch tx.Encoding {
+	case EncodingJSON:
+		if err := json.Unmarshal([]byte(tx.Data), &decoded); err != nil {
+			return nil, fmt.Errorf("payload is not valid JSON: %v", err)
+		}
+	case EncodingCBOR:
+		raw, err := base64.StdEncoding.DecodeString(tx.Data)
+		if err != nil {
+			return nil, fmt.Errorf("payload is not valid base64: %v", err)
+		}
+		decoded, err = DecodeCBOR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("payload is not valid CBOR: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payload encoding: %q", tx.Encoding)
+	}
+
+	payload, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("structured payload must be an object")
+	}
+	return payload, nil
+}
+
+func NewStructuredTransaction(schemaID string, payload map[string]interface{}, encoding string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	data, err := EncodePayload(payload, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     DataTx,
+		Data:     data,
+		Sender:   wallet.GetAddress(),
+		Fee:      fee,
+		SchemaID: schemaID,
+		Encoding: encoding,
+	}, wallet)
+}
+
+func NewSchemaTransaction(schema *Schema, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %v", err)
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     SchemaTx,
+		Data:     string(data),
+		Sender:   wallet.GetAddress(),
+		Fee:      fee,
+		SchemaID: schema.ID,
+	}, wallet)
+}
+
+func (bc *Blockchain) GetSchema(schemaID string) (*Schema, *Transaction, error) {
+	index := bc.Index()
+	for _, txID := range index.Types[SchemaTx] {
+		tx, _ := bc.TransactionAt(index.Transactions[txID])
+		if tx != nil && tx.SchemaID == schemaID {
+			schema, err := ParseSchema(tx.Data)
+			return schema, tx, err
+		}
+	}
+	return nil, nil, fmt.Errorf("schema %s is not registered", schemaID)
+}
+
+func (bc *Blockchain) GetSchemas() []*Transaction {
+	var registrations []*Transaction
+	seen := make(map[string]bool)
+	index := bc.Index()
+	for _, txID := range index.Types[SchemaTx] {
+		tx, _ := bc.TransactionAt(index.Transactions[txID])
+		if tx != nil && !seen[tx.SchemaID] {
+			seen[tx.SchemaID] = true
+			registrations = append(registrations, tx)
+		}
+	}
+	return registrations
+}
+
+func (bc *Blockchain) ValidateSchemaRegistration(tx *Transaction) error {
+	schema, err := ParseSchema(tx.Data)
+	if err != nil {
+		return err
+	}
+
+	if schema.ID != tx.SchemaID {
+		return fmt.Errorf("schema id %q does not match transaction schema id %q", schema.ID, tx.SchemaID)
+	}
+
+	if _, _, err := bc.GetSchema(schema.ID); err == nil {
+		return fmt.Errorf("schema %s is already registered", schema.ID)
+	}
+
+	for _, pending := range bc.PendingTx {
+		if pending.Type == SchemaTx && pending.SchemaID == schema.ID && pending.ID != tx.ID {
+			return fmt.Errorf("schema %s is already pending registration", schema.ID)
+		}
+	}
+
+	return nil
+}
+
+func (bc *Blockchain) ValidatePayload(tx *Transaction) error {
+	if tx.SchemaID == "" {
+		return nil
+	}
+
+	schema, _, err := bc.GetSchema(tx.SchemaID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		return err
+	}
+
+	if err := schema.Validate(payload); err != nil {
+		return fmt.Errorf("payload does not match schema %s: %v", schema.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultSearchLimit = 20
+	maxSearchTokenLen  = 64
+	maxSnippetLen      = 120
+)
+
+type SearchDocument struct {
+	Height   int64 `json:"height"`
+	Position int   `json:"position"`
+	Length   int   `json:"length"`
+}
+
+type SearchIndex struct {
+	Height      int64                     `json:"height"`
+	BlockHashes []string                  `json:"block_hashes"`
+	Documents   map[string]SearchDocument `json:"documents"`
+	Postings    map[string]map[string]int `json:"postings"`
+	TotalLength int64                     `json:"total_length"`
+}
+
+type SearchHit struct {
+	TxID      string   `json:"tx_id"`
+	BlockHash string   `json:"block_hash"`
+	Height    int64    `json:"height"`
+	Position  int      `json:"position"`
+	Score     float64  `json:"score"`
+	Matched   []string `json:"matched"`
+	Type      string   `json:"type,omitempty"`
+	Sender    string   `json:"sender,omitempty"`
+	Snippet   string   `json:"snippet,omitempty"`
+}
+
+type SearchResults struct {
+	Query string       `json:"query"`
+	Terms []string     `json:"terms"`
+	Total int          `json:"total"`
+	Hits  []*SearchHit `json:"hits"`
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		Documents: make(map[string]SearchDocument),
+		Postings:  make(map[string]map[string]int),
+	}
+}
+
+func (idx *SearchIndex) TipHash() string {
+	if len(idx.BlockHashes) == 0 {
+		return ""
+	}
+	return idx.BlockHashes[len(idx.BlockHashes)-1]
+}
+
+func (idx *SearchIndex) Terms() int {
+	return len(idx.Postings)
+}
+
+func (idx *SearchIndex) AddBlock(block *Block) {
+	height := idx.Height
+
+	for i, tx := range block.Transactions {
+		tokens := SearchTokens(tx)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		idx.Documents[tx.ID] = SearchDocument{Height: height, Position: block.Position(i), Length: len(tokens)}
+		idx.TotalLength += int64(len(tokens))
+
+		for _, token := range tokens {
+			postings, exists := idx.Postings[token]
+			if !exists {
+				postings = make(map[string]int)
+				idx.Postings[token] = postings
+			}
+			postings[tx.ID]++
+		}
+	}
+
+	idx.BlockHashes = append(idx.BlockHashes, block.Hash)
+	idx.Height = height + 1
+}
+
+// Sync brings the index up to the chain's tip. Blocks past the last indexed
+// height are added incrementally; if an indexed block is no longer on the
+// chain (a reorg), the index is rebuilt from genesis.
+func (idx *SearchIndex) Sync(chain []*Block) (added int, rebuilt bool) {
+	if !chainHasTip(chain, idx.Height, idx.TipHash()) {
+		*idx = *NewSearchIndex()
+		rebuilt = true
+	}
+
+	for idx.Height < int64(len(chain)) {
+		idx.AddBlock(chain[idx.Height])
+		added++
+	}
+	return added, rebuilt
+}
+
+func (idx *SearchIndex) Search(query string, limit int) *SearchResults {
+	terms := uniqueTokens(Tokenize(query))
+	results := &SearchResults{Query: query, Terms: terms}
+	if len(terms) == 0 || len(idx.Documents) == 0 {
+		return results
+	}
+
+	// Every term must match; the rarest term drives the candidate set.
+	sort.Slice(terms, func(i, j int) bool {
+		return len(idx.Postings[terms[i]]) < len(idx.Postings[terms[j]])
+	})
+
+	documents := float64(len(idx.Documents))
+	averageLength := float64(idx.TotalLength) / documents
+
+	var hits []*SearchHit
+	for txID := range idx.Postings[terms[0]] {
+		doc := idx.Documents[txID]
+		score := 0.0
+		matched := true
+
+		for _, term := range terms {
+			frequency := float64(idx.Postings[term][txID])
+			if frequency == 0 {
+				matched = false
+				break
+			}
+
+			idf := math.Log(1 + (documents-float64(len(idx.Postings[term]))+0.5)/(float64(len(idx.Postings[term]))+0.5))
+			norm := frequency + 1.2*(0.25+0.75*float64(doc.Length)/averageLength)
+			score += idf * frequency * 2.2 / norm
+		}
+		if !matched {
+			continue
+		}
+
+		hit := &SearchHit{
+			TxID:     txID,
+			Height:   doc.Height,
+			Position: doc.Position,
+			Score:    math.Round(score*1000) / 1000,
+			Matched:  terms,
+		}
+		if doc.Height < int64(len(idx.BlockHashes)) {
+			hit.BlockHash = idx.BlockHashes[doc.Height]
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Height != hits[j].Height {
+			return hits[i].Height > hits[j].Height
+		}
+		return hits[i].Position < hits[j].Position
+	})
+
+	results.Total = len(hits)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	results.Hits = hits
+	return results
+}
+
+func (bc *Blockchain) DescribeSearchHits(hits []*SearchHit) {
+	for _, hit := range hits {
+		tx, block := bc.TransactionAt(TxLocation{Height: hit.Height, Position: hit.Position})
+		if tx == nil || tx.ID != hit.TxID || block.Hash != hit.BlockHash {
+			continue
+		}
+
+		hit.Type = tx.Type.String()
+		hit.Sender = tx.Sender
+		hit.Snippet = tx.Data
+		if tx.Encoding == EncodingCBOR {
+			if payload, err := tx.DecodePayload(); err == nil {
+				encoded, _ := json.Marshal(payload)
+				hit.Snippet = string(encoded)
+			}
+		}
+		if len(hit.Snippet) > maxSnippetLen {
+			hit.Snippet = hit.Snippet[:maxSnippetLen] + "..."
+		}
+	}
+}
+
+// SearchTokens returns the terms a transaction is indexed under: the words
+// of its data plus field=value pairs for JSON and schema-encoded payloads.
+func SearchTokens(tx *Transaction) []string {
+	if tx.Data == "" || tx.Type == RewardTx {
+		return nil
+	}
+
+	var tokens []string
+	if tx.SchemaID == "" || tx.Encoding != EncodingCBOR {
+		tokens = Tokenize(tx.Data)
+	}
+
+	var payload map[string]interface{}
+	if tx.SchemaID != "" {
+		payload, _ = tx.DecodePayload()
+	} else if strings.HasPrefix(strings.TrimSpace(tx.Data), "{") {
+		json.Unmarshal([]byte(tx.Data), &payload)
+	}
+	for _, field := range flattenFields("", payload) {
+		tokens = append(tokens, Tokenize(field)...)
+	}
+
+	return tokens
+}
+
+func flattenFields(prefix string, payload map[string]interface{}) []string {
+	var fields []string
+	for key, value := range payload {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			fields = append(fields, flattenFields(name, v)...)
+		case []interface{}:
+			for _, item := range v {
+				if _, nested := item.(map[string]interface{}); !nested {
+					fields = append(fields, fmt.Sprintf("%s=%v", name, item))
+				}
+			}
+		case nil:
+		default:
+			fields = append(fields, fmt.Sprintf("%s=%v", name, v))
+		}
+	}
+	return fields
+}
+
+// Tokenize lowercases text and splits it into words. Words joined by = . - @
+// (such as user=alice or alice@example.com) are kept whole and also indexed
+// by their parts, so both the pair and each side can be searched.
+func Tokenize(text string) []string {
+	isWordChar := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || isTokenJoiner(r)
+	}
+
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isWordChar(r) }) {
+		word = strings.TrimFunc(word, isTokenJoiner)
+		if word == "" || len(word) > maxSearchTokenLen {
+			continue
+		}
+		tokens = append(tokens, word)
+
+		if strings.IndexFunc(word, isTokenJoiner) >= 0 {
+			for _, part := range strings.FieldsFunc(word, isTokenJoiner) {
+				tokens = append(tokens, part)
+			}
+		}
+	}
+	return tokens
+}
+
+func isTokenJoiner(r rune) bool {
+	return r == '=' || r == '.' || r == '-' || r == '@'
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	var unique []string
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+func chainHasTip(chain []*Block, height int64, tipHash string) bool {
+	if height > int64(len(chain)) {
+		return false
+	}
+	if height == 0 {
+		return true
+	}
+	return chain[height-1].Hash == tipHash
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"chainlog/crypto"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var streamNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,127}$`)
+
+type StreamEntry struct {
+	Transaction *Transaction
+	Block       *Block
+}
+
+type SequenceGap struct {
+	From uint64
+	To   uint64
+}
+
+type StreamSender struct {
+	Address      string
+	Entries      int
+	LastSequence uint64
+	Gaps         []SequenceGap
+	Reordered    []uint64
+	Duplicates   []uint64
+}
+
+type StreamInfo struct {
+	Name    string
+	Entries int
+	Senders []*StreamSender
+}
+
+func ValidateStreamName(stream string) error {
+	if !streamNamePattern.MatchString(stream) {
+		return fmt.Errorf("invalid stream name %q: use letters, digits, '.', '_', '-' or '/'", stream)
+	}
+	return nil
+}
+
+func NewStreamTransaction(stream string, sequence uint64, data string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
+	if err := ValidateStreamName(stream); err != nil {
+		return nil, err
+	}
+
+	return newSignedTransaction(&Transaction{
+		Type:     DataTx,
+		Data:     data,
+		Sender:   wallet.GetAddress(),
+		Fee:      fee,
+		Stream:   stream,
+		Sequence: sequence,
+	}, wallet)
+}
+
+func (bc *Blockchain) LastSequence(stream string, sender string) uint64 {
+	last := uint64(0)
+	for _, entry := range bc.GetStreamEntries(stream) {
+		if entry.Transaction.Sender == sender && entry.Transaction.Sequence > last {
+			last = entry.Transaction.Sequence
+		}
+	}
+	return last
+}
+
+func (bc *Blockchain) NextSequence(stream string, sender string) uint64 {
+	return bc.LastSequence(stream, sender) + 1
+}
+
+func (bc *Blockchain) ValidateStreamSequence(tx *Transaction) error {
+	if tx.Stream == "" {
+		if tx.Sequence != 0 {
+			return fmt.Errorf("sequence number set without a stream")
+		}
+		return nil
+	}
+
+	if err := ValidateStreamName(tx.Stream); err != nil {
+		return err
+	}
+
+	if tx.Sequence == 0 {
+		return fmt.Errorf("stream entries must have a sequence number starting at 1")
+	}
+
+	last := uint64(0)
+	for _, entry := range bc.GetStreamEntries(tx.Stream) {
+		if entry.Transaction.ID == tx.ID || entry.Transaction.Sender != tx.Sender {
+			continue
+		}
+		if entry.Transaction.Sequence > last {
+			last = entry.Transaction.Sequence
+		}
+	}
+
+	if tx.Sequence != last+1 {
+		return fmt.Errorf("stream %s expects sequence %d from %.8s..., got %d",
+			tx.Stream, last+1, tx.Sender, tx.Sequence)
+	}
+	return nil
+}
+
+func (bc *Blockchain) GetStreamEntries(stream string) []*StreamEntry {
+	var entries []*StreamEntry
+
+	index := bc.Index()
+	for _, txID := range index.Streams[stream] {
+		tx, block := bc.TransactionAt(index.Transactions[txID])
+		if tx == nil {
+			continue
+		}
+		entries = append(entries, &StreamEntry{Transaction: tx, Block: block})
+	}
+
+	for _, tx := range bc.PendingTx {
+		if tx.Stream == stream {
+			entries = append(entries, &StreamEntry{Transaction: tx})
+		}
+	}
+
+	return entries
+}
+
+func (bc *Blockchain) GetStreamNames() []string {
+	seen := make(map[string]bool)
+	for stream := range bc.Index().Streams {
+		seen[stream] = true
+	}
+	for _, tx := range bc.PendingTx {
+		if tx.Stream != "" {
+			seen[tx.Stream] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (bc *Blockchain) GetStreamInfo(stream string) *StreamInfo {
+	return AnalyzeStream(stream, bc.GetStreamEntries(stream))
+}
+
+func AnalyzeStream(stream string, entries []*StreamEntry) *StreamInfo {
+	info := &StreamInfo{Name: stream, Entries: len(entries)}
+
+	senders := make(map[string]*StreamSender)
+	seen := make(map[string]map[uint64]bool)
+	var order []string
+
+	for _, entry := range entries {
+		tx := entry.Transaction
+		sender, exists := senders[tx.Sender]
+		if !exists {
+			sender = &StreamSender{Address: tx.Sender}
+			senders[tx.Sender] = sender
+			seen[tx.Sender] = make(map[uint64]bool)
+			order = append(order, tx.Sender)
+		}
+
+		sender.Entries++
+		if seen[tx.Sender][tx.Sequence] {
+			sender.Duplicates = append(sender.Duplicates, tx.Sequence)
+		} else if tx.Sequence < sender.LastSequence {
+			sender.Reordered = append(sender.Reordered, tx.Sequence)
+		}
+		seen[tx.Sender][tx.Sequence] = true
+
+		if tx.Sequence > sender.LastSequence {
+			sender.LastSequence = tx.Sequence
+		}
+	}
+
+	for _, address := range order {
+		sender := senders[address]
+
+		sequences := make([]uint64, 0, len(seen[address]))
+		for seq := range seen[address] {
+			sequences = append(sequences, seq)
+		}
+		sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+
+		expected := uint64(1)
+		for _, seq := range sequences {
+			if seq > expected {
+				sender.Gaps = append(sender.Gaps, SequenceGap{From: expected, To: seq - 1})
+			}
+			expected = seq + 1
+		}
+
+		info.Senders = append(info.Senders, sender)
+	}
+
+	return info
+}
+
+func (ss *StreamSender) IsContiguous() bool {
+	return len(ss.Gaps) == 0 && len(ss.Reordered) == 0 && len(ss.Duplicates) == 0
+}
@@ -5,21 +5,36 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type TransactionType int
 
+// TxVersion is the ID scheme of new transactions. Version 0 is the scheme
+// of chains started before transactions carried their type and signing
+// key: its ID covers fewer fields and its signature cannot be checked, so
+// such transactions are only accepted in blocks already on a chain.
+const TxVersion = 1
+
 const (
 	DataTx   TransactionType = iota  
 	TransferTx
 	FeeTx                            
 	RewardTx                         
 	StakeTx                          
+	AttestationTx
+	AmendmentTx
+	DelegationTx
+	SchemaTx
+	ErasureTx
+	BatchTx
 )
 
 type Transaction struct {
 	ID        string          
+	Version   int             `json:",omitempty"`
 	Type      TransactionType 
 	Data      string          
 	Sender    string          
@@ -29,16 +44,27 @@ type Transaction struct {
 	Signature string          
 	Timestamp int64           
 	Nonce     uint64          
+	Reference  string
+	SchemaID   string `json:",omitempty"`
+	Encoding   string `json:",omitempty"`
+	Stream     string `json:",omitempty"`
+	Sequence   uint64 `json:",omitempty"`
+	Payload    *PayloadRef `json:",omitempty"`
+	PublicKey  string
+	Multisig   *crypto.MultisigAccount `json:",omitempty"`
+	Signatures []*MultisigSignature    `json:",omitempty"`
 }
 
 func NewDataTransaction(data string, wallet *crypto.Wallet, fee uint64) (*Transaction, error) {
 	tx := &Transaction{
+		Version:   TxVersion,
 		Type:      DataTx,
 		Data:      data,
 		Sender:    wallet.GetAddress(),
 		Fee:       fee,
 		Timestamp: time.Now().Unix(),
 		Nonce:     generateNonce(),
+		PublicKey: crypto.PublicKeyToString(wallet.PublicKey),
 	}
 	
 	tx.ID = tx.CalculateID()
@@ -52,7 +78,62 @@ func NewDataTransaction(data string, wallet *crypto.Wallet, fee uint64) (*Transa
 	return tx, nil
 }
 
+func newSignedTransaction(tx *Transaction, wallet *crypto.Wallet) (*Transaction, error) {
+	tx.Version = TxVersion
+	tx.Timestamp = time.Now().Unix()
+	tx.Nonce = generateNonce()
+	tx.PublicKey = crypto.PublicKeyToString(wallet.PublicKey)
+	tx.ID = tx.CalculateID()
+
+	signature, err := crypto.SignString(wallet.PrivateKey, tx.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %v", err)
+	}
+	tx.Signature = signature
+
+	return tx, nil
+}
+
+// CalculateID hashes every field a signature must cover, the version and
+// type included. Each field is length-prefixed so that no text can be
+// shifted from one field into its neighbour without changing the ID.
+// Version 0 transactions keep the ID they were given.
 func (tx *Transaction) CalculateID() string {
+	if tx.Version == 0 {
+		return tx.legacyID()
+	}
+
+	fields := []string{
+		strconv.Itoa(tx.Version),
+		strconv.Itoa(int(tx.Type)),
+		tx.Data,
+		tx.Sender,
+		tx.Receiver,
+		strconv.FormatUint(tx.Amount, 10),
+		strconv.FormatUint(tx.Fee, 10),
+		strconv.FormatInt(tx.Timestamp, 10),
+		strconv.FormatUint(tx.Nonce, 10),
+		tx.Reference,
+		tx.SchemaID,
+		tx.Encoding,
+		tx.Stream,
+		strconv.FormatUint(tx.Sequence, 10),
+	}
+
+	if tx.Payload != nil {
+		fields = append(fields, tx.Payload.Hash, strconv.FormatInt(tx.Payload.Size, 10), tx.Payload.MediaType)
+	}
+
+	var txData strings.Builder
+	for _, field := range fields {
+		fmt.Fprintf(&txData, "%d:%s;", len(field), field)
+	}
+
+	hash := sha256.Sum256([]byte(txData.String()))
+	return hex.EncodeToString(hash[:])
+}
+
+func (tx *Transaction) legacyID() string {
 	txData := fmt.Sprintf("%s%s%s%d%d%d%d",
 		tx.Data,
 		tx.Sender,
@@ -61,25 +142,85 @@ func (tx *Transaction) CalculateID() string {
 		tx.Fee,
 		tx.Timestamp,
 		tx.Nonce)
-	
+
 	hash := sha256.Sum256([]byte(txData))
 	return hex.EncodeToString(hash[:])
 }
 
+// VerifyConfirmed checks the signature of a transaction found in a block.
+// A version 0 transaction has none that can be checked, so it must instead
+// be of a type that existed then and hold nothing its ID does not cover.
+func (tx *Transaction) VerifyConfirmed() error {
+	switch {
+	case tx.Version > TxVersion:
+		return fmt.Errorf("unknown transaction version %d", tx.Version)
+	case tx.Version > 0:
+		return tx.VerifySignature()
+	case tx.Type > StakeTx:
+		return fmt.Errorf("%s transactions did not exist before version 1", tx.Type)
+	case tx.Reference != "" || tx.SchemaID != "" || tx.Encoding != "" || tx.Stream != "" || tx.Sequence != 0 ||
+		tx.Payload != nil || tx.PublicKey != "" || tx.Multisig != nil || len(tx.Signatures) > 0:
+		return fmt.Errorf("version 0 transaction holds fields its ID does not cover")
+	}
+	return nil
+}
+
+func (tx *Transaction) VerifySignature() error {
+	if tx.Multisig != nil {
+		return tx.VerifyMultisig()
+	}
+
+	if tx.PublicKey == "" {
+		return fmt.Errorf("transaction has no public key")
+	}
+
+	publicKey, err := crypto.StringToPublicKey(tx.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if crypto.PublicKeyToAddress(publicKey) != tx.Sender {
+		return fmt.Errorf("public key does not match sender address")
+	}
+
+	if !crypto.VerifyStringSignature(publicKey, tx.ID, tx.Signature) {
+		return fmt.Errorf("signature verification failed")
+	}
+
+	return nil
+}
+
 func generateNonce() uint64 {
 	return uint64(time.Now().UnixNano())
 }
 
+var transactionTypeNames = []string{"DATA", "TRANSFER", "FEE", "REWARD", "STAKE", "ATTESTATION", "AMENDMENT", "DELEGATION", "SCHEMA", "ERASURE", "BATCH"}
+
+func (t TransactionType) String() string {
+	if int(t) < 0 || int(t) >= len(transactionTypeNames) {
+		return fmt.Sprintf("UNKNOWN(%d)", int(t))
+	}
+	return transactionTypeNames[t]
+}
+
+func ParseTransactionType(name string) (TransactionType, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for i, typeName := range transactionTypeNames {
+		if typeName == name {
+			return TransactionType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown transaction type: %s", name)
+}
+
 func (tx *Transaction) Display() {
-	typeNames := []string{"DATA", "FEE", "REWARD", "STAKE"}
-	
 	displayID := tx.ID
 	if len(displayID) >= 16 {
 		displayID = displayID[:16] + "..."
 	}
 	
 	fmt.Printf("╔═ TRANSACTION %s\n", displayID)
-	fmt.Printf("║ Type: %s\n", typeNames[tx.Type])
+	fmt.Printf("║ Type: %s\n", tx.Type)
 	
 	if len(tx.Sender) >= 8 {
 		fmt.Printf("║ From: %s...\n", tx.Sender[:8])
@@ -95,6 +236,18 @@ func (tx *Transaction) Display() {
 		}
 	}
 	
+	if tx.Reference != "" {
+		fmt.Printf("║ Refers To: %.16s...\n", tx.Reference)
+	}
+	if tx.Stream != "" {
+		fmt.Printf("║ Stream: %s #%d\n", tx.Stream, tx.Sequence)
+	}
+	if tx.Payload != nil {
+		fmt.Printf("║ Payload: %.16s... (%d bytes, %s)\n", tx.Payload.Hash, tx.Payload.Size, tx.Payload.MediaType)
+	}
+	if tx.SchemaID != "" {
+		fmt.Printf("║ Schema: %s (%s)\n", tx.SchemaID, tx.Encoding)
+	}
 	fmt.Printf("║ Data: %s\n", tx.Data)
 	if tx.Amount > 0 {
 		fmt.Printf("║ Amount: %d LogCoins\n", tx.Amount)
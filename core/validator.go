@@ -43,6 +43,11 @@ func (v *Validator) ValidateBlock(block *Block) bool {
 		}
 	}
 	
+	if !block.Pruned && block.MerkleRoot != "" && block.MerkleRoot != block.ComputeMerkleRoot() {
+		fmt.Println("Block merkle root does not match its transactions")
+		return false
+	}
+	
 	calculatedHash := block.CalculateHash()
 	if block.Hash != calculatedHash {
 		fmt.Printf("Block hash is invalid. Expected %s, got %s\n", 
@@ -65,6 +70,23 @@ func (v *Validator) ValidateBlock(block *Block) bool {
 	return true
 }
 
+// AdmitTransaction checks a transaction entering the mempool: the node's
+// mempool limits, then the rules every transaction must follow. Blocks and
+// imports only need ValidateTransaction; the limits are local policy.
+func (v *Validator) AdmitTransaction(tx *Transaction) bool {
+	if tx.Version != TxVersion {
+		fmt.Printf("Transaction rejected: new transactions must be version %d\n", TxVersion)
+		return false
+	}
+	if err := v.blockchain.CheckMempoolLimits(tx); err != nil {
+		fmt.Printf("Transaction rejected: %v\n", err)
+		return false
+	}
+	return v.ValidateTransaction(tx)
+}
+
+// ValidateTransaction checks the rules a transaction must follow wherever it
+// is found, in the mempool or in a block.
 func (v *Validator) ValidateTransaction(tx *Transaction) bool {
 	fmt.Printf("Validating Transaction %s...\n", tx.ID[:16])
 	
@@ -84,11 +106,89 @@ func (v *Validator) ValidateTransaction(tx *Transaction) bool {
 	}
 	
 	currentTime := GetCurrentTimestamp()
-	if tx.Timestamp > currentTime+300 { 
+	if tx.Timestamp > currentTime+300 {
 		fmt.Println("Transaction timestamp is in future")
 		return false
 	}
-	
+
+	// Every sender is an address derived from a key or a multisig account,
+	// so an unsigned transaction could claim to come from anyone.
+	if tx.Version > 0 && tx.Multisig == nil && tx.PublicKey == "" {
+		fmt.Printf("Transaction from %s is not signed\n", tx.Sender)
+		return false
+	}
+	if err := tx.VerifyConfirmed(); err != nil {
+		fmt.Printf("Transaction signature is invalid: %v\n", err)
+		return false
+	}
+
+	if tx.Payload != nil {
+		if tx.Type != DataTx {
+			fmt.Println("Only data transactions can anchor an off-chain payload")
+			return false
+		}
+		if err := tx.Payload.Validate(); err != nil {
+			fmt.Printf("Invalid payload reference: %v\n", err)
+			return false
+		}
+	}
+
+	if tx.Stream != "" && tx.Type != DataTx && tx.Type != BatchTx {
+		fmt.Printf("Only data and batch transactions can belong to a stream\n")
+		return false
+	}
+
+	if err := v.blockchain.ValidateStreamSequence(tx); err != nil {
+		fmt.Printf("Invalid stream sequence: %v\n", err)
+		return false
+	}
+
+	switch tx.Type {
+	case DataTx:
+		if err := ValidateEncryptedPayload(tx); err != nil {
+			fmt.Printf("Invalid encrypted payload: %v\n", err)
+			return false
+		}
+		if err := v.blockchain.ValidateSubjectEntry(tx); err != nil {
+			fmt.Printf("Invalid subject entry: %v\n", err)
+			return false
+		}
+		if err := v.blockchain.ValidatePayload(tx); err != nil {
+			fmt.Printf("Invalid structured payload: %v\n", err)
+			return false
+		}
+	case SchemaTx:
+		if err := v.blockchain.ValidateSchemaRegistration(tx); err != nil {
+			fmt.Printf("Invalid schema registration: %v\n", err)
+			return false
+		}
+	case AttestationTx:
+		if err := v.blockchain.ValidateAttestation(tx); err != nil {
+			fmt.Printf("Invalid attestation: %v\n", err)
+			return false
+		}
+	case AmendmentTx:
+		if err := v.blockchain.ValidateAmendment(tx); err != nil {
+			fmt.Printf("Invalid amendment: %v\n", err)
+			return false
+		}
+	case BatchTx:
+		if err := v.blockchain.ValidateBatch(tx); err != nil {
+			fmt.Printf("Invalid batch: %v\n", err)
+			return false
+		}
+	case ErasureTx:
+		if err := v.blockchain.ValidateErasure(tx); err != nil {
+			fmt.Printf("Invalid erasure: %v\n", err)
+			return false
+		}
+	case DelegationTx:
+		if tx.Receiver == "" || (tx.Data != DelegationGrant && tx.Data != DelegationRevoke) {
+			fmt.Println("Delegation must name a delegate and grant or revoke")
+			return false
+		}
+	}
+
 	fmt.Printf("Transaction validation passed!\n")
 	return true
 }
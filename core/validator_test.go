@@ -0,0 +1,154 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// Mempool limits are the node's own policy: they keep transactions out of
+// its mempool but never make a transaction invalid.
+func TestAdmitTransactionLimits(t *testing.T) {
+	wallet := testWallets(t, 1)[0]
+	large, err := NewDataTransaction(strings.Repeat("x", 100), wallet, 1)
+	if err != nil {
+		t.Fatalf("NewDataTransaction: %v", err)
+	}
+	queued, err := NewDataTransaction("queued", wallet, 1)
+	if err != nil {
+		t.Fatalf("NewDataTransaction: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		maxSize   int
+		maxQueued int
+		admitted  bool
+	}{
+		{name: "no limits", admitted: true},
+		{name: "within limits", maxSize: 100, maxQueued: 2, admitted: true},
+		{name: "too large", maxSize: 99},
+		{name: "mempool full", maxQueued: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := NewBlockchain()
+			bc.MaxTxSize, bc.MaxPendingTx = tt.maxSize, tt.maxQueued
+			bc.AddTransaction(queued)
+
+			validator := NewValidator(bc)
+			if !validator.ValidateTransaction(large) {
+				t.Error("ValidateTransaction applied the mempool limits")
+			}
+			if got := validator.AdmitTransaction(large); got != tt.admitted {
+				t.Errorf("AdmitTransaction = %t, want %t", got, tt.admitted)
+			}
+		})
+	}
+}
+
+// legacyTransaction builds a transaction the way chains before version 1
+// did: no type in the ID, no public key, no checkable signature.
+func legacyTransaction(data string) *Transaction {
+	tx := &Transaction{
+		Type:      DataTx,
+		Data:      data,
+		Sender:    "legacy-sender",
+		Fee:       1,
+		Timestamp: GetCurrentTimestamp(),
+		Nonce:     7,
+		Signature: "legacy-signature",
+	}
+	tx.ID = tx.CalculateID()
+	return tx
+}
+
+func TestValidateTransactionVersions(t *testing.T) {
+	wallet := testWallets(t, 1)[0]
+
+	tests := []struct {
+		name     string
+		build    func(t *testing.T) *Transaction
+		valid    bool
+		admitted bool
+	}{
+		{
+			name: "current",
+			build: func(t *testing.T) *Transaction {
+				tx, err := NewDataTransaction("current", wallet, 1)
+				if err != nil {
+					t.Fatalf("NewDataTransaction: %v", err)
+				}
+				return tx
+			},
+			valid:    true,
+			admitted: true,
+		},
+		{
+			name:  "legacy",
+			build: func(t *testing.T) *Transaction { return legacyTransaction("legacy") },
+			valid: true,
+		},
+		{
+			name: "legacy id over a current transaction",
+			build: func(t *testing.T) *Transaction {
+				tx, err := NewDataTransaction("current", wallet, 1)
+				if err != nil {
+					t.Fatalf("NewDataTransaction: %v", err)
+				}
+				tx.Version = 0
+				tx.ID = tx.CalculateID()
+				return tx
+			},
+		},
+		{
+			name: "legacy with a stream",
+			build: func(t *testing.T) *Transaction {
+				tx := legacyTransaction("legacy")
+				tx.Stream, tx.Sequence = "logs", 1
+				return tx
+			},
+		},
+		{
+			name: "legacy of a later type",
+			build: func(t *testing.T) *Transaction {
+				tx := legacyTransaction("legacy")
+				tx.Type = AttestationTx
+				return tx
+			},
+		},
+		{
+			name: "legacy with a wrong id",
+			build: func(t *testing.T) *Transaction {
+				tx := legacyTransaction("legacy")
+				tx.ID = strings.Repeat("0", 64)
+				return tx
+			},
+		},
+		{
+			name: "unknown version",
+			build: func(t *testing.T) *Transaction {
+				tx, err := NewDataTransaction("future", wallet, 1)
+				if err != nil {
+					t.Fatalf("NewDataTransaction: %v", err)
+				}
+				tx.Version = TxVersion + 1
+				tx.ID = tx.CalculateID()
+				return tx
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator(NewBlockchain())
+			tx := tt.build(t)
+			if got := validator.ValidateTransaction(tx); got != tt.valid {
+				t.Errorf("ValidateTransaction = %t, want %t", got, tt.valid)
+			}
+			if got := validator.AdmitTransaction(tx); got != tt.admitted {
+				t.Errorf("AdmitTransaction = %t, want %t", got, tt.admitted)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	EnvelopeVersion   = 1
+	EnvelopeAlgorithm = "ECDH-P256+HKDF-SHA256+A256GCM"
+	SubjectAlgorithm  = "SUBJECT-KEY+A256GCM"
+
+	envelopeInfo = "chainlog-envelope-v1"
+)
+
+type EnvelopeRecipient struct {
+	KeyID      string `json:"kid"`
+	WrappedKey string `json:"key"`
+}
+
+type Envelope struct {
+	Version      int                  `json:"v"`
+	Algorithm    string               `json:"alg"`
+	EphemeralKey string               `json:"epk,omitempty"`
+	Recipients   []*EnvelopeRecipient `json:"recipients,omitempty"`
+	SubjectKeyID string               `json:"skid,omitempty"`
+	Nonce        string               `json:"nonce"`
+	Ciphertext   string               `json:"ct"`
+}
+
+func SealEnvelope(plaintext []byte, recipients []*ecdsa.PublicKey) (*Envelope, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("envelope needs at least one recipient")
+	}
+
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, fmt.Errorf("failed to generate content key: %v", err)
+	}
+
+	nonce, ciphertext, err := sealAEAD(contentKey, plaintext, []byte(envelopeInfo))
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %v", err)
+	}
+	ephemeralBytes := ephemeral.PublicKey().Bytes()
+
+	envelope := &Envelope{
+		Version:      EnvelopeVersion,
+		Algorithm:    EnvelopeAlgorithm,
+		EphemeralKey: hex.EncodeToString(ephemeralBytes),
+		Nonce:        hex.EncodeToString(nonce),
+		Ciphertext:   hex.EncodeToString(ciphertext),
+	}
+
+	seen := make(map[string]bool)
+	for _, recipient := range recipients {
+		keyID := PublicKeyToAddress(recipient)
+		if seen[keyID] {
+			continue
+		}
+		seen[keyID] = true
+
+		recipientKey, err := recipient.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient key %s: %v", keyID[:8], err)
+		}
+
+		shared, err := ephemeral.ECDH(recipientKey)
+		if err != nil {
+			return nil, fmt.Errorf("key agreement failed for %s: %v", keyID[:8], err)
+		}
+
+		wrapKey, err := deriveWrapKey(shared, ephemeralBytes, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapNonce, wrapped, err := sealAEAD(wrapKey, contentKey, []byte(keyID))
+		if err != nil {
+			return nil, err
+		}
+
+		envelope.Recipients = append(envelope.Recipients, &EnvelopeRecipient{
+			KeyID:      keyID,
+			WrappedKey: hex.EncodeToString(append(wrapNonce, wrapped...)),
+		})
+	}
+
+	return envelope, nil
+}
+
+func SealWithSubjectKey(plaintext []byte, subjectKey []byte) (*Envelope, error) {
+	if len(subjectKey) != 32 {
+		return nil, fmt.Errorf("subject key must be 32 bytes")
+	}
+
+	nonce, ciphertext, err := sealAEAD(subjectKey, plaintext, []byte(envelopeInfo))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Version:      EnvelopeVersion,
+		Algorithm:    SubjectAlgorithm,
+		SubjectKeyID: SubjectKeyID(subjectKey),
+		Nonce:        hex.EncodeToString(nonce),
+		Ciphertext:   hex.EncodeToString(ciphertext),
+	}, nil
+}
+
+func GenerateSubjectKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate subject key: %v", err)
+	}
+	return key, nil
+}
+
+func SubjectKeyID(subjectKey []byte) string {
+	hash := sha256.Sum256(append([]byte("chainlog-subject-key|"), subjectKey...))
+	return hex.EncodeToString(hash[:20])
+}
+
+func (e *Envelope) Open(wallet *Wallet) ([]byte, error) {
+	if e.Version != EnvelopeVersion || e.Algorithm != EnvelopeAlgorithm {
+		return nil, fmt.Errorf("envelope is not encrypted to wallet keys (%s)", e.Algorithm)
+	}
+
+	var recipient *EnvelopeRecipient
+	for _, candidate := range e.Recipients {
+		if candidate.KeyID == wallet.GetAddress() {
+			recipient = candidate
+			break
+		}
+	}
+	if recipient == nil {
+		return nil, fmt.Errorf("wallet %s is not a recipient of this envelope", wallet.GetAddressShort())
+	}
+
+	ephemeralBytes, err := hex.DecodeString(e.EphemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key encoding")
+	}
+
+	ephemeral, err := ecdh.P256().NewPublicKey(ephemeralBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %v", err)
+	}
+
+	privateKey, err := wallet.PrivateKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("wallet key cannot be used for key agreement: %v", err)
+	}
+
+	shared, err := privateKey.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %v", err)
+	}
+
+	wrapKey, err := deriveWrapKey(shared, ephemeralBytes, recipient.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := hex.DecodeString(recipient.WrappedKey)
+	if err != nil || len(wrapped) < 12 {
+		return nil, fmt.Errorf("invalid wrapped key encoding")
+	}
+
+	contentKey, err := openAEAD(wrapKey, wrapped[:12], wrapped[12:], []byte(recipient.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap content key: %v", err)
+	}
+
+	return e.OpenWithKey(contentKey)
+}
+
+func (e *Envelope) OpenWithKey(contentKey []byte) ([]byte, error) {
+	nonce, err := hex.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce encoding")
+	}
+
+	ciphertext, err := hex.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext encoding")
+	}
+
+	plaintext, err := openAEAD(contentKey, nonce, ciphertext, []byte(envelopeInfo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %v", err)
+	}
+	return plaintext, nil
+}
+
+func (e *Envelope) IsSubjectKeyed() bool {
+	return e.Algorithm == SubjectAlgorithm
+}
+
+func (e *Envelope) RecipientIDs() []string {
+	ids := make([]string, 0, len(e.Recipients))
+	for _, recipient := range e.Recipients {
+		ids = append(ids, recipient.KeyID)
+	}
+	return ids
+}
+
+func (e *Envelope) String() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+func ParseEnvelope(data string) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		return nil, fmt.Errorf("invalid envelope: %v", err)
+	}
+
+	if envelope.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version: v%d", envelope.Version)
+	}
+
+	switch envelope.Algorithm {
+	case EnvelopeAlgorithm:
+		if len(envelope.Recipients) == 0 || envelope.EphemeralKey == "" {
+			return nil, fmt.Errorf("envelope has no recipients")
+		}
+	case SubjectAlgorithm:
+		if len(envelope.SubjectKeyID) != 40 {
+			return nil, fmt.Errorf("envelope has no subject key id")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported envelope algorithm: %s", envelope.Algorithm)
+	}
+
+	if envelope.Ciphertext == "" || envelope.Nonce == "" {
+		return nil, fmt.Errorf("envelope has no ciphertext")
+	}
+
+	return &envelope, nil
+}
+
+func deriveWrapKey(shared []byte, ephemeral []byte, keyID string) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, shared, ephemeral, envelopeInfo+"|"+keyID, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	return key, nil
+}
+
+func sealAEAD(key []byte, plaintext []byte, additionalData []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func openAEAD(key []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
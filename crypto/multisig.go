@@ -0,0 +1,134 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+type MultisigAccount struct {
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"public_keys"`
+	Address    string   `json:"address"`
+}
+
+func NewMultisigAccount(threshold int, publicKeys []string) (*MultisigAccount, error) {
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("multisig account needs at least one public key")
+	}
+
+	if threshold < 1 || threshold > len(publicKeys) {
+		return nil, fmt.Errorf("invalid threshold %d for %d keys", threshold, len(publicKeys))
+	}
+
+	keys := make([]string, 0, len(publicKeys))
+	seen := make(map[string]bool)
+	for _, key := range publicKeys {
+		key = strings.ToLower(key)
+		if _, err := StringToPublicKey(key); err != nil {
+			return nil, fmt.Errorf("invalid public key %.16s...: %v", key, err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate public key %.16s...", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return &MultisigAccount{
+		Threshold:  threshold,
+		PublicKeys: keys,
+		Address:    MultisigAddress(threshold, keys),
+	}, nil
+}
+
+func MultisigAddress(threshold int, publicKeys []string) string {
+	keys := append([]string(nil), publicKeys...)
+	sort.Strings(keys)
+
+	accountData := fmt.Sprintf("multisig:%d:%s", threshold, strings.Join(keys, ","))
+	hash := sha256.Sum256([]byte(accountData))
+
+	return hex.EncodeToString(hash[:20])
+}
+
+func (ma *MultisigAccount) HasKey(publicKey string) bool {
+	publicKey = strings.ToLower(publicKey)
+	for _, key := range ma.PublicKeys {
+		if key == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+func (ma *MultisigAccount) Verify() error {
+	if ma.Address != MultisigAddress(ma.Threshold, ma.PublicKeys) {
+		return fmt.Errorf("multisig address does not match key set and threshold")
+	}
+	if ma.Threshold < 1 || ma.Threshold > len(ma.PublicKeys) {
+		return fmt.Errorf("invalid threshold %d for %d keys", ma.Threshold, len(ma.PublicKeys))
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range ma.PublicKeys {
+		if CanonicalPublicKey(key) != key {
+			return fmt.Errorf("public key %.16s... is not in canonical form", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("duplicate public key %.16s...", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// CanonicalPublicKey returns the form PublicKeyToString gives a key, or an
+// empty string if it is not a valid public key.
+func CanonicalPublicKey(publicKey string) string {
+	key, err := StringToPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	return PublicKeyToString(key)
+}
+
+func (ma *MultisigAccount) Display() {
+	fmt.Printf("MULTISIG ACCOUNT (%d-of-%d)\n", ma.Threshold, len(ma.PublicKeys))
+	fmt.Printf("├─ Address: %s\n", ma.Address)
+	for i, key := range ma.PublicKeys {
+		prefix := "├─"
+		if i == len(ma.PublicKeys)-1 {
+			prefix = "└─"
+		}
+		fmt.Printf("%s Key %d: %s...\n", prefix, i+1, key[:16])
+	}
+}
+
+func StringToPublicKey(publicKeyHex string) (*ecdsa.PublicKey, error) {
+	if len(publicKeyHex) != 128 {
+		return nil, fmt.Errorf("invalid public key length: expected 128 hex characters, got %d", len(publicKeyHex))
+	}
+
+	keyBytes, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key format: not valid hexadecimal")
+	}
+
+	if _, err := ecdh.P256().NewPublicKey(append([]byte{4}, keyBytes...)); err != nil {
+		return nil, fmt.Errorf("invalid public key: point is not on curve")
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(keyBytes[:32]),
+		Y:     new(big.Int).SetBytes(keyBytes[32:]),
+	}, nil
+}
@@ -17,7 +17,9 @@ func SignData(privateKey *ecdsa.PrivateKey, data []byte) (string, error) {
 		return "", fmt.Errorf("failed to sign data: %v", err)
 	}
 	
-	signature := append(r.Bytes(), s.Bytes()...)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
 	return hex.EncodeToString(signature), nil
 }
 
@@ -95,7 +95,10 @@ func PublicKeyToString(pubKey *ecdsa.PublicKey) string {
 	if pubKey == nil || pubKey.X == nil || pubKey.Y == nil {
 		return ""
 	}
-	return fmt.Sprintf("%x", pubKey.X.Bytes()) + fmt.Sprintf("%x", pubKey.Y.Bytes())
+	keyBytes := make([]byte, 64)
+	pubKey.X.FillBytes(keyBytes[:32])
+	pubKey.Y.FillBytes(keyBytes[32:])
+	return hex.EncodeToString(keyBytes)
 }
 
 func (w *Wallet) Display() {
@@ -74,6 +74,30 @@ func (tp *TransactionProcessor) ProcessTransaction(tx *core.Transaction) error {
 				if tx.Sender == tx.Receiver {
 						return fmt.Errorf("cannot transfer to self")
 				}
+		case core.AttestationTx:
+				if tx.Reference == "" {
+						return fmt.Errorf("attestations must reference a transaction")
+				}
+		case core.AmendmentTx:
+				if tx.Reference == "" {
+						return fmt.Errorf("amendments must reference a transaction")
+				}
+		case core.DelegationTx:
+				if tx.Receiver == "" {
+						return fmt.Errorf("delegations require a delegate address")
+				}
+		case core.SchemaTx:
+				if tx.SchemaID == "" {
+						return fmt.Errorf("schema registrations require a schema id")
+				}
+		case core.BatchTx:
+				if len(tx.Data) != 64 {
+						return fmt.Errorf("batches must carry a merkle root")
+				}
+		case core.ErasureTx:
+				if tx.Data == "" {
+						return fmt.Errorf("erasures must name a subject key id")
+				}
 		case core.StakeTx:
 				if tx.Amount == 0 {
 						return fmt.Errorf("staking amount must be greater than 0")
@@ -84,7 +108,7 @@ func (tp *TransactionProcessor) ProcessTransaction(tx *core.Transaction) error {
 		}
 	
 	switch tx.Type {
-		case core.DataTx:
+		case core.DataTx, core.AttestationTx, core.AmendmentTx, core.DelegationTx, core.SchemaTx, core.ErasureTx, core.BatchTx:
 			return tp.processDataTransaction(tx)
 		case core.TransferTx: 
 			return tp.processTransferTransaction(tx)
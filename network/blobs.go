@@ -0,0 +1,159 @@
+package network
+
+import (
+	"bufio"
+	"chainlog/core"
+	"chainlog/storage"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// blobChunkSize is how much of a blob one BLOB message carries. Larger
+// blobs are served over several requests on the same connection.
+const blobChunkSize = 256 * 1024
+
+type BlobMessage struct {
+	Hash    string `json:"hash"`
+	Offset  int64  `json:"offset,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Content []byte `json:"content,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RequestBlob fetches an anchored payload from the first connected peer
+// that has it.
+func (n *Node) RequestBlob(ref *core.PayloadRef) error {
+	logf(LogDebug, "Requesting blob %.16s... from peers\n", ref.Hash)
+
+	for address, peer := range n.Peers {
+		if !peer.Connected {
+			continue
+		}
+		if err := RemoteBlob(address, ref); err != nil {
+			logf(LogWarn, "Failed to fetch blob from %s: %v\n", address, err)
+			continue
+		}
+		logf(LogInfo, "Stored blob %.16s... (%d bytes) from %s\n", ref.Hash, ref.Size, address)
+		return nil
+	}
+	return fmt.Errorf("no peer could serve blob %.16s...", ref.Hash)
+}
+
+func (n *Node) handleGetBlob(msg Message, conn net.Conn) {
+	var request BlobMessage
+	if err := decodeMessageData(msg.Data, &request); err != nil {
+		logf(LogError, "Error decoding blob request: %v\n", err)
+		return
+	}
+
+	response := &BlobMessage{Hash: request.Hash, Offset: request.Offset}
+	content, size, err := storage.ReadBlobChunk(request.Hash, request.Offset, blobChunkSize)
+	if err != nil {
+		logf(LogWarn, "Cannot serve blob: %v\n", err)
+		response.Error = err.Error()
+	} else {
+		response.Size = size
+		response.Content = content
+	}
+
+	reply := Message{
+		Type:    MsgBlob,
+		Data:    response,
+		From:    n.Address,
+		Version: "1.0",
+	}
+
+	jsonData, err := json.Marshal(reply)
+	if err != nil {
+		logf(LogError, "Error marshaling blob response: %v\n", err)
+		return
+	}
+
+	conn.Write(jsonData)
+	logf(LogDebug, "Sent blob %.16s... bytes %d-%d to peer\n", request.Hash, request.Offset, request.Offset+int64(len(content)))
+}
+
+// RemoteBlob downloads a payload from a peer chunk by chunk and stores it.
+// Only blobs anchored on chain are requested, and the peer's content must
+// match the anchor's size and hash, so a peer cannot push data of its own.
+func RemoteBlob(address string, ref *core.PayloadRef) error {
+	if storage.HasBlob(ref.Hash) {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := reader.ReadString('\n'); err != nil {
+		return fmt.Errorf("no greeting from %s: %v", address, err)
+	}
+
+	writer, err := storage.NewBlobWriter()
+	if err != nil {
+		return err
+	}
+	defer writer.Abort()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+		request := Message{
+			Type:    MsgGetBlob,
+			Data:    &BlobMessage{Hash: ref.Hash, Offset: writer.Size()},
+			From:    conn.LocalAddr().String(),
+			Version: "1.0",
+		}
+		if err := json.NewEncoder(conn).Encode(request); err != nil {
+			return fmt.Errorf("failed to send blob request: %v", err)
+		}
+
+		var reply Message
+		if err := decoder.Decode(&reply); err != nil {
+			return fmt.Errorf("failed to read blob response: %v", err)
+		}
+		if reply.Type != MsgBlob {
+			return fmt.Errorf("unexpected %s reply from %s", reply.Type, address)
+		}
+
+		var chunk BlobMessage
+		if err := decodeMessageData(reply.Data, &chunk); err != nil {
+			return fmt.Errorf("invalid blob response: %v", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("%s: %s", address, chunk.Error)
+		}
+		if chunk.Hash != ref.Hash || chunk.Offset != writer.Size() || chunk.Size != ref.Size {
+			return fmt.Errorf("%s answered with a different blob than was requested", address)
+		}
+		if len(chunk.Content) == 0 && writer.Size() < ref.Size {
+			return fmt.Errorf("%s sent an empty chunk", address)
+		}
+		if writer.Size()+int64(len(chunk.Content)) > ref.Size {
+			return fmt.Errorf("%s sent more than the anchored %d bytes", address, ref.Size)
+		}
+		if _, err := writer.Write(chunk.Content); err != nil {
+			return fmt.Errorf("failed to write blob: %v", err)
+		}
+		if writer.Size() == ref.Size {
+			break
+		}
+	}
+
+	_, err = writer.Commit(ref.Hash)
+	return err
+}
+
+func decodeMessageData(data interface{}, target interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
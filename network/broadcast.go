@@ -2,17 +2,16 @@ package network
 
 import (
 	"chainlog/core"
-	"fmt"
 )
 
 func (n *Node) BroadcastTransaction(tx *core.Transaction) {
-	fmt.Printf("Broadcasting transaction to %d peers...\n", n.GetPeerCount())
+	logf(LogDebug, "Broadcasting transaction to %d peers...\n", n.GetPeerCount())
 	
 	for address, peer := range n.Peers {
 		if peer.Connected {
 			err := n.SendMessage(address, MsgNewTransaction, tx)
 			if err != nil {
-				fmt.Printf("Failed to broadcast to %s: %v\n", address, err)
+				logf(LogWarn, "Failed to broadcast to %s: %v\n", address, err)
 				peer.Connected = false
 			}
 		}
@@ -20,13 +19,13 @@ func (n *Node) BroadcastTransaction(tx *core.Transaction) {
 }
 
 func (n *Node) BroadcastBlock(block *core.Block) {
-	fmt.Printf("Broadcasting block %d to %d peers...\n", block.Index, n.GetPeerCount())
+	logf(LogDebug, "Broadcasting block %d to %d peers...\n", block.Index, n.GetPeerCount())
 	
 	for address, peer := range n.Peers {
 		if peer.Connected {
 			err := n.SendMessage(address, MsgNewBlock, block)
 			if err != nil {
-				fmt.Printf("Failed to broadcast to %s: %v\n", address, err)
+				logf(LogWarn, "Failed to broadcast to %s: %v\n", address, err)
 				peer.Connected = false
 			}
 		}
@@ -34,28 +33,46 @@ func (n *Node) BroadcastBlock(block *core.Block) {
 }
 
 func (n *Node) RequestBlocks() {
-	fmt.Printf("Requesting blocks from peers...\n")
+	logf(LogDebug, "Requesting blocks from peers...\n")
 	
+	n.chainMu.Lock()
+	from := int64(n.Blockchain.GetBlockCount())
+	n.chainMu.Unlock()
 	for address, peer := range n.Peers {
-		if peer.Connected {
-			err := n.SendMessage(address, MsgGetBlocks, map[string]interface{}{
-				"from_height": n.Blockchain.GetBlockCount(),
-			})
-			if err != nil {
-				fmt.Printf("Failed to request blocks from %s: %v\n", address, err)
-			}
+		if !peer.Connected {
+			continue
+		}
+
+		// Peers keep pruning while connected, so check the height in the
+		// greeting of this connection rather than the first one.
+		conn, err := n.dialPeer(address)
+		if err != nil {
+			logf(LogWarn, "Failed to request blocks from %s: %v\n", address, err)
+			continue
+		}
+		if peer.PrunedHeight > from {
+			logf(LogDebug, "Not asking %s for blocks: it is pruned below %d\n", address, peer.PrunedHeight)
+			conn.Close()
+			continue
+		}
+		err = n.writeMessage(conn, address, MsgGetBlocks, map[string]interface{}{
+			"from_height": from,
+		})
+		conn.Close()
+		if err != nil {
+			logf(LogWarn, "Failed to request blocks from %s: %v\n", address, err)
 		}
 	}
 }
 
 func (n *Node) RequestPeers() {
-	fmt.Printf("Discovering new peers...\n")
+	logf(LogDebug, "Discovering new peers...\n")
 	
 	for address, peer := range n.Peers {
 		if peer.Connected {
 			err := n.SendMessage(address, MsgGetPeers, nil)
 			if err != nil {
-				fmt.Printf("Failed to request peers from %s: %v\n", address, err)
+				logf(LogWarn, "Failed to request peers from %s: %v\n", address, err)
 			}
 		}
 	}
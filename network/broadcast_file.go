@@ -2,7 +2,7 @@ package network
 
 import (
 	"chainlog/core"
-	"fmt"
+	"chainlog/storage"
 	"os"
 	"strings"
 	"time"
@@ -23,7 +23,7 @@ func (n *Node) CheckBroadcastFile() {
 }
 
 func (n *Node) processBroadcastFile() {
-    filePath := "pending_broadcasts.txt"
+    filePath := storage.Path(storage.BroadcastQueueFile)
     data, err := os.ReadFile(filePath)
     if err != nil {
         return 
@@ -38,7 +38,7 @@ func (n *Node) processBroadcastFile() {
         
         if tx := n.findTransaction(txID); tx != nil {
             n.BroadcastTransaction(tx)
-            fmt.Printf("Broadcast transaction: %s...\n", txID[:16])
+            logf(LogInfo, "Broadcast transaction: %s...\n", txID[:16])
         }
     }
     
@@ -46,6 +46,9 @@ func (n *Node) processBroadcastFile() {
 }
 
 func (n *Node) findTransaction(txID string) *core.Transaction {
+    n.chainMu.Lock()
+    defer n.chainMu.Unlock()
+    
     for _, tx := range n.Blockchain.GetPendingTransactions() {
         if strings.HasPrefix(tx.ID, txID) {
             return tx
@@ -1,21 +1,20 @@
 package network
 
 import (
-	"fmt"
 	"time"
 )
 
 func (n *Node) Bootstrap(bootstrapNodes []string) {
-	fmt.Printf("Bootstrapping with %d nodes...\n", len(bootstrapNodes))
+	logf(LogInfo, "Bootstrapping with %d nodes...\n", len(bootstrapNodes))
 	
 	for _, nodeAddr := range bootstrapNodes {
 		n.AddPeer(nodeAddr)
 		
 		err := n.ConnectToPeer(nodeAddr)
 		if err != nil {
-			fmt.Printf("Failed to bootstrap with %s: %v\n", nodeAddr, err)
+			logf(LogWarn, "Failed to bootstrap with %s: %v\n", nodeAddr, err)
 		} else {
-			fmt.Printf("Successfully connected to %s\n", nodeAddr)
+			logf(LogInfo, "Successfully connected to %s\n", nodeAddr)
 		}
 		
 		time.Sleep(100 * time.Millisecond)
@@ -58,7 +57,7 @@ func (n *Node) reconnectDisconnectedPeers() {
 	
 	for addr, peer := range n.Peers {
 		if !peer.Connected && time.Since(peer.LastSeen) > 2*time.Minute {
-			fmt.Printf("Attempting to reconnect to %s...\n", addr)
+			logf(LogInfo, "Attempting to reconnect to %s...\n", addr)
 			go n.ConnectToPeer(addr)
 		}
 	}
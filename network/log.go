@@ -0,0 +1,48 @@
+package network
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+type LogLevel int32
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevel atomic.Int32
+
+func init() {
+	logLevel.Store(int32(LogInfo))
+}
+
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch name {
+	case "debug":
+		return LogDebug, nil
+	case "info":
+		return LogInfo, nil
+	case "warn":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	}
+	return LogInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// SetLogLevel hides node messages below level. Per-message traffic is
+// logged at debug, normal activity at info, failures talking to peers at
+// warn and local errors at error.
+func SetLogLevel(level LogLevel) {
+	logLevel.Store(int32(level))
+}
+
+func logf(level LogLevel, format string, args ...interface{}) {
+	if int32(level) >= logLevel.Load() {
+		fmt.Printf(format, args...)
+	}
+}
@@ -1,15 +1,20 @@
 package network
 
 import (
+	"bufio"
 	"chainlog/core"
 	"chainlog/crypto"
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+const greetingPrefix = "Hello from ChainLog node "
+
 type Node struct {
 	ID           string
 	Address      string          
@@ -21,6 +26,17 @@ type Node struct {
 	Server       net.Listener
 	mutex        sync.Mutex      
 	stopChan     chan bool
+	search       *core.SearchIndex
+	searchMu     sync.Mutex
+
+	// chainMu guards Blockchain, which peers' messages and the node's own
+	// miner and receivers all change.
+	chainMu sync.Mutex
+
+	conns    map[net.Conn]bool
+	handlers sync.WaitGroup
+	stopped  bool
+	stopOnce sync.Once
 }
 
 type Peer struct {
@@ -31,6 +47,10 @@ type Peer struct {
 	Encoder   *json.Encoder        
 	Decoder   *json.Decoder      
 	LastSeen  time.Time
+
+	// PrunedHeight is the height from which the peer still has every
+	// block's transactions, as announced in its greeting.
+	PrunedHeight int64
 }
 
 func NewNode(address string, wallet *crypto.Wallet, bc *core.Blockchain, isMiner bool) *Node {
@@ -42,6 +62,7 @@ func NewNode(address string, wallet *crypto.Wallet, bc *core.Blockchain, isMiner
 		Wallet:     wallet,
 		IsMiner:    isMiner,
 		stopChan:   make(chan bool),
+		conns:      make(map[net.Conn]bool),
 	}
 }
 
@@ -52,19 +73,65 @@ func (n *Node) Start() error {
 	}
 	n.Server = server
 
-	fmt.Printf(" Node %s started on %s\n", n.ID, n.Address)
+	logf(LogInfo, " Node %s started on %s\n", n.ID, n.Address)
 	
 	go n.acceptConnections()
 	
 	return nil
 }
 
+// Stop ends the background loops, stops accepting connections, closes the
+// open ones and waits for their handlers to return.
 func (n *Node) Stop() {
-	close(n.stopChan)
-	if n.Server != nil {
-		n.Server.Close()
+	n.stopOnce.Do(func() {
+		close(n.stopChan)
+		if n.Server != nil {
+			n.Server.Close()
+		}
+
+		n.mutex.Lock()
+		n.stopped = true
+		for conn := range n.conns {
+			conn.Close()
+		}
+		for _, peer := range n.Peers {
+			peer.Connected = false
+		}
+		n.mutex.Unlock()
+
+		n.handlers.Wait()
+		logf(LogInfo, "Node %s stopped\n", n.ID)
+	})
+}
+
+// WithChain runs fn holding the lock on the node's blockchain, so that no
+// block or transaction from a peer lands while fn reloads, changes or saves
+// it.
+func (n *Node) WithChain(fn func()) {
+	n.chainMu.Lock()
+	defer n.chainMu.Unlock()
+	fn()
+}
+
+// track registers a connection so Stop can close it, and reports false if
+// the node is already stopping.
+func (n *Node) track(conn net.Conn) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.stopped {
+		return false
 	}
-	fmt.Printf("Node %s stopped\n", n.ID)
+	n.conns[conn] = true
+	n.handlers.Add(1)
+	return true
+}
+
+func (n *Node) untrack(conn net.Conn) {
+	n.mutex.Lock()
+	delete(n.conns, conn)
+	n.mutex.Unlock()
+	n.handlers.Done()
 }
 
 func (n *Node) acceptConnections() {
@@ -75,33 +142,38 @@ func (n *Node) acceptConnections() {
 			case <-n.stopChan:
 				return 
 			default:
-				fmt.Printf("Connection error: %v\n", err)
+				logf(LogWarn, "Connection error: %v\n", err)
 				continue
 			}
 		}
 		
+		if !n.track(conn) {
+			conn.Close()
+			return
+		}
 		go n.handleConnection(conn)
 	}
 }
 
 func (n *Node) handleConnection(conn net.Conn) {
+	defer n.untrack(conn)
 	defer conn.Close()
 	
-	conn.Write([]byte("Hello from ChainLog node " + n.ID + "\n"))
-	fmt.Printf("New connection from %s\n", conn.RemoteAddr().String())
+	conn.Write([]byte(n.greeting()))
+	logf(LogDebug, "New connection from %s\n", conn.RemoteAddr().String())
 
 	buffer := make([]byte, 1024*1024)
 
 	for {
 			nBytes, err := conn.Read(buffer)
 			if err != nil {
-					fmt.Printf("Connection closed: %v\n", err)
+					logf(LogDebug, "Connection closed: %v\n", err)
 					return
 			}
 			
 			var msg Message
 			if err := json.Unmarshal(buffer[:nBytes], &msg); err != nil {
-					fmt.Printf("Error parsing message: %v\n", err)
+					logf(LogWarn, "Error parsing message: %v\n", err)
 					continue
 			}
 			
@@ -109,6 +181,34 @@ func (n *Node) handleConnection(conn net.Conn) {
 	}
 }
 
+// greeting is the handshake line sent to every new connection. A pruned
+// node appends the height from which it can still serve full blocks.
+func (n *Node) greeting() string {
+	line := greetingPrefix + n.ID
+	n.chainMu.Lock()
+	height := n.Blockchain.PrunedHeight()
+	n.chainMu.Unlock()
+	if height > 0 {
+		line += fmt.Sprintf(" pruned=%d", height)
+	}
+	return line + "\n"
+}
+
+// parseGreeting returns the pruned height a peer announced, or zero for a
+// full node.
+func parseGreeting(line string) int64 {
+	if !strings.HasPrefix(line, greetingPrefix) {
+		return 0
+	}
+	for _, field := range strings.Fields(line[len(greetingPrefix):]) {
+		if value, found := strings.CutPrefix(field, "pruned="); found {
+			height, _ := strconv.ParseInt(value, 10, 64)
+			return height
+		}
+	}
+	return 0
+}
+
 func (n *Node) AddPeer(address string) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
@@ -120,7 +220,7 @@ func (n *Node) AddPeer(address string) {
 			Connected: false,
 			LastSeen:  time.Now(),
 		}
-		fmt.Printf("Added peer: %s\n", address)
+		logf(LogInfo, "Added peer: %s\n", address)
 	}
 }
 
@@ -130,17 +230,37 @@ func (n *Node) ConnectToPeer(address string) error {
 			return err
 	}
 	
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("no greeting from %s: %v", address, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
 	peer := &Peer{
 			ID:        "peer-" + address,
 			Address:   address,
 			Connected: true,
 			Conn:      conn,
 			Encoder:   json.NewEncoder(conn),
-			Decoder:   json.NewDecoder(conn),
+			Decoder:   json.NewDecoder(reader),
 			LastSeen:  time.Now(),
+			PrunedHeight: parseGreeting(line),
+	}
+	if peer.PrunedHeight > 0 {
+		logf(LogInfo, "Peer %s is pruned, full blocks from height %d\n", address, peer.PrunedHeight)
 	}
 	
+	if !n.track(conn) {
+		conn.Close()
+		return fmt.Errorf("node is stopping")
+	}
+
+	n.mutex.Lock()
 	n.Peers[address] = peer
+	n.mutex.Unlock()
 	
 	go n.listenToPeer(peer)
 	
@@ -148,6 +268,8 @@ func (n *Node) ConnectToPeer(address string) error {
 }
 
 func (n *Node) listenToPeer(peer *Peer) {
+	defer n.untrack(peer.Conn)
+
 	for {
 		var msg Message
 		if err := peer.Decoder.Decode(&msg); err != nil {
@@ -171,7 +293,10 @@ func (n *Node) Display() {
 	fmt.Printf("├─ Address: %s\n", n.Address)
 	fmt.Printf("├─ Wallet: %s\n", n.Wallet.GetAddressShort())
 	fmt.Printf("├─ Miner: %t\n", n.IsMiner)
-	fmt.Printf("├─ Blockchain Height: %d\n", n.Blockchain.GetBlockCount())
+	n.chainMu.Lock()
+	height := n.Blockchain.GetBlockCount()
+	n.chainMu.Unlock()
+	fmt.Printf("├─ Blockchain Height: %d\n", height)
 	fmt.Printf("└─ Peers: %d\n", n.GetPeerCount())
 	
 	if n.GetPeerCount() > 0 {
@@ -182,8 +307,12 @@ func (n *Node) Display() {
 			if peer.Connected {
 				status = "Online"
 			}
-			fmt.Printf("   %s %s (last seen: %v ago)\n", 
-				status, addr, time.Since(peer.LastSeen).Round(time.Second))
+			pruned := ""
+			if peer.PrunedHeight > 0 {
+				pruned = fmt.Sprintf(", pruned below %d", peer.PrunedHeight)
+			}
+			fmt.Printf("   %s %s (last seen: %v ago%s)\n", 
+				status, addr, time.Since(peer.LastSeen).Round(time.Second), pruned)
 		}
 		n.mutex.Unlock()
 	}
@@ -0,0 +1,48 @@
+package network
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"sync"
+	"testing"
+)
+
+// Transactions from peers arrive while the node's own processes reload the
+// chain; run with -race to check they all go through the chain lock.
+func TestNodeChainLock(t *testing.T) {
+	SetLogLevel(LogError)
+	wallet, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	bc := core.NewBlockchain()
+	node := NewNode("127.0.0.1:0", wallet, bc, false)
+
+	const count = 20
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		tx, err := core.NewDataTransaction(string(rune('a'+i)), wallet, 1)
+		if err != nil {
+			t.Fatalf("NewDataTransaction: %v", err)
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			node.HandleMessage(Message{Type: MsgNewTransaction, Data: tx}, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			// What a reload does to the pending pool.
+			node.WithChain(func() {
+				bc.PendingTx = append([]*core.Transaction{}, bc.PendingTx...)
+			})
+		}()
+	}
+	wg.Wait()
+
+	node.WithChain(func() {
+		if got := len(bc.PendingTx); got != count {
+			t.Errorf("pool has %d transactions, want %d", got, count)
+		}
+	})
+}
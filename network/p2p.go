@@ -1,10 +1,12 @@
 package network
 
 import (
+	"bufio"
 	"chainlog/core"
 	"encoding/json"
 	"fmt"
 	"net"
+	"time"
 )
 
 type MessageType string
@@ -16,6 +18,12 @@ const (
 	MsgBlocks      MessageType = "BLOCKS"
 	MsgGetPeers    MessageType = "GET_PEERS"
 	MsgPeers       MessageType = "PEERS"
+	MsgGetBlob     MessageType = "GET_BLOB"
+	MsgBlob        MessageType = "BLOB"
+	MsgSearch      MessageType = "SEARCH"
+	MsgSearchResults MessageType = "SEARCH_RESULTS"
+	MsgGetSnapshot MessageType = "GET_SNAPSHOT"
+	MsgSnapshot    MessageType = "SNAPSHOT"
 )
 
 type Message struct {
@@ -26,12 +34,40 @@ type Message struct {
 }
 
 func (n *Node) SendMessage(peerAddress string, msgType MessageType, data interface{}) error {
-	conn, err := net.Dial("tcp", peerAddress)
+	conn, err := n.dialPeer(peerAddress)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
-	
+	return n.writeMessage(conn, peerAddress, msgType, data)
+}
+
+// dialPeer connects to a peer and reads its greeting. The greeting carries
+// the peer's current pruned height, which replaces the one it announced
+// when it was first connected.
+func (n *Node) dialPeer(peerAddress string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", peerAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no greeting from %s: %v", peerAddress, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	n.mutex.Lock()
+	if peer, exists := n.Peers[peerAddress]; exists {
+		peer.PrunedHeight = parseGreeting(line)
+	}
+	n.mutex.Unlock()
+	return conn, nil
+}
+
+func (n *Node) writeMessage(conn net.Conn, peerAddress string, msgType MessageType, data interface{}) error {
 	message := Message{
 		Type:    msgType,
 		Data:    data,
@@ -44,17 +80,16 @@ func (n *Node) SendMessage(peerAddress string, msgType MessageType, data interfa
 		return err
 	}
 	
-	_, err = conn.Write(jsonData)
-	if err != nil {
+	if _, err := conn.Write(jsonData); err != nil {
 		return err
 	}
 	
-	fmt.Printf("Sent %s message to %s\n", msgType, peerAddress)
+	logf(LogDebug, "Sent %s message to %s\n", msgType, peerAddress)
 	return nil
 }
 
 func (n *Node) HandleMessage(msg Message, conn net.Conn) {
-	fmt.Printf("Received %s message from %s\n", msg.Type, msg.From)
+	logf(LogDebug, "Received %s message from %s\n", msg.Type, msg.From)
 	
 	switch msg.Type {
 	case MsgNewBlock:
@@ -65,78 +100,97 @@ func (n *Node) HandleMessage(msg Message, conn net.Conn) {
 		n.handleGetBlocks(msg, conn)
 	case MsgGetPeers:
 		n.handleGetPeers(msg, conn)
+	case MsgGetBlob:
+		n.handleGetBlob(msg, conn)
+	case MsgBlob:
+		logf(LogWarn, "Ignoring unrequested blob from %s\n", msg.From)
+	case MsgSearch:
+		n.handleSearch(msg, conn)
+	case MsgGetSnapshot:
+		n.handleGetSnapshot(msg, conn)
 	default:
-		fmt.Printf("Unknown message type: %s\n", msg.Type)
+		logf(LogWarn, "Unknown message type: %s\n", msg.Type)
 	}
 }
 
 func (n *Node) handleNewBlock(msg Message) {
-    fmt.Printf("   ↳ New block received from network\n")
+    logf(LogDebug, "   ↳ New block received from network\n")
     
     blockData, err := json.Marshal(msg.Data)
     if err != nil {
-        fmt.Printf("Error marshaling block data: %v\n", err)
+        logf(LogError, "Error marshaling block data: %v\n", err)
         return
     }
     
     var block core.Block
     if err := json.Unmarshal(blockData, &block); err != nil {
-        fmt.Printf("Error unmarshaling block: %v\n", err)
+        logf(LogError, "Error unmarshaling block: %v\n", err)
+        return
+    }
+    
+    if block.Pruned {
+        logf(LogWarn, "Rejected block %d: it carries no transactions\n", block.Index)
         return
     }
     
+    n.chainMu.Lock()
+    defer n.chainMu.Unlock()
+    
     validator := core.NewValidator(n.Blockchain)
     if !validator.ValidateBlock(&block) {
-        fmt.Printf("Invalid block received: %d\n", block.Index)
+        logf(LogWarn, "Invalid block received: %d\n", block.Index)
         return
     }
     
     currentHeight := n.Blockchain.GetBlockCount()
     if block.Index <= int64(currentHeight-1) {
-        fmt.Printf("Already have block %d\n", block.Index)
+        logf(LogDebug, "Already have block %d\n", block.Index)
         return
     }
     
-    n.Blockchain.Chain = append(n.Blockchain.Chain, &block)
-    fmt.Printf("Added block %d from network\n", block.Index)
+    n.Blockchain.AppendBlock(&block)
+    logf(LogInfo, "Added block %d from network\n", block.Index)
     
     n.Blockchain.ClearPendingTransactions()
 }
 
 func (n *Node) handleNewTransaction(msg Message) {
-    fmt.Printf("   ↳ New transaction received from network\n")
+    logf(LogDebug, "   ↳ New transaction received from network\n")
     
     txData, err := json.Marshal(msg.Data)
     if err != nil {
-        fmt.Printf("Error marshaling transaction data: %v\n", err)
+        logf(LogError, "Error marshaling transaction data: %v\n", err)
         return
     }
     
     var tx core.Transaction
     if err := json.Unmarshal(txData, &tx); err != nil {
-        fmt.Printf("Error unmarshaling transaction: %v\n", err)
+        logf(LogError, "Error unmarshaling transaction: %v\n", err)
         return
     }
     
+    n.chainMu.Lock()
+    defer n.chainMu.Unlock()
+    
     validator := core.NewValidator(n.Blockchain)
-    if !validator.ValidateTransaction(&tx) {
-        fmt.Printf("Invalid transaction received: %s\n", tx.ID[:16])
+    if !validator.AdmitTransaction(&tx) {
+        logf(LogWarn, "Invalid transaction received: %s\n", tx.ID[:16])
         return
     }
     
     for _, pendingTx := range n.Blockchain.PendingTx {
         if pendingTx.ID == tx.ID {
-            fmt.Printf("Transaction already in pool: %s\n", tx.ID[:16])
+            logf(LogDebug, "Transaction already in pool: %s\n", tx.ID[:16])
             return
         }
     }
     
     n.Blockchain.PendingTx = append(n.Blockchain.PendingTx, &tx)
-    fmt.Printf("Added transaction to pool: %s\n", tx.ID[:16])
+    logf(LogInfo, "Added transaction to pool: %s\n", tx.ID[:16])
 }
 
 func (n *Node) handleGetBlocks(msg Message, conn net.Conn) {
-    fmt.Printf("   ↳ Sending blockchain to peer\n")
+    logf(LogDebug, "   ↳ Sending blockchain to peer\n")
     
     fromHeight := 0
     if data, ok := msg.Data.(map[string]interface{}); ok {
@@ -145,6 +199,13 @@ func (n *Node) handleGetBlocks(msg Message, conn net.Conn) {
         }
     }
     
+    n.chainMu.Lock()
+    if pruned := int(n.Blockchain.PrunedHeight()); fromHeight < pruned {
+        n.chainMu.Unlock()
+        logf(LogWarn, "Cannot send blocks from %d: transactions below %d have been pruned\n", fromHeight, pruned)
+        return
+    }
+    
     // Send blocks starting from requested height
     var blocksToSend []*core.Block
     for i := fromHeight; i < n.Blockchain.GetBlockCount(); i++ {
@@ -152,6 +213,7 @@ func (n *Node) handleGetBlocks(msg Message, conn net.Conn) {
             blocksToSend = append(blocksToSend, n.Blockchain.Chain[i])
         }
     }
+    n.chainMu.Unlock()
     
     response := Message{
         Type:    MsgBlocks,
@@ -162,16 +224,16 @@ func (n *Node) handleGetBlocks(msg Message, conn net.Conn) {
     
     jsonData, err := json.Marshal(response)
     if err != nil {
-        fmt.Printf("Error marshaling blocks response: %v\n", err)
+        logf(LogError, "Error marshaling blocks response: %v\n", err)
         return
     }
     
     conn.Write(jsonData)
-    fmt.Printf("Sent %d blocks to peer\n", len(blocksToSend))
+    logf(LogDebug, "Sent %d blocks to peer\n", len(blocksToSend))
 }
 
 func (n *Node) handleGetPeers(msg Message, conn net.Conn) {
-    fmt.Printf("   ↳ Sending peer list to peer\n")
+    logf(LogDebug, "   ↳ Sending peer list to peer\n")
     
 		peerAddresses := make([]string, 0, len(n.Peers))
     for addr := range n.Peers {
@@ -187,10 +249,10 @@ func (n *Node) handleGetPeers(msg Message, conn net.Conn) {
     
     jsonData, err := json.Marshal(response)
     if err != nil {
-        fmt.Printf("Error marshaling peers response: %v\n", err)
+        logf(LogError, "Error marshaling peers response: %v\n", err)
         return
     }
     
     conn.Write(jsonData)
-    fmt.Printf("Sent %d peer addresses to peer\n", len(peerAddresses))
+    logf(LogDebug, "Sent %d peer addresses to peer\n", len(peerAddresses))
 }
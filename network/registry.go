@@ -1,6 +1,7 @@
 package network
 
 import (
+	"chainlog/storage"
 	"encoding/json"
 	"os"
 	"sync"
@@ -78,11 +79,11 @@ func (nr *NodeRegistry) GetRunningNodes() []*NodeInfo {
 
 func (nr *NodeRegistry) save() {
 	data, _ := json.Marshal(nr.Nodes)
-	os.WriteFile("chainlog-data/nodes.json", data, 0644)
+	storage.WriteFileAtomic(storage.Path(storage.NodesFile), data, 0644)
 }
 
 func (nr *NodeRegistry) load() {
-	data, err := os.ReadFile("chainlog-data/nodes.json")
+	data, err := os.ReadFile(storage.Path(storage.NodesFile))
 	if err != nil {
 		return
 	}
@@ -0,0 +1,122 @@
+package network
+
+import (
+	"bufio"
+	"chainlog/core"
+	"chainlog/storage"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+const maxSearchResults = 100
+
+type SearchRequest struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+type SearchResponse struct {
+	Results *core.SearchResults `json:"results,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+func (n *Node) searchIndex() (*core.SearchIndex, error) {
+	n.searchMu.Lock()
+	defer n.searchMu.Unlock()
+
+	if n.search == nil {
+		index, err := storage.LoadSearchIndex()
+		if err != nil {
+			return nil, err
+		}
+		n.search = index
+	}
+
+	n.search.Sync(n.Blockchain.Chain)
+	return n.search, nil
+}
+
+func (n *Node) handleSearch(msg Message, conn net.Conn) {
+	var request SearchRequest
+	response := &SearchResponse{}
+
+	n.chainMu.Lock()
+	if err := decodeMessageData(msg.Data, &request); err != nil {
+		response.Error = fmt.Sprintf("invalid search request: %v", err)
+	} else if index, err := n.searchIndex(); err != nil {
+		response.Error = err.Error()
+	} else {
+		if request.Limit <= 0 || request.Limit > maxSearchResults {
+			request.Limit = maxSearchResults
+		}
+		response.Results = index.Search(request.Query, request.Limit)
+		n.Blockchain.DescribeSearchHits(response.Results.Hits)
+	}
+	n.chainMu.Unlock()
+
+	reply := Message{
+		Type:    MsgSearchResults,
+		Data:    response,
+		From:    n.Address,
+		Version: "1.0",
+	}
+
+	jsonData, err := json.Marshal(reply)
+	if err != nil {
+		logf(LogError, "Error marshaling search response: %v\n", err)
+		return
+	}
+
+	conn.Write(jsonData)
+	if response.Error != "" {
+		logf(LogWarn, "Search failed: %s\n", response.Error)
+		return
+	}
+	logf(LogDebug, "Sent %d search results for %q\n", len(response.Results.Hits), request.Query)
+}
+
+func RemoteSearch(address, query string, limit int) (*core.SearchResults, error) {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.ReadString('\n'); err != nil {
+		return nil, fmt.Errorf("no greeting from %s: %v", address, err)
+	}
+
+	request := Message{
+		Type:    MsgSearch,
+		Data:    &SearchRequest{Query: query, Limit: limit},
+		From:    conn.LocalAddr().String(),
+		Version: "1.0",
+	}
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("failed to send search request: %v", err)
+	}
+
+	var reply Message
+	if err := json.NewDecoder(reader).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to read search response: %v", err)
+	}
+	if reply.Type != MsgSearchResults {
+		return nil, fmt.Errorf("unexpected %s reply from %s", reply.Type, address)
+	}
+
+	var response SearchResponse
+	if err := decodeMessageData(reply.Data, &response); err != nil {
+		return nil, fmt.Errorf("invalid search response: %v", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s: %s", address, response.Error)
+	}
+	if response.Results == nil {
+		return nil, fmt.Errorf("%s returned no results", address)
+	}
+	return response.Results, nil
+}
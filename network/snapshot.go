@@ -0,0 +1,129 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"chainlog/core"
+	"chainlog/storage"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+const maxServedSnapshotSize = 64 * 1024 * 1024
+
+type SnapshotResponse struct {
+	Height    int64  `json:"height,omitempty"`
+	BlockHash string `json:"block_hash,omitempty"`
+	Content   []byte `json:"content,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// buildSnapshot snapshots what the node has saved, which is where accounts
+// and staking live; blocks only held in memory are not part of it yet.
+func buildSnapshot() (*storage.Snapshot, []byte, error) {
+	ledger := storage.NewLedgerManager(core.NewBlockchain())
+	if err := ledger.LoadBlockchain(); err != nil {
+		return nil, nil, err
+	}
+	snap, err := ledger.CreateSnapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := storage.EncodeSnapshot(snap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(content) > maxServedSnapshotSize {
+		return nil, nil, fmt.Errorf("snapshot is %d bytes, too large to serve over P2P", len(content))
+	}
+	return snap, content, nil
+}
+
+func (n *Node) handleGetSnapshot(msg Message, conn net.Conn) {
+	response := &SnapshotResponse{}
+
+	snap, content, err := buildSnapshot()
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Height = snap.Height
+		response.BlockHash = snap.BlockHash
+		response.Content = content
+	}
+
+	reply := Message{
+		Type:    MsgSnapshot,
+		Data:    response,
+		From:    n.Address,
+		Version: "1.0",
+	}
+
+	jsonData, err := json.Marshal(reply)
+	if err != nil {
+		logf(LogError, "Error marshaling snapshot response: %v\n", err)
+		return
+	}
+
+	conn.Write(jsonData)
+	if response.Error != "" {
+		logf(LogWarn, "Cannot serve snapshot: %s\n", response.Error)
+		return
+	}
+	logf(LogInfo, "Sent snapshot at height %d (%d bytes) to peer\n", response.Height, len(content))
+}
+
+// RemoteSnapshot downloads a peer's snapshot and checks that it is
+// consistent. That does not make it trustworthy: whether its headers lead
+// to a trusted block is left to the caller or to the restore.
+func RemoteSnapshot(address string) (*storage.Snapshot, error) {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Minute))
+
+	reader := bufio.NewReader(conn)
+	if _, err := reader.ReadString('\n'); err != nil {
+		return nil, fmt.Errorf("no greeting from %s: %v", address, err)
+	}
+
+	request := Message{
+		Type:    MsgGetSnapshot,
+		From:    conn.LocalAddr().String(),
+		Version: "1.0",
+	}
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("failed to send snapshot request: %v", err)
+	}
+
+	var reply Message
+	if err := json.NewDecoder(reader).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot response: %v", err)
+	}
+	if reply.Type != MsgSnapshot {
+		return nil, fmt.Errorf("unexpected %s reply from %s", reply.Type, address)
+	}
+
+	var response SnapshotResponse
+	if err := decodeMessageData(reply.Data, &response); err != nil {
+		return nil, fmt.Errorf("invalid snapshot response: %v", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s: %s", address, response.Error)
+	}
+
+	snap, err := storage.ReadSnapshot(bytes.NewReader(response.Content))
+	if err != nil {
+		return nil, err
+	}
+	if snap.Height != response.Height || snap.BlockHash != response.BlockHash {
+		return nil, fmt.Errorf("%s sent a snapshot for a different block than it announced", address)
+	}
+	if err := snap.Check(); err != nil {
+		return nil, fmt.Errorf("snapshot from %s is invalid: %v", address, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,292 @@
+package sdk
+
+import (
+	"bytes"
+	"chainlog/core"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ArchiveFormat  = "chainlog-archive"
+	ArchiveVersion = 1
+
+	ArchiveJSONL = "jsonl"
+	ArchiveCBOR  = "cbor"
+)
+
+// ArchiveManifest opens every archive and describes the blocks that follow
+// it. ContentHash is the SHA-256 of everything after the manifest, so a
+// reader can tell whether any block record was altered, dropped or added.
+type ArchiveManifest struct {
+	Format       string `json:"format"`
+	Version      int    `json:"version"`
+	Encoding     string `json:"encoding"`
+	CreatedAt    int64  `json:"created_at"`
+	GenesisHash  string `json:"genesis_hash"`
+	From         int64  `json:"from"`
+	To           int64  `json:"to"`
+	Blocks       int64  `json:"blocks"`
+	Transactions int64  `json:"transactions"`
+	PrevHash     string `json:"prev_hash"`
+	TipHash      string `json:"tip_hash"`
+	ContentHash  string `json:"content_hash"`
+}
+
+// Archive is a range of blocks in a portable file: a manifest followed by
+// one record per block, either as JSON lines or as a CBOR sequence. It
+// does not depend on how a node stores its blocks.
+type Archive struct {
+	Manifest *ArchiveManifest
+	Blocks   []*core.Block
+}
+
+func NewArchive(chain []*core.Block, from, to int64, encoding string) (*Archive, error) {
+	if encoding != ArchiveJSONL && encoding != ArchiveCBOR {
+		return nil, fmt.Errorf("unknown archive format %q (use %s or %s)", encoding, ArchiveJSONL, ArchiveCBOR)
+	}
+	if from < 0 || to < from || to >= int64(len(chain)) {
+		return nil, fmt.Errorf("invalid block range %d-%d (chain has blocks 0-%d)", from, to, len(chain)-1)
+	}
+
+	blocks := chain[from : to+1]
+	for _, block := range blocks {
+		if block.Pruned {
+			return nil, fmt.Errorf("block %d has been pruned and cannot be exported", block.Index)
+		}
+	}
+
+	manifest := &ArchiveManifest{
+		Format:      ArchiveFormat,
+		Version:     ArchiveVersion,
+		Encoding:    encoding,
+		CreatedAt:   time.Now().Unix(),
+		GenesisHash: chain[0].Hash,
+		From:        from,
+		To:          to,
+		Blocks:      int64(len(blocks)),
+		PrevHash:    blocks[0].PrevHash,
+		TipHash:     blocks[len(blocks)-1].Hash,
+	}
+	for _, block := range blocks {
+		manifest.Transactions += int64(len(block.Transactions))
+	}
+	return &Archive{Manifest: manifest, Blocks: blocks}, nil
+}
+
+func (a *Archive) Write(w io.Writer) error {
+	var content bytes.Buffer
+	for _, block := range a.Blocks {
+		record, err := encodeArchiveRecord(block, a.Manifest.Encoding)
+		if err != nil {
+			return fmt.Errorf("failed to encode block %d: %v", block.Index, err)
+		}
+		content.Write(record)
+	}
+
+	sum := sha256.Sum256(content.Bytes())
+	a.Manifest.ContentHash = hex.EncodeToString(sum[:])
+
+	manifest, err := encodeArchiveRecord(a.Manifest, a.Manifest.Encoding)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	if _, err := w.Write(manifest); err != nil {
+		return err
+	}
+	_, err = w.Write(content.Bytes())
+	return err
+}
+
+// ReadArchive parses an archive in either format and checks it against its
+// content hash. The blocks themselves are checked by Verify.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("archive is empty")
+	}
+
+	var records [][]byte
+	encoding := ArchiveCBOR
+	if data[0] == '{' {
+		encoding = ArchiveJSONL
+		records = bytes.SplitAfter(data, []byte("\n"))
+		if len(records[len(records)-1]) == 0 {
+			records = records[:len(records)-1]
+		}
+	} else if records, err = core.SplitCBORSequence(data); err != nil {
+		return nil, fmt.Errorf("not a chain archive: %v", err)
+	}
+
+	var manifest ArchiveManifest
+	if err := decodeArchiveRecord(records[0], encoding, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest: %v", err)
+	}
+	if manifest.Format != ArchiveFormat {
+		return nil, fmt.Errorf("not a chain archive")
+	}
+	if manifest.Version != ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+	if manifest.Encoding != encoding {
+		return nil, fmt.Errorf("manifest says %s but the archive is %s", manifest.Encoding, encoding)
+	}
+
+	sum := sha256.Sum256(data[len(records[0]):])
+	if hex.EncodeToString(sum[:]) != manifest.ContentHash {
+		return nil, fmt.Errorf("archive content does not match its manifest hash")
+	}
+
+	archive := &Archive{Manifest: &manifest}
+	for i, record := range records[1:] {
+		var block core.Block
+		if err := decodeArchiveRecord(record, encoding, &block); err != nil {
+			return nil, fmt.Errorf("invalid block record %d: %v", i, err)
+		}
+		archive.Blocks = append(archive.Blocks, &block)
+	}
+	return archive, nil
+}
+
+// Verify fully checks every block in the archive: heights and hash links
+// run unbroken from PrevHash to TipHash, each block hashes correctly and
+// meets the minimum proof of work, its transactions match its Merkle root,
+// and every transaction keeps its ID and is signed by its sender, or is a
+// version 0 transaction holding only what its ID covers. Reward
+// transactions carry no content-derived ID or signature, so only the
+// content hash covers their bodies.
+func (a *Archive) Verify() error {
+	m := a.Manifest
+	if int64(len(a.Blocks)) != m.Blocks || m.Blocks != m.To-m.From+1 {
+		return fmt.Errorf("archive holds %d blocks, manifest lists %d-%d", len(a.Blocks), m.From, m.To)
+	}
+
+	seen := make(map[string]bool)
+	var transactions int64
+	prevHash := m.PrevHash
+	var prevTime int64
+
+	for i, block := range a.Blocks {
+		height := m.From + int64(i)
+		if block.Index != height {
+			return fmt.Errorf("record %d holds block %d, expected %d", i, block.Index, height)
+		}
+		if block.Pruned {
+			return fmt.Errorf("block %d has no transactions", height)
+		}
+		if block.PrevHash != prevHash {
+			return fmt.Errorf("block %d does not point to the block before it", height)
+		}
+		if height == 0 && (block.PrevHash != "" || block.Hash != m.GenesisHash) {
+			return fmt.Errorf("block 0 is not the genesis block named in the manifest")
+		}
+		if block.CalculateHash() != block.Hash {
+			return fmt.Errorf("block %d does not hash to %.16s...", height, block.Hash)
+		}
+		if !block.Header().HasValidWork() {
+			return fmt.Errorf("block %d does not meet the minimum proof of work", height)
+		}
+		if block.MerkleRoot != "" && block.MerkleRoot != block.ComputeMerkleRoot() {
+			return fmt.Errorf("block %d transactions do not match its merkle root", height)
+		}
+		if i > 0 && block.Timestamp < prevTime {
+			return fmt.Errorf("block %d is timestamped before the block before it", height)
+		}
+
+		for _, tx := range block.Transactions {
+			if seen[tx.ID] {
+				return fmt.Errorf("transaction %.16s... appears twice", tx.ID)
+			}
+			seen[tx.ID] = true
+
+			if tx.Type == core.RewardTx {
+				continue
+			}
+			if tx.ID != tx.CalculateID() {
+				return fmt.Errorf("transaction %.16s... in block %d has an invalid ID", tx.ID, height)
+			}
+			if err := tx.VerifyConfirmed(); err != nil {
+				return fmt.Errorf("transaction %.16s... in block %d: %v", tx.ID, height, err)
+			}
+		}
+		transactions += int64(len(block.Transactions))
+
+		prevHash = block.Hash
+		prevTime = block.Timestamp
+	}
+
+	if prevHash != m.TipHash {
+		return fmt.Errorf("archive ends at %.16s..., manifest says %.16s...", prevHash, m.TipHash)
+	}
+	if transactions != m.Transactions {
+		return fmt.Errorf("archive holds %d transactions, manifest lists %d", transactions, m.Transactions)
+	}
+	return nil
+}
+
+func encodeArchiveRecord(value interface{}, encoding string) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == ArchiveJSONL {
+		return append(data, '\n'), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return core.EncodeCBOR(cborValue(generic))
+}
+
+func decodeArchiveRecord(record []byte, encoding string, target interface{}) error {
+	if encoding == ArchiveJSONL {
+		return json.Unmarshal(record, target)
+	}
+
+	generic, err := core.DecodeCBOR(record)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// cborValue turns the numbers in decoded JSON back into integers where
+// they are integers, so 64-bit nonces survive the trip through CBOR.
+func cborValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = cborValue(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = cborValue(v[key])
+		}
+	}
+	return value
+}
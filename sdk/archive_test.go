@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"bytes"
+	"chainlog/core"
+	"chainlog/crypto"
+	"strings"
+	"testing"
+)
+
+// archivedChain returns an archive of a chain whose two mined blocks carry
+// a signed stream entry each, the first also a version 0 transaction from
+// before transactions were signed.
+func archivedChain(t *testing.T) *Archive {
+	t.Helper()
+	wallet, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+
+	bc := core.NewBlockchain()
+	for sequence := uint64(1); sequence <= 2; sequence++ {
+		tx, err := core.NewStreamTransaction("app/logs", sequence, "entry", wallet, 1)
+		if err != nil {
+			t.Fatalf("NewStreamTransaction: %v", err)
+		}
+		txs := []*core.Transaction{tx}
+		if sequence == 1 {
+			legacy := &core.Transaction{Type: core.DataTx, Data: "legacy", Sender: "legacy-sender", Fee: 1, Timestamp: tx.Timestamp}
+			legacy.ID = legacy.CalculateID()
+			txs = append(txs, legacy)
+		}
+		mineBlock(t, bc, txs)
+	}
+
+	archive, err := NewArchive(bc.Chain, 0, 2, ArchiveJSONL)
+	if err != nil {
+		t.Fatalf("NewArchive: %v", err)
+	}
+	return archive
+}
+
+func TestArchiveVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(a *Archive)
+		want   string
+	}{
+		{
+			name:   "intact",
+			tamper: func(a *Archive) {},
+		},
+		{
+			name: "unsigned transaction",
+			tamper: func(a *Archive) {
+				tx := a.Blocks[2].Transactions[0]
+				tx.PublicKey, tx.Signature = "", ""
+			},
+			want: "no public key",
+		},
+		{
+			name: "no proof of work",
+			tamper: func(a *Archive) {
+				// A block claiming difficulty 0 still needs the minimum work.
+				block := a.Blocks[2]
+				block.Difficulty = 0
+				for block.Hash = block.CalculateHash(); core.MeetsDifficulty(block.Hash, core.MinDifficulty); block.Hash = block.CalculateHash() {
+					block.Nonce++
+				}
+				a.Manifest.TipHash = block.Hash
+			},
+			want: "minimum proof of work",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := archivedChain(t)
+			tt.tamper(archive)
+
+			var buf bytes.Buffer
+			if err := archive.Write(&buf); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			read, err := ReadArchive(&buf)
+			if err != nil {
+				t.Fatalf("ReadArchive: %v", err)
+			}
+
+			err = read.Verify()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Verify: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Verify = %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package sdk
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"chainlog/storage"
+	"fmt"
+	"time"
+)
+
+type Batch struct {
+	TxID      string   `json:"tx_id,omitempty"`
+	Root      string   `json:"root"`
+	Source    string   `json:"source,omitempty"`
+	SchemaID  string   `json:"schema_id,omitempty"`
+	Stream    string   `json:"stream,omitempty"`
+	Sequence  uint64   `json:"sequence,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	Entries   []string `json:"entries"`
+}
+
+func NewBatch(source string) *Batch {
+	return &Batch{
+		Source:    source,
+		CreatedAt: time.Now().Unix(),
+	}
+}
+
+func (b *Batch) Add(entry string) {
+	b.Entries = append(b.Entries, entry)
+	b.Root = ""
+}
+
+func (b *Batch) Len() int {
+	return len(b.Entries)
+}
+
+func (b *Batch) Leaves() []string {
+	leaves := make([]string, 0, len(b.Entries))
+	for _, entry := range b.Entries {
+		leaves = append(leaves, core.HashMerkleLeaf([]byte(entry)))
+	}
+	return leaves
+}
+
+func (b *Batch) ComputeRoot() string {
+	if b.Root == "" {
+		b.Root = core.MerkleRoot(b.Leaves())
+	}
+	return b.Root
+}
+
+func (b *Batch) Seal(wallet *crypto.Wallet, fee uint64) (*core.Transaction, error) {
+	if len(b.Entries) == 0 {
+		return nil, fmt.Errorf("batch has no entries")
+	}
+
+	tx, err := core.NewBatchTransaction(b.ComputeRoot(), &core.BatchOptions{
+		SchemaID: b.SchemaID,
+		Stream:   b.Stream,
+		Sequence: b.Sequence,
+	}, wallet, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	b.TxID = tx.ID
+	return tx, nil
+}
+
+func (b *Batch) Save() error {
+	if b.TxID == "" {
+		return fmt.Errorf("batch has not been sealed into a transaction")
+	}
+	return storage.SaveBatch(b.TxID, b)
+}
+
+func LoadBatch(txID string) (*Batch, error) {
+	var batch Batch
+	if err := storage.LoadBatch(txID, &batch); err != nil {
+		return nil, err
+	}
+
+	if batch.TxID != txID {
+		return nil, fmt.Errorf("batch file belongs to %s, not %s", batch.TxID, txID)
+	}
+
+	root := batch.Root
+	batch.Root = ""
+	if batch.ComputeRoot() != root {
+		return nil, fmt.Errorf("stored leaves for batch %.16s... do not match its root", txID)
+	}
+
+	return &batch, nil
+}
+
+func (b *Batch) EntryProof(index int) (*core.MerkleProof, error) {
+	if index < 0 || index >= len(b.Entries) {
+		return nil, fmt.Errorf("entry %d out of range (batch has %d entries)", index+1, len(b.Entries))
+	}
+	return core.BuildMerkleProof(b.Leaves(), index)
+}
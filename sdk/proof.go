@@ -0,0 +1,175 @@
+package sdk
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// InclusionProof chains one batch entry to a block the verifier trusts:
+// the entry proof leads to the batch root, the transaction proof to the
+// block's Merkle root, and the block proof from that block's header to a
+// later block.
+type InclusionProof struct {
+	Entry       string            `json:"entry"`
+	EntryIndex  int               `json:"entry_index"`
+	EntryProof  *core.MerkleProof `json:"entry_proof"`
+	Transaction *core.Transaction `json:"transaction"`
+	TxProof     *core.MerkleProof `json:"tx_proof"`
+	Header      *core.BlockHeader `json:"block_header"`
+	BlockProof  *BlockProof       `json:"block_proof"`
+}
+
+// BlockProof links a block to a later one through the headers of every
+// block after it, each of which must carry valid proof of work.
+type BlockProof struct {
+	Headers []*core.BlockHeader `json:"headers"`
+}
+
+// NewBlockProof proves that the block at height is an ancestor of the
+// chain's tip.
+func NewBlockProof(bc *core.Blockchain, height int64) (*BlockProof, error) {
+	if height < 0 || height >= int64(len(bc.Chain)) {
+		return nil, fmt.Errorf("block %d is not in the chain", height)
+	}
+
+	proof := &BlockProof{Headers: []*core.BlockHeader{}}
+	for _, block := range bc.Chain[height+1:] {
+		proof.Headers = append(proof.Headers, block.Header())
+	}
+	return proof, nil
+}
+
+// Verify checks that header leads to the block with the trusted hash,
+// which may be the header itself or any block the proof passes through.
+func (bp *BlockProof) Verify(header *core.BlockHeader, trusted string) error {
+	if trusted == "" {
+		return fmt.Errorf("no trusted block hash to verify against")
+	}
+
+	headers := append([]*core.BlockHeader{header}, bp.Headers...)
+	for i, h := range headers {
+		if h.CalculateHash() != h.Hash {
+			return fmt.Errorf("header of block %d does not hash to %.16s...", h.Index, h.Hash)
+		}
+		if !h.HasValidWork() {
+			return fmt.Errorf("block %d does not carry valid proof of work", h.Index)
+		}
+		if i > 0 && (h.Index != headers[i-1].Index+1 || h.PrevHash != headers[i-1].Hash) {
+			return fmt.Errorf("block %d does not follow block %d", h.Index, headers[i-1].Index)
+		}
+		if h.Hash == trusted {
+			return nil
+		}
+	}
+	return fmt.Errorf("block %d does not lead to trusted block %.16s...", header.Index, trusted)
+}
+
+func (b *Batch) Prove(index int, bc *core.Blockchain) (*InclusionProof, error) {
+	entryProof, err := b.EntryProof(index)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, block := bc.FindTransaction(b.TxID)
+	if tx == nil {
+		return nil, fmt.Errorf("batch transaction %.16s... not found", b.TxID)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("batch transaction %.16s... is not confirmed yet", b.TxID)
+	}
+	if block.MerkleRoot == "" {
+		return nil, fmt.Errorf("block %d predates merkle roots and cannot prove inclusion", block.Index)
+	}
+
+	txProof, err := block.TransactionProof(tx.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	blockProof, err := NewBlockProof(bc, block.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InclusionProof{
+		Entry:       b.Entries[index],
+		EntryIndex:  index,
+		EntryProof:  entryProof,
+		Transaction: tx,
+		TxProof:     txProof,
+		Header:      block.Header(),
+		BlockProof:  blockProof,
+	}, nil
+}
+
+// Tip returns the header of the last block the proof reaches, the latest
+// block a verifier can use as its trusted hash.
+func (p *InclusionProof) Tip() *core.BlockHeader {
+	if p.BlockProof == nil || len(p.BlockProof.Headers) == 0 {
+		return p.Header
+	}
+	return p.BlockProof.Headers[len(p.BlockProof.Headers)-1]
+}
+
+// Verify checks every link from the entry to the block with the trusted
+// hash. The trusted hash must come from the verifier's own copy of the
+// chain or another source it trusts, never from the proof.
+func (p *InclusionProof) Verify(trusted string) error {
+	if p.EntryProof == nil || p.TxProof == nil || p.Transaction == nil || p.Header == nil || p.BlockProof == nil {
+		return fmt.Errorf("proof is incomplete")
+	}
+
+	if p.EntryProof.Leaf != core.HashMerkleLeaf([]byte(p.Entry)) {
+		return fmt.Errorf("entry does not match the proven leaf")
+	}
+
+	tx := p.Transaction
+	if tx.Type != core.BatchTx {
+		return fmt.Errorf("transaction %.16s... is not a batch", tx.ID)
+	}
+	if !p.EntryProof.Verify(tx.Data) {
+		return fmt.Errorf("entry is not included in batch root %.16s...", tx.Data)
+	}
+
+	if tx.CalculateID() != tx.ID {
+		return fmt.Errorf("batch transaction contents do not match its id")
+	}
+	if err := tx.VerifySignature(); err != nil {
+		return fmt.Errorf("batch signature is invalid: %v", err)
+	}
+
+	if p.TxProof.Leaf != core.HashMerkleLeaf([]byte(tx.ID)) {
+		return fmt.Errorf("transaction proof is for a different transaction")
+	}
+	if !p.TxProof.Verify(p.Header.MerkleRoot) {
+		return fmt.Errorf("transaction is not included in block %d", p.Header.Index)
+	}
+
+	return p.BlockProof.Verify(p.Header, trusted)
+}
+
+func (p *InclusionProof) SaveToFile(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write proof: %v", err)
+	}
+	return nil
+}
+
+func LoadProofFromFile(path string) (*InclusionProof, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proof: %v", err)
+	}
+
+	var proof InclusionProof
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return nil, fmt.Errorf("invalid proof file: %v", err)
+	}
+	return &proof, nil
+}
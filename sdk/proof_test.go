@@ -0,0 +1,166 @@
+package sdk
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func mineBlock(t *testing.T, bc *core.Blockchain, txs []*core.Transaction) *core.Block {
+	t.Helper()
+	block := core.NewBlock(int64(len(bc.Chain)), "", bc.GetLastBlock().Hash)
+	block.Transactions = txs
+	block.MerkleRoot = block.ComputeMerkleRoot()
+	for block.Hash = block.CalculateHash(); !core.MeetsDifficulty(block.Hash, core.MinDifficulty); block.Hash = block.CalculateHash() {
+		block.Nonce++
+	}
+	bc.AppendBlock(block)
+	return block
+}
+
+// provenChain returns a chain holding a sealed batch in block 1, followed
+// by two more blocks, and a proof for the batch's second entry.
+func provenChain(t *testing.T) (*core.Blockchain, *InclusionProof) {
+	t.Helper()
+	wallet, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+
+	batch := NewBatch("test")
+	for i := 0; i < 5; i++ {
+		batch.Add(fmt.Sprintf("log line %d", i))
+	}
+	tx, err := batch.Seal(wallet, 1)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	bc := core.NewBlockchain()
+	mineBlock(t, bc, []*core.Transaction{tx})
+	mineBlock(t, bc, nil)
+	mineBlock(t, bc, nil)
+
+	proof, err := batch.Prove(1, bc)
+	if err != nil {
+		t.Fatalf("Prove: %v", err)
+	}
+	return bc, proof
+}
+
+func TestInclusionProofVerifies(t *testing.T) {
+	bc, proof := provenChain(t)
+
+	if len(proof.BlockProof.Headers) != 2 || proof.Tip().Hash != bc.GetLastBlock().Hash {
+		t.Fatalf("block proof does not reach the tip")
+	}
+	for height := 1; height < len(bc.Chain); height++ {
+		if err := proof.Verify(bc.Chain[height].Hash); err != nil {
+			t.Errorf("Verify against block %d: %v", height, err)
+		}
+	}
+}
+
+func TestInclusionProofRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted func(bc *core.Blockchain) string
+		tamper  func(p *InclusionProof)
+		want    string
+	}{
+		{
+			name:    "no trusted hash",
+			trusted: func(bc *core.Blockchain) string { return "" },
+			want:    "no trusted block hash",
+		},
+		{
+			name:    "untrusted chain",
+			trusted: func(bc *core.Blockchain) string { return strings.Repeat("0", 64) },
+			want:    "does not lead to trusted block",
+		},
+		{
+			name:    "block before the proven one",
+			trusted: func(bc *core.Blockchain) string { return bc.Chain[0].Hash },
+			want:    "does not lead to trusted block",
+		},
+		{
+			name:   "different entry",
+			tamper: func(p *InclusionProof) { p.Entry = "forged line" },
+			want:   "entry does not match",
+		},
+		{
+			name:   "retyped transaction",
+			tamper: func(p *InclusionProof) { p.Transaction.Type = core.DataTx },
+			want:   "not a batch",
+		},
+		{
+			name:   "altered transaction",
+			tamper: func(p *InclusionProof) { p.Transaction.Sender = strings.Repeat("a", 40) },
+			want:   "do not match its id",
+		},
+		{
+			name:   "altered block header",
+			tamper: func(p *InclusionProof) { p.Header.Timestamp++ },
+			want:   "does not hash to",
+		},
+		{
+			name: "header without proof of work",
+			tamper: func(p *InclusionProof) {
+				h := p.BlockProof.Headers[0]
+				for h.Hash = h.CalculateHash(); core.MeetsDifficulty(h.Hash, core.MinDifficulty); h.Hash = h.CalculateHash() {
+					h.Nonce++
+				}
+			},
+			want: "proof of work",
+		},
+		{
+			name:   "broken header link",
+			tamper: func(p *InclusionProof) { p.BlockProof.Headers = p.BlockProof.Headers[1:] },
+			want:   "does not follow",
+		},
+		{
+			name:   "missing block proof",
+			tamper: func(p *InclusionProof) { p.BlockProof = nil },
+			want:   "incomplete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, proof := provenChain(t)
+			trusted := bc.GetLastBlock().Hash
+			if tt.trusted != nil {
+				trusted = tt.trusted(bc)
+			}
+			if tt.tamper != nil {
+				tt.tamper(proof)
+			}
+
+			err := proof.Verify(trusted)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProveUnconfirmedBatch(t *testing.T) {
+	wallet, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	batch := NewBatch("test")
+	batch.Add("line")
+	tx, err := batch.Seal(wallet, 1)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	bc := core.NewBlockchain()
+	bc.PendingTx = append(bc.PendingTx, tx)
+	if _, err := batch.Prove(0, bc); err == nil {
+		t.Error("Prove succeeded for a batch that is not in a block")
+	}
+}
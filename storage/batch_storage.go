@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const BatchesDir = "batches"
+
+func batchFile(txID string) string {
+	return filepath.Join(BatchesDir, txID+".json")
+}
+
+func SaveBatch(txID string, batch interface{}) error {
+	if err := os.MkdirAll(filepath.Join(DataDir, BatchesDir), 0755); err != nil {
+		return fmt.Errorf("failed to create batch directory: %v", err)
+	}
+	return SaveToFile(batch, batchFile(txID))
+}
+
+func LoadBatch(txID string, batch interface{}) error {
+	if !HasBatch(txID) {
+		return fmt.Errorf("no local leaves for batch %s", txID)
+	}
+	return LoadFromFile(batch, batchFile(txID))
+}
+
+func HasBatch(txID string) bool {
+	return FileExists(batchFile(txID))
+}
+
+func GetAllBatchIDs() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(DataDir, BatchesDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch directory: %v", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		ids = append(ids, name[:len(name)-len(".json")])
+	}
+	return ids, nil
+}
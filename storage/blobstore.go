@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const BlobsDir = "blobs"
+
+func isValidBlobHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+func BlobPath(hash string) string {
+	return filepath.Join(DataDir, BlobsDir, hash[:2], hash)
+}
+
+func HasBlob(hash string) bool {
+	if !isValidBlobHash(hash) {
+		return false
+	}
+	_, err := os.Stat(BlobPath(hash))
+	return err == nil
+}
+
+func StoreBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if HasBlob(hash) {
+		return hash, nil
+	}
+
+	dir := filepath.Dir(BlobPath(hash))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, hash+".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), BlobPath(hash)); err != nil {
+		return "", fmt.Errorf("failed to store blob: %v", err)
+	}
+
+	return hash, nil
+}
+
+func StoreBlobFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	writer, err := NewBlobWriter()
+	if err != nil {
+		return "", 0, err
+	}
+	defer writer.Abort()
+
+	if _, err := io.Copy(writer, file); err != nil {
+		return "", 0, fmt.Errorf("failed to copy file: %v", err)
+	}
+
+	hash, err := writer.Commit("")
+	if err != nil {
+		return "", 0, err
+	}
+	return hash, writer.Size(), nil
+}
+
+// BlobWriter streams a blob into a temporary file in the blob store and
+// moves it into place under its hash once it is complete.
+type BlobWriter struct {
+	tmp    *os.File
+	hasher hash.Hash
+	size   int64
+}
+
+func NewBlobWriter() (*BlobWriter, error) {
+	blobRoot := filepath.Join(DataDir, BlobsDir)
+	if err := os.MkdirAll(blobRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(blobRoot, "incoming.tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob file: %v", err)
+	}
+	return &BlobWriter{tmp: tmp, hasher: sha256.New()}, nil
+}
+
+func (w *BlobWriter) Write(p []byte) (int, error) {
+	n, err := w.tmp.Write(p)
+	w.hasher.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *BlobWriter) Size() int64 {
+	return w.size
+}
+
+// Commit stores the blob under its hash. A non-empty expected hash must
+// match the content, or nothing is stored.
+func (w *BlobWriter) Commit(expected string) (string, error) {
+	if err := w.tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %v", err)
+	}
+
+	hash := hex.EncodeToString(w.hasher.Sum(nil))
+	if expected != "" && hash != expected {
+		return "", fmt.Errorf("blob content does not match hash %.16s...", expected)
+	}
+	if HasBlob(hash) {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(BlobPath(hash)), 0755); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	if err := os.Rename(w.tmp.Name(), BlobPath(hash)); err != nil {
+		return "", fmt.Errorf("failed to store blob: %v", err)
+	}
+	return hash, nil
+}
+
+// Abort drops whatever was written if the blob was not committed.
+func (w *BlobWriter) Abort() {
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+}
+
+// ReadBlobChunk reads up to limit bytes of a stored blob from offset and
+// returns them with the blob's full size. Chunks are not checked against
+// the hash; whoever assembles the blob does that.
+func ReadBlobChunk(hash string, offset, limit int64) ([]byte, int64, error) {
+	if !isValidBlobHash(hash) {
+		return nil, 0, fmt.Errorf("invalid blob hash: %s", hash)
+	}
+
+	file, err := os.Open(BlobPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, fmt.Errorf("blob not found: %s", hash)
+		}
+		return nil, 0, fmt.Errorf("failed to read blob: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read blob: %v", err)
+	}
+	if offset < 0 || offset > info.Size() {
+		return nil, 0, fmt.Errorf("offset %d is outside blob of %d bytes", offset, info.Size())
+	}
+
+	if remaining := info.Size() - offset; limit > remaining {
+		limit = remaining
+	}
+	chunk := make([]byte, limit)
+	if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("failed to read blob: %v", err)
+	}
+	return chunk, info.Size(), nil
+}
+
+func LoadBlob(hash string) ([]byte, error) {
+	if !isValidBlobHash(hash) {
+		return nil, fmt.Errorf("invalid blob hash: %s", hash)
+	}
+
+	data, err := os.ReadFile(BlobPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("blob not found: %s", hash)
+		}
+		return nil, fmt.Errorf("failed to read blob: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("blob %s is corrupt: content hash mismatch", hash[:16])
+	}
+
+	return data, nil
+}
+
+func HashFile(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
@@ -0,0 +1,478 @@
+package storage
+
+import (
+	"bufio"
+	"chainlog/core"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	BlocksDir       = "blocks"
+	BlockOffsetFile = "offsets.idx"
+	MaxSegmentSize  = 64 * 1024 * 1024
+
+	blockOffsetSize = 12
+	maxBlockRecord  = 256 * 1024 * 1024
+)
+
+type blockOffset struct {
+	Segment uint32
+	Offset  uint64
+}
+
+// BlockStore keeps blocks in append-only segment files, one checksummed
+// record of block JSON per height. offsets.idx
+// holds a fixed-size (segment, offset) entry per height for random access.
+type BlockStore struct {
+	dir         string
+	offsets     []blockOffset
+	index       *os.File
+	tail        *os.File
+	tailID      uint32
+	tailSize    int64
+	tipHash     string
+	recoveredAt int64
+}
+
+func segmentName(id uint32) string {
+	return fmt.Sprintf("seg-%06d.log", id)
+}
+
+func BlockStoreExists() bool {
+	return FileExists(filepath.Join(BlocksDir, BlockOffsetFile))
+}
+
+func OpenBlockStore() (*BlockStore, error) {
+	dir := filepath.Join(DataDir, BlocksDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create block store: %v", err)
+	}
+
+	if err := finishPrune(dir); err != nil {
+		return nil, err
+	}
+
+	index, err := os.OpenFile(filepath.Join(dir, BlockOffsetFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block offsets: %v", err)
+	}
+
+	store := &BlockStore{dir: dir, index: index, recoveredAt: -1}
+	if err := store.loadOffsets(); err != nil {
+		index.Close()
+		return nil, err
+	}
+	if err := store.recover(); err != nil {
+		index.Close()
+		return nil, err
+	}
+	if err := store.openTail(); err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	if height := store.Height(); height > 0 {
+		tip, err := store.ReadBlock(height - 1)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store.tipHash = tip.Hash
+	}
+	return store, nil
+}
+
+func (s *BlockStore) loadOffsets() error {
+	data, err := io.ReadAll(s.index)
+	if err != nil {
+		return fmt.Errorf("failed to read block offsets: %v", err)
+	}
+
+	count := len(data) / blockOffsetSize
+	s.offsets = make([]blockOffset, 0, count)
+	for i := 0; i < count; i++ {
+		entry := data[i*blockOffsetSize:]
+		s.offsets = append(s.offsets, blockOffset{
+			Segment: binary.BigEndian.Uint32(entry[0:4]),
+			Offset:  binary.BigEndian.Uint64(entry[4:12]),
+		})
+	}
+
+	if len(data)%blockOffsetSize != 0 {
+		return s.index.Truncate(int64(count * blockOffsetSize))
+	}
+	return nil
+}
+
+// recover scans everything written after the last indexed record. Complete
+// records that never made it into offsets.idx are indexed; a torn record at
+// the end of the log is cut off.
+func (s *BlockStore) recover() error {
+	segments, err := s.segmentIDs()
+	if err != nil {
+		return err
+	}
+
+	indexed := len(s.offsets)
+	var start blockOffset
+	if n := len(s.offsets); n > 0 {
+		last := s.offsets[n-1]
+		payload, err := s.readRecordAt(last)
+		if err != nil {
+			// The offsets point past the data or at a torn record; fall
+			// back to a full rescan.
+			s.offsets = s.offsets[:0]
+		} else {
+			start = blockOffset{Segment: last.Segment, Offset: last.Offset + recordHeaderSize + uint64(len(payload))}
+		}
+	}
+
+	for _, id := range segments {
+		if id < start.Segment {
+			continue
+		}
+
+		offset := uint64(0)
+		if id == start.Segment {
+			offset = start.Offset
+		}
+
+		path := filepath.Join(s.dir, segmentName(id))
+		valid, found, err := scanSegment(path, offset, func(at uint64) {
+			s.offsets = append(s.offsets, blockOffset{Segment: id, Offset: at})
+		})
+		if err != nil {
+			return err
+		}
+		if found > 0 && s.recoveredAt < 0 {
+			s.recoveredAt = int64(len(s.offsets) - found)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat segment: %v", err)
+		}
+		if int64(valid) < info.Size() {
+			fmt.Printf("Block store: discarding %d torn bytes at the end of %s\n", info.Size()-int64(valid), segmentName(id))
+			if err := os.Truncate(path, int64(valid)); err != nil {
+				return fmt.Errorf("failed to truncate torn segment: %v", err)
+			}
+			for _, later := range segments {
+				if later > id {
+					os.Remove(filepath.Join(s.dir, segmentName(later)))
+				}
+			}
+			if s.recoveredAt < 0 {
+				s.recoveredAt = int64(len(s.offsets))
+			}
+			break
+		}
+	}
+
+	if len(s.offsets) == indexed && s.recoveredAt < 0 {
+		return nil
+	}
+	return s.writeOffsets()
+}
+
+func scanSegment(path string, offset uint64, found func(at uint64)) (uint64, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open segment: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("failed to seek segment: %v", err)
+	}
+
+	reader := bufio.NewReader(file)
+	count := 0
+	for {
+		payload, err := readRecord(reader, maxBlockRecord)
+		if err != nil {
+			return offset, count, nil
+		}
+		found(offset)
+		offset += recordHeaderSize + uint64(len(payload))
+		count++
+	}
+}
+
+func (s *BlockStore) recordLength(at blockOffset) (uint32, error) {
+	file, err := os.Open(filepath.Join(s.dir, segmentName(at.Segment)))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var header [recordHeaderSize]byte
+	if _, err := file.ReadAt(header[:], int64(at.Offset)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(header[0:4]), nil
+}
+
+func (s *BlockStore) readRecordAt(at blockOffset) ([]byte, error) {
+	file, err := os.Open(filepath.Join(s.dir, segmentName(at.Segment)))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readRecord(io.NewSectionReader(file, int64(at.Offset), maxBlockRecord), maxBlockRecord)
+}
+
+func (s *BlockStore) segmentIDs() ([]uint32, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block store: %v", err)
+	}
+
+	var ids []uint32
+	for _, entry := range entries {
+		var id uint32
+		if !strings.HasPrefix(entry.Name(), "seg-") {
+			continue
+		}
+		if _, err := fmt.Sscanf(entry.Name(), "seg-%06d.log", &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (s *BlockStore) openTail() error {
+	if s.tail != nil {
+		s.tail.Close()
+	}
+
+	s.tailID = 0
+	if n := len(s.offsets); n > 0 {
+		s.tailID = s.offsets[n-1].Segment
+	}
+
+	tail, err := os.OpenFile(filepath.Join(s.dir, segmentName(s.tailID)), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %v", err)
+	}
+	info, err := tail.Stat()
+	if err != nil {
+		tail.Close()
+		return fmt.Errorf("failed to stat segment: %v", err)
+	}
+
+	s.tail = tail
+	s.tailSize = info.Size()
+	return nil
+}
+
+func (s *BlockStore) writeOffsets() error {
+	data := make([]byte, 0, len(s.offsets)*blockOffsetSize)
+	for _, entry := range s.offsets {
+		data = binary.BigEndian.AppendUint32(data, entry.Segment)
+		data = binary.BigEndian.AppendUint64(data, entry.Offset)
+	}
+
+	if err := s.index.Truncate(0); err != nil {
+		return fmt.Errorf("failed to rewrite block offsets: %v", err)
+	}
+	if _, err := s.index.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to rewrite block offsets: %v", err)
+	}
+	return s.index.Sync()
+}
+
+func (s *BlockStore) Height() int64 {
+	return int64(len(s.offsets))
+}
+
+func (s *BlockStore) TipHash() string {
+	return s.tipHash
+}
+
+// RecoveredAt reports the height from which records were re-indexed or cut
+// off while opening the store, or -1 if the log was already consistent.
+func (s *BlockStore) RecoveredAt() int64 {
+	return s.recoveredAt
+}
+
+// Append writes blocks to the tail segment and fsyncs the segment before
+// recording their offsets, so an indexed block is always durable.
+func (s *BlockStore) Append(blocks ...*core.Block) error {
+	start := len(s.offsets)
+	var added []blockOffset
+	for _, block := range blocks {
+		payload, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block %d: %v", block.Index, err)
+		}
+
+		if s.tailSize > 0 && s.tailSize+recordHeaderSize+int64(len(payload)) > MaxSegmentSize {
+			if err := s.tail.Sync(); err != nil {
+				return fmt.Errorf("failed to sync segment: %v", err)
+			}
+			s.offsets = append(s.offsets, added...)
+			added = nil
+			if err := s.rollSegment(); err != nil {
+				return err
+			}
+		}
+
+		record := encodeRecord(payload)
+
+		if _, err := s.tail.Write(record); err != nil {
+			return fmt.Errorf("failed to append block %d: %v", block.Index, err)
+		}
+		added = append(added, blockOffset{Segment: s.tailID, Offset: uint64(s.tailSize)})
+		s.tailSize += int64(len(record))
+		s.tipHash = block.Hash
+	}
+
+	if err := s.tail.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment: %v", err)
+	}
+
+	s.offsets = append(s.offsets, added...)
+
+	data := make([]byte, 0, (len(s.offsets)-start)*blockOffsetSize)
+	for _, entry := range s.offsets[start:] {
+		data = binary.BigEndian.AppendUint32(data, entry.Segment)
+		data = binary.BigEndian.AppendUint64(data, entry.Offset)
+	}
+	if _, err := s.index.WriteAt(data, int64(start*blockOffsetSize)); err != nil {
+		return fmt.Errorf("failed to write block offsets: %v", err)
+	}
+	return s.index.Sync()
+}
+
+func (s *BlockStore) rollSegment() error {
+	s.tail.Close()
+	s.tailID++
+
+	tail, err := os.OpenFile(filepath.Join(s.dir, segmentName(s.tailID)), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %v", err)
+	}
+	s.tail = tail
+	s.tailSize = 0
+	return nil
+}
+
+func (s *BlockStore) ReadBlock(height int64) (*core.Block, error) {
+	if height < 0 || height >= s.Height() {
+		return nil, fmt.Errorf("block %d is not in the store (height %d)", height, s.Height())
+	}
+
+	payload, err := s.readRecordAt(s.offsets[height])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block %d: %v", height, err)
+	}
+
+	var block core.Block
+	if err := json.Unmarshal(payload, &block); err != nil {
+		return nil, fmt.Errorf("failed to decode block %d: %v", height, err)
+	}
+	return &block, nil
+}
+
+// Iterate streams every stored block in height order, one record at a time.
+func (s *BlockStore) Iterate(fn func(block *core.Block) error) error {
+	var height int64
+	for height < s.Height() {
+		segment := s.offsets[height].Segment
+		file, err := os.Open(filepath.Join(s.dir, segmentName(segment)))
+		if err != nil {
+			return fmt.Errorf("failed to open segment: %v", err)
+		}
+
+		if _, err := file.Seek(int64(s.offsets[height].Offset), io.SeekStart); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to seek segment: %v", err)
+		}
+
+		reader := bufio.NewReaderSize(file, 256*1024)
+		for height < s.Height() && s.offsets[height].Segment == segment {
+			payload, err := readRecord(reader, maxBlockRecord)
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("failed to read block %d: %v", height, err)
+			}
+
+			var block core.Block
+			if err := json.Unmarshal(payload, &block); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to decode block %d: %v", height, err)
+			}
+			if err := fn(&block); err != nil {
+				file.Close()
+				return err
+			}
+			height++
+		}
+		file.Close()
+	}
+	return nil
+}
+
+// Truncate removes every block at or above height, for example when the
+// chain is reorganized onto a different branch.
+func (s *BlockStore) Truncate(height int64) error {
+	if height >= s.Height() {
+		return nil
+	}
+	if height < 0 {
+		height = 0
+	}
+
+	cut := s.offsets[height]
+	segments, err := s.segmentIDs()
+	if err != nil {
+		return err
+	}
+
+	s.tail.Close()
+	s.tail = nil
+	for _, id := range segments {
+		if id > cut.Segment {
+			if err := os.Remove(filepath.Join(s.dir, segmentName(id))); err != nil {
+				return fmt.Errorf("failed to remove segment: %v", err)
+			}
+		}
+	}
+	if err := os.Truncate(filepath.Join(s.dir, segmentName(cut.Segment)), int64(cut.Offset)); err != nil {
+		return fmt.Errorf("failed to truncate segment: %v", err)
+	}
+
+	s.offsets = s.offsets[:height]
+	if err := s.writeOffsets(); err != nil {
+		return err
+	}
+
+	s.tipHash = ""
+	if height > 0 {
+		tip, err := s.ReadBlock(height - 1)
+		if err != nil {
+			return err
+		}
+		s.tipHash = tip.Hash
+	}
+
+	return s.openTail()
+}
+
+func (s *BlockStore) Close() error {
+	if s.tail != nil {
+		s.tail.Close()
+		s.tail = nil
+	}
+	return s.index.Close()
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"chainlog/core"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testBlocks = 5
+
+// testStore writes a fresh store of testBlocks blocks into a temporary data
+// directory and returns the blocks and their record offsets.
+func testStore(t *testing.T) ([]*core.Block, []blockOffset) {
+	t.Helper()
+	DataDir = t.TempDir()
+
+	store, err := OpenBlockStore()
+	if err != nil {
+		t.Fatalf("OpenBlockStore: %v", err)
+	}
+	defer store.Close()
+
+	var blocks []*core.Block
+	prev := ""
+	for i := 0; i < testBlocks; i++ {
+		block := core.NewBlock(int64(i), "", prev)
+		block.Hash = block.CalculateHash()
+		prev = block.Hash
+		blocks = append(blocks, block)
+	}
+	if err := store.Append(blocks...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	return blocks, append([]blockOffset(nil), store.offsets...)
+}
+
+func blockStorePath(name string) string {
+	return filepath.Join(DataDir, BlocksDir, name)
+}
+
+func TestBlockStoreRecovery(t *testing.T) {
+	segment := segmentName(0)
+
+	tests := []struct {
+		name        string
+		damage      func(t *testing.T, offsets []blockOffset)
+		height      int64
+		recoveredAt int64
+	}{
+		{
+			name:        "clean",
+			damage:      func(t *testing.T, offsets []blockOffset) {},
+			height:      testBlocks,
+			recoveredAt: -1,
+		},
+		{
+			name: "records missing from the index",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(BlockOffsetFile), 3*blockOffsetSize)
+			},
+			height:      testBlocks,
+			recoveredAt: 3,
+		},
+		{
+			name: "partial index entry",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(BlockOffsetFile), 3*blockOffsetSize+5)
+			},
+			height:      testBlocks,
+			recoveredAt: 3,
+		},
+		{
+			name: "index lost",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				if err := os.Remove(blockStorePath(BlockOffsetFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			height:      testBlocks,
+			recoveredAt: 0,
+		},
+		{
+			name: "garbage after the last record",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				file, err := os.OpenFile(blockStorePath(segment), os.O_WRONLY|os.O_APPEND, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				file.Write([]byte{0, 0, 0, 9, 1, 2, 3})
+				file.Close()
+			},
+			height:      testBlocks,
+			recoveredAt: testBlocks,
+		},
+		{
+			name: "torn unindexed record",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(BlockOffsetFile), 3*blockOffsetSize)
+				truncateFile(t, blockStorePath(segment), int64(offsets[4].Offset)+recordHeaderSize+10)
+			},
+			height:      4,
+			recoveredAt: 3,
+		},
+		{
+			name: "corrupt unindexed record",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(BlockOffsetFile), 3*blockOffsetSize)
+				flipByte(t, blockStorePath(segment), int64(offsets[3].Offset)+recordHeaderSize+1)
+			},
+			height:      3,
+			recoveredAt: 3,
+		},
+		{
+			name: "torn indexed record",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(segment), int64(offsets[4].Offset)+recordHeaderSize+10)
+			},
+			height:      4,
+			recoveredAt: 0,
+		},
+		{
+			name: "index past the end of the log",
+			damage: func(t *testing.T, offsets []blockOffset) {
+				truncateFile(t, blockStorePath(segment), int64(offsets[3].Offset))
+			},
+			height:      3,
+			recoveredAt: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, offsets := testStore(t)
+			tt.damage(t, offsets)
+
+			store, err := OpenBlockStore()
+			if err != nil {
+				t.Fatalf("OpenBlockStore: %v", err)
+			}
+			if store.Height() != tt.height || store.RecoveredAt() != tt.recoveredAt {
+				t.Errorf("height %d, recovered at %d; want %d, %d", store.Height(), store.RecoveredAt(), tt.height, tt.recoveredAt)
+			}
+			if store.TipHash() != blocks[store.Height()-1].Hash {
+				t.Errorf("tip hash %.16s, want block %d", store.TipHash(), store.Height()-1)
+			}
+			for height := int64(0); height < store.Height(); height++ {
+				block, err := store.ReadBlock(height)
+				if err != nil || block.Hash != blocks[height].Hash {
+					t.Errorf("ReadBlock(%d) = %v, %v", height, block, err)
+				}
+			}
+
+			// The repaired store must take new blocks and reopen cleanly.
+			next := core.NewBlock(store.Height(), "", store.TipHash())
+			next.Hash = next.CalculateHash()
+			if err := store.Append(next); err != nil {
+				t.Fatalf("Append after recovery: %v", err)
+			}
+			store.Close()
+
+			store, err = OpenBlockStore()
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer store.Close()
+			if store.Height() != tt.height+1 || store.RecoveredAt() != -1 || store.TipHash() != next.Hash {
+				t.Errorf("after reopen: height %d, recovered at %d", store.Height(), store.RecoveredAt())
+			}
+		})
+	}
+}
+
+func TestBlockStoreTruncate(t *testing.T) {
+	blocks, _ := testStore(t)
+
+	store, err := OpenBlockStore()
+	if err != nil {
+		t.Fatalf("OpenBlockStore: %v", err)
+	}
+	if err := store.Truncate(2); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if store.Height() != 2 || store.TipHash() != blocks[1].Hash {
+		t.Errorf("after Truncate(2): height %d", store.Height())
+	}
+	store.Close()
+
+	store, err = OpenBlockStore()
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if store.Height() != 2 || store.RecoveredAt() != -1 {
+		t.Errorf("after reopen: height %d, recovered at %d", store.Height(), store.RecoveredAt())
+	}
+
+	var seen int
+	err = store.Iterate(func(block *core.Block) error {
+		if block.Hash != blocks[seen].Hash {
+			t.Errorf("Iterate: block %d has the wrong hash", seen)
+		}
+		seen++
+		return nil
+	})
+	if err != nil || seen != 2 {
+		t.Errorf("Iterate saw %d blocks, err %v", seen, err)
+	}
+}
+
+func truncateFile(t *testing.T, path string, size int64) {
+	t.Helper()
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func flipByte(t *testing.T, path string, at int64) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	b := make([]byte, 1)
+	if _, err := file.ReadAt(b, at); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xff
+	if _, err := file.WriteAt(b, at); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ChainIndexFile = "chain_index.json"
+
+	indexMetaKey       = "index/meta"
+	indexTxPrefix      = "index/tx/"
+	indexBlockPrefix   = "index/block/"
+	indexAddrPrefix    = "index/addr/"
+	indexStreamPrefix  = "index/stream/"
+	indexSubjectPrefix = "index/subject/"
+	indexTypePrefix    = "index/type/"
+)
+
+// indexVersion is raised when the saved index gains lists that older
+// saves lack; such an index is rebuilt from the chain. Version 1 added the
+// subject key lists.
+const indexVersion = 1
+
+type indexMeta struct {
+	Height  int64  `json:"height"`
+	TipHash string `json:"tip_hash"`
+	Version int    `json:"version"`
+}
+
+func (lm *LedgerManager) LoadIndex() error {
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+
+	if FileExists(ChainIndexFile) {
+		// Indexes used to be a JSON file; they now live in the state store
+		// and are rebuilt from the chain on the next save.
+		os.Remove(filepath.Join(DataDir, ChainIndexFile))
+	}
+
+	raw, exists, err := kv.Get(indexMetaKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no chain index in %s yet", StateDBFile)
+	}
+
+	var meta indexMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return fmt.Errorf("invalid chain index metadata: %v", err)
+	}
+	if meta.Version < indexVersion {
+		return fmt.Errorf("chain index in %s was saved by an older version", StateDBFile)
+	}
+
+	index := core.NewChainIndex()
+	index.Height = meta.Height
+	index.TipHash = meta.TipHash
+
+	kv.Scan(indexTxPrefix, func(key string, value []byte) bool {
+		var location core.TxLocation
+		if json.Unmarshal(value, &location) == nil {
+			index.Transactions[strings.TrimPrefix(key, indexTxPrefix)] = location
+		}
+		return true
+	})
+	kv.Scan(indexBlockPrefix, func(key string, value []byte) bool {
+		if height, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			index.Blocks[strings.TrimPrefix(key, indexBlockPrefix)] = height
+		}
+		return true
+	})
+	loadLists := func(prefix string, add func(name string, ids []string)) {
+		kv.Scan(prefix, func(key string, value []byte) bool {
+			var ids []string
+			if json.Unmarshal(value, &ids) == nil {
+				add(strings.TrimPrefix(key, prefix), ids)
+			}
+			return true
+		})
+	}
+	loadLists(indexAddrPrefix, func(name string, ids []string) { index.Addresses[name] = ids })
+	loadLists(indexStreamPrefix, func(name string, ids []string) { index.Streams[name] = ids })
+	loadLists(indexSubjectPrefix, func(name string, ids []string) { index.Subjects[name] = ids })
+	loadLists(indexTypePrefix, func(name string, ids []string) {
+		if txType, err := strconv.Atoi(name); err == nil {
+			index.Types[core.TransactionType(txType)] = ids
+		}
+	})
+
+	lm.Blockchain.SetIndex(index)
+	lm.savedIndexTip = meta.TipHash
+	lm.savedIndexHeight = meta.Height
+	return nil
+}
+
+func (lm *LedgerManager) SaveIndex() error {
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+
+	batch := NewKVBatch()
+	saved, err := lm.stageIndex(kv, batch)
+	if err != nil || batch.Len() == 0 {
+		return err
+	}
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	saved()
+	return nil
+}
+
+// stageIndex adds the index entries for blocks indexed since the last save
+// to batch. If the saved tip was unwound by a reorg, every index key is
+// rewritten instead. The returned func records the save once the batch has
+// been committed.
+func (lm *LedgerManager) stageIndex(kv KVStore, batch *KVBatch) (func(), error) {
+	index := lm.Blockchain.Index()
+	if index.TipHash == lm.savedIndexTip && index.Height == lm.savedIndexHeight {
+		return func() {}, nil
+	}
+
+	from := lm.savedIndexHeight
+	if height, exists := index.Blocks[lm.savedIndexTip]; lm.savedIndexTip == "" || !exists || height != from-1 {
+		from = 0
+		err := kv.Scan("index/", func(key string, value []byte) bool {
+			batch.Delete(key)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addresses := make(map[string]bool)
+	streams := make(map[string]bool)
+	subjects := make(map[string]bool)
+	types := make(map[core.TransactionType]bool)
+
+	for height := from; height < index.Height; height++ {
+		block := lm.Blockchain.Chain[height]
+		batch.Put(indexBlockPrefix+block.Hash, []byte(strconv.FormatInt(height, 10)))
+
+		for _, tx := range block.Transactions {
+			location, _ := json.Marshal(index.Transactions[tx.ID])
+			batch.Put(indexTxPrefix+tx.ID, location)
+
+			addresses[tx.Sender] = true
+			if tx.Receiver != "" {
+				addresses[tx.Receiver] = true
+			}
+			if tx.Stream != "" {
+				streams[tx.Stream] = true
+			}
+			if keyID := tx.SubjectKey(); keyID != "" {
+				subjects[keyID] = true
+			}
+			types[tx.Type] = true
+		}
+		for _, dropped := range block.Dropped {
+			location, _ := json.Marshal(index.Transactions[dropped.ID])
+			batch.Put(indexTxPrefix+dropped.ID, location)
+		}
+	}
+
+	for address := range addresses {
+		if ids, exists := index.Addresses[address]; exists {
+			encoded, _ := json.Marshal(ids)
+			batch.Put(indexAddrPrefix+address, encoded)
+		}
+	}
+	for stream := range streams {
+		encoded, _ := json.Marshal(index.Streams[stream])
+		batch.Put(indexStreamPrefix+stream, encoded)
+	}
+	for keyID := range subjects {
+		encoded, _ := json.Marshal(index.Subjects[keyID])
+		batch.Put(indexSubjectPrefix+keyID, encoded)
+	}
+	for txType := range types {
+		encoded, _ := json.Marshal(index.Types[txType])
+		batch.Put(indexTypePrefix+strconv.Itoa(int(txType)), encoded)
+	}
+
+	meta, _ := json.Marshal(&indexMeta{Height: index.Height, TipHash: index.TipHash, Version: indexVersion})
+	batch.Put(indexMetaKey, meta)
+
+	tip, height := index.TipHash, index.Height
+	return func() {
+		lm.savedIndexTip = tip
+		lm.savedIndexHeight = height
+	}, nil
+}
+
+func (lm *LedgerManager) Reindex() (*core.ChainIndex, error) {
+	for _, block := range lm.Blockchain.Chain {
+		if block.Pruned {
+			return nil, fmt.Errorf("block %d has been pruned, its transactions cannot be indexed again", block.Index)
+		}
+	}
+
+	lm.Blockchain.SetIndex(nil)
+	lm.savedIndexTip = ""
+	lm.savedIndexHeight = 0
+
+	if err := lm.SaveIndex(); err != nil {
+		return nil, err
+	}
+	return lm.Blockchain.Index(), nil
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"bufio"
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	JournalFile = "journal.log"
+
+	maxJournalRecord = 64 * 1024
+)
+
+// journalEntry is written ahead of every save that touches the block store.
+// It records the height the store is cut back to before new blocks are
+// appended, and the tip the state store batch will commit. Until the entry
+// is cleared, the block store and the state store may disagree.
+//
+// The blocks a reorg cuts off are written to the journal ahead of the
+// entry, so that recovery can put them back and the block store matches the
+// accounts and index the state store last committed.
+type journalEntry struct {
+	Fork      int64  `json:"fork"`
+	Height    int64  `json:"height"`
+	TipHash   string `json:"tip_hash"`
+	Abandoned int64  `json:"abandoned,omitempty"`
+	StartedAt int64  `json:"started_at"`
+}
+
+func journalPath() string {
+	return filepath.Join(DataDir, JournalFile)
+}
+
+func beginJournal(entry *journalEntry, store *BlockStore) error {
+	var records []byte
+	entry.Abandoned = 0
+	for height := entry.Fork; height < store.Height(); height++ {
+		block, err := store.ReadBlock(height)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to encode block %d for the journal: %v", height, err)
+		}
+		records = append(records, encodeRecord(payload)...)
+		entry.Abandoned++
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %v", err)
+	}
+	records = append(records, encodeRecord(payload)...)
+
+	file, err := os.OpenFile(journalPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(records); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %v", err)
+	}
+	syncDir(DataDir)
+	return nil
+}
+
+func clearJournal() error {
+	file, err := os.OpenFile(journalPath(), os.O_WRONLY, 0644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to clear journal: %v", err)
+	}
+	return file.Sync()
+}
+
+// readJournal returns the save that was in progress when the last process
+// stopped and the blocks it cut off, or nil if there was none. A torn
+// journal means the process died before it touched the block store, so it
+// is treated as no entry at all.
+func readJournal() (*journalEntry, []*core.Block, error) {
+	file, err := os.Open(journalPath())
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer file.Close()
+
+	var records [][]byte
+	reader := bufio.NewReader(file)
+	for {
+		payload, err := readRecord(reader, maxBlockRecord)
+		if err != nil {
+			break
+		}
+		records = append(records, payload)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+
+	var entry journalEntry
+	last := records[len(records)-1]
+	if len(last) > maxJournalRecord || json.Unmarshal(last, &entry) != nil || entry.Abandoned != int64(len(records)-1) {
+		return nil, nil, nil
+	}
+
+	abandoned := make([]*core.Block, 0, entry.Abandoned)
+	for _, payload := range records[:entry.Abandoned] {
+		var block core.Block
+		if err := json.Unmarshal(payload, &block); err != nil {
+			return nil, nil, fmt.Errorf("invalid block in journal: %v", err)
+		}
+		abandoned = append(abandoned, &block)
+	}
+	return &entry, abandoned, nil
+}
+
+// recover brings the block store and the state store back in line after a
+// crash. If a journaled save never committed its state batch, the blocks it
+// appended are cut off and the blocks it replaced are put back, so the
+// store ends at the tip whose accounts and index the state store holds.
+// Without a journal entry, blocks past the committed tip were appended by a
+// save that did commit, so they are kept and the tip is moved up to them.
+// The caller holds the ledger lock.
+func (lm *LedgerManager) recover(store *BlockStore) error {
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+
+	var meta chainMeta
+	raw, hasMeta, err := kv.Get(chainMetaKey)
+	if err != nil {
+		return err
+	}
+	if hasMeta {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return fmt.Errorf("invalid chain metadata: %v", err)
+		}
+	}
+
+	entry, abandoned, err := readJournal()
+	if err != nil {
+		return err
+	}
+
+	if entry != nil {
+		if hasMeta && meta.Height == entry.Height && meta.TipHash == entry.TipHash {
+			return clearJournal()
+		}
+
+		height := entry.Fork
+		if height > store.Height() {
+			height = store.Height()
+		}
+		fmt.Printf("Recovery: save to height %d was interrupted, rolling back to height %d\n", entry.Height, height)
+		if err := store.Truncate(height); err != nil {
+			return err
+		}
+
+		if n := int64(len(abandoned)); n > 0 && height == entry.Fork &&
+			hasMeta && meta.Height == height+n && abandoned[n-1].Hash == meta.TipHash {
+			fmt.Printf("Recovery: restoring %d block(s) the interrupted save replaced, back to the committed tip at height %d\n", n, meta.Height)
+			if err := store.Append(abandoned...); err != nil {
+				return err
+			}
+			return clearJournal()
+		}
+
+		// The committed tip can't be restored, so the chain metadata and
+		// index are pointed at what is left. The index is rebuilt from the
+		// surviving blocks on load.
+		batch := NewKVBatch()
+		if hasMeta && meta.Height > store.Height() {
+			fmt.Println("Recovery: the committed tip is gone; the chain index will be rebuilt and account balances may be ahead of the chain")
+			err := kv.Scan("index/", func(key string, value []byte) bool {
+				batch.Delete(key)
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+		meta.Height = store.Height()
+		meta.TipHash = store.TipHash()
+		encoded, _ := json.Marshal(&meta)
+		batch.Put(chainMetaKey, encoded)
+		if err := kv.Write(batch); err != nil {
+			return err
+		}
+		return clearJournal()
+	}
+
+	if !hasMeta || meta.Height <= 0 || store.Height() <= meta.Height {
+		return nil
+	}
+	block, err := store.ReadBlock(meta.Height - 1)
+	if err != nil {
+		return err
+	}
+	if block.Hash != meta.TipHash {
+		return nil
+	}
+	fmt.Printf("Recovery: block store is ahead of the committed tip, keeping blocks %d-%d\n", meta.Height, store.Height()-1)
+	meta.Height = store.Height()
+	meta.TipHash = store.TipHash()
+	encoded, _ := json.Marshal(&meta)
+	batch := NewKVBatch()
+	batch.Put(chainMetaKey, encoded)
+	return kv.Write(batch)
+}
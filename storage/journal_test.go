@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testLedger(t *testing.T, kv KVStore) *LedgerManager {
+	t.Helper()
+	lm := NewLedgerManager(core.NewBlockchain())
+	lm.kv = kv
+	return lm
+}
+
+// extendChain appends n blocks carrying one transaction each to chain.
+func extendChain(chain []*core.Block, n int, branch string) []*core.Block {
+	chain = append([]*core.Block(nil), chain...)
+	for i := 0; i < n; i++ {
+		tip := chain[len(chain)-1]
+		block := core.NewBlock(tip.Index+1, "", tip.Hash)
+		block.Transactions = []*core.Transaction{{ID: fmt.Sprintf("%s-%d", branch, block.Index), Sender: branch}}
+		block.MerkleRoot = block.ComputeMerkleRoot()
+		block.Hash = block.CalculateHash()
+		chain = append(chain, block)
+	}
+	return chain
+}
+
+// An interrupted reorg has already cut the old branch out of the block
+// store. Recovery must put it back, since the state store still holds the
+// old branch's index and accounts.
+func TestRecoverInterruptedReorg(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+
+	lm := testLedger(t, kv)
+	committed := extendChain(lm.Blockchain.Chain, 4, "old")
+	lm.Blockchain.Chain = committed
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+
+	// Replay a reorg onto a longer branch up to the point where the state
+	// batch would be written.
+	reorg := extendChain(committed[:2], 5, "new")
+	lm.Blockchain.Chain = reorg
+	store, _ := lm.blockStore()
+	err := beginJournal(&journalEntry{
+		Fork:      2,
+		Height:    int64(len(reorg)),
+		TipHash:   reorg[len(reorg)-1].Hash,
+		StartedAt: time.Now().Unix(),
+	}, store)
+	if err != nil {
+		t.Fatalf("beginJournal: %v", err)
+	}
+	if err := lm.syncBlockStore(store, 2); err != nil {
+		t.Fatalf("syncBlockStore: %v", err)
+	}
+	store.Close()
+
+	recovered := testLedger(t, kv)
+	if err := recovered.LoadBlockchain(); err != nil {
+		t.Fatalf("LoadBlockchain: %v", err)
+	}
+	defer recovered.store.Close()
+
+	chain := recovered.Blockchain.Chain
+	if len(chain) != len(committed) || chain[len(chain)-1].Hash != committed[len(committed)-1].Hash {
+		t.Fatalf("recovered chain ends at height %d, want the committed tip at %d", len(chain), len(committed))
+	}
+	for _, block := range committed[1:] {
+		tx, found := recovered.Blockchain.FindTransaction(block.Transactions[0].ID)
+		if tx == nil || found == nil || found.Hash != block.Hash {
+			t.Errorf("transaction %s of the committed branch is not found", block.Transactions[0].ID)
+		}
+	}
+	if entry, _, _ := readJournal(); entry != nil {
+		t.Error("journal was not cleared")
+	}
+
+	// Saving the new branch afterwards must work as a normal reorg.
+	recovered.Blockchain.Chain = reorg
+	if err := recovered.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain after recovery: %v", err)
+	}
+	if tx, _ := recovered.Blockchain.FindTransaction("old-3"); tx != nil {
+		t.Error("transaction of the abandoned branch is still indexed")
+	}
+}
+
+func TestReadJournalIgnoresTornEntry(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+
+	lm := testLedger(t, kv)
+	lm.Blockchain.Chain = extendChain(lm.Blockchain.Chain, 3, "old")
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	defer lm.store.Close()
+
+	if err := beginJournal(&journalEntry{Fork: 1, Height: 4}, lm.store); err != nil {
+		t.Fatalf("beginJournal: %v", err)
+	}
+	entry, abandoned, err := readJournal()
+	if err != nil || entry == nil || entry.Abandoned != 3 || len(abandoned) != 3 {
+		t.Fatalf("readJournal = %+v, %d blocks, %v", entry, len(abandoned), err)
+	}
+
+	// Cutting the journal short loses the entry, which comes last.
+	info, _ := os.Stat(journalPath())
+	truncateFile(t, journalPath(), info.Size()-5)
+	if entry, _, err := readJournal(); entry != nil || err != nil {
+		t.Errorf("torn journal read as %+v, %v", entry, err)
+	}
+}
+
+// Blocks past the committed tip with no journal entry were appended by a
+// save that committed; recovery keeps them rather than cutting them off.
+func TestRecoverKeepsBlocksPastCommittedTip(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+
+	lm := testLedger(t, kv)
+	lm.Blockchain.Chain = extendChain(lm.Blockchain.Chain, 3, "old")
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	lm.store.Close()
+
+	// Chain metadata left behind at height 2, as a stale writer used to.
+	chain := lm.Blockchain.Chain
+	meta, _ := json.Marshal(&chainMeta{Height: 2, TipHash: chain[1].Hash})
+	batch := NewKVBatch()
+	batch.Put(chainMetaKey, meta)
+	if err := kv.Write(batch); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	recovered := testLedger(t, kv)
+	if err := recovered.LoadBlockchain(); err != nil {
+		t.Fatalf("LoadBlockchain: %v", err)
+	}
+	defer recovered.store.Close()
+	if got := len(recovered.Blockchain.Chain); got != len(chain) {
+		t.Fatalf("recovered chain has %d blocks, want %d", got, len(chain))
+	}
+	if tx, _ := recovered.Blockchain.FindTransaction("old-3"); tx == nil {
+		t.Error("transaction of the kept block is not found")
+	}
+	if err := recovered.SaveBlockchain(); err != nil {
+		t.Errorf("SaveBlockchain after recovery: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"chainlog/crypto"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const KeyVaultFile = "keyvault.json"
+
+type SubjectKey struct {
+	Subject     string `json:"subject"`
+	KeyID       string `json:"key_id"`
+	Key         string `json:"key,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	DestroyedAt int64  `json:"destroyed_at,omitempty"`
+	ErasureTx   string `json:"erasure_tx,omitempty"`
+}
+
+type KeyVault struct {
+	Keys map[string]*SubjectKey `json:"keys"`
+	mu   sync.RWMutex
+}
+
+var (
+	keyVault     *KeyVault
+	keyVaultOnce sync.Once
+)
+
+func GetKeyVault() *KeyVault {
+	keyVaultOnce.Do(func() {
+		keyVault = &KeyVault{
+			Keys: make(map[string]*SubjectKey),
+		}
+		if err := keyVault.load(); err != nil {
+			fmt.Printf("Warning: Could not load key vault: %v\n", err)
+		}
+	})
+	return keyVault
+}
+
+func (kv *KeyVault) GetOrCreateSubjectKey(subject string) (*SubjectKey, []byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if existing, exists := kv.Keys[subject]; exists {
+		if existing.DestroyedAt != 0 {
+			return nil, nil, fmt.Errorf("key for subject %q was destroyed; new entries cannot be recorded under it", subject)
+		}
+		key, err := hex.DecodeString(existing.Key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("stored key for subject %q is corrupt", subject)
+		}
+		return existing, key, nil
+	}
+
+	key, err := crypto.GenerateSubjectKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subjectKey := &SubjectKey{
+		Subject:   subject,
+		KeyID:     crypto.SubjectKeyID(key),
+		Key:       hex.EncodeToString(key),
+		CreatedAt: time.Now().Unix(),
+	}
+	kv.Keys[subject] = subjectKey
+
+	if err := kv.save(); err != nil {
+		delete(kv.Keys, subject)
+		return nil, nil, err
+	}
+	return subjectKey, key, nil
+}
+
+func (kv *KeyVault) GetSubjectKey(subject string) (*SubjectKey, bool) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	subjectKey, exists := kv.Keys[subject]
+	return subjectKey, exists
+}
+
+func (kv *KeyVault) GetKeyByID(keyID string) (*SubjectKey, []byte, bool) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	for _, subjectKey := range kv.Keys {
+		if subjectKey.KeyID != keyID {
+			continue
+		}
+		if subjectKey.DestroyedAt != 0 {
+			return subjectKey, nil, true
+		}
+		key, err := hex.DecodeString(subjectKey.Key)
+		if err != nil {
+			return subjectKey, nil, true
+		}
+		return subjectKey, key, true
+	}
+	return nil, nil, false
+}
+
+func (kv *KeyVault) DestroySubjectKey(subject string, erasureTx string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	subjectKey, exists := kv.Keys[subject]
+	if !exists {
+		return fmt.Errorf("no key found for subject %q", subject)
+	}
+
+	if subjectKey.DestroyedAt != 0 {
+		return fmt.Errorf("key for subject %q was already destroyed", subject)
+	}
+
+	subjectKey.Key = ""
+	subjectKey.DestroyedAt = time.Now().Unix()
+	subjectKey.ErasureTx = erasureTx
+
+	return kv.save()
+}
+
+func (kv *KeyVault) GetAllSubjectKeys() []*SubjectKey {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+
+	keys := make([]*SubjectKey, 0, len(kv.Keys))
+	for _, subjectKey := range kv.Keys {
+		keys = append(keys, subjectKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Subject < keys[j].Subject
+	})
+	return keys
+}
+
+func (kv *KeyVault) load() error {
+	if !FileExists(KeyVaultFile) {
+		return nil
+	}
+
+	var keys map[string]*SubjectKey
+	if err := LoadFromFile(&keys, KeyVaultFile); err != nil {
+		return err
+	}
+
+	kv.Keys = keys
+	return nil
+}
+
+func (kv *KeyVault) save() error {
+	if err := EnsureDataDir(); err != nil {
+		return err
+	}
+	return SaveToFile(kv.Keys, KeyVaultFile)
+}
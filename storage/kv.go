@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+type KVStore interface {
+	Get(key string) ([]byte, bool, error)
+	Scan(prefix string, fn func(key string, value []byte) bool) error
+	Write(batch *KVBatch) error
+	Close() error
+}
+
+type kvOp struct {
+	Key    string
+	Value  []byte
+	Delete bool
+	Merge  func(stored []byte) []byte
+}
+
+// KVBatch collects puts and deletes that a store applies all together or
+// not at all.
+type KVBatch struct {
+	ops []kvOp
+}
+
+func NewKVBatch() *KVBatch {
+	return &KVBatch{}
+}
+
+func (b *KVBatch) Put(key string, value []byte) {
+	b.ops = append(b.ops, kvOp{Key: key, Value: value})
+}
+
+func (b *KVBatch) Delete(key string) {
+	b.ops = append(b.ops, kvOp{Key: key, Delete: true})
+}
+
+// Merge puts the value fn computes from the key's stored value. A store
+// calls fn while it holds its write lock, so no other writer can change the
+// stored value in between.
+func (b *KVBatch) Merge(key string, fn func(stored []byte) []byte) {
+	b.ops = append(b.ops, kvOp{Key: key, Merge: fn})
+}
+
+func (b *KVBatch) Len() int {
+	return len(b.ops)
+}
+
+// resolve turns merges into plain puts against the stored data.
+func (b *KVBatch) resolve(data map[string][]byte) *KVBatch {
+	resolved := &KVBatch{ops: make([]kvOp, len(b.ops))}
+	for i, op := range b.ops {
+		if op.Merge != nil {
+			op = kvOp{Key: op.Key, Value: op.Merge(data[op.Key])}
+		}
+		resolved.ops[i] = op
+	}
+	return resolved
+}
+
+type MemoryKV struct {
+	data map[string][]byte
+	mu   sync.RWMutex
+}
+
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{data: make(map[string][]byte)}
+}
+
+func (m *MemoryKV) Get(key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, exists := m.data[key]
+	return value, exists, nil
+}
+
+func (m *MemoryKV) Scan(prefix string, fn func(key string, value []byte) bool) error {
+	m.mu.RLock()
+	keys := sortedKeys(m.data, prefix)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = m.data[key]
+	}
+	m.mu.RUnlock()
+
+	for i, key := range keys {
+		if !fn(key, values[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *MemoryKV) Write(batch *KVBatch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	applyKVBatch(m.data, batch.resolve(m.data))
+	return nil
+}
+
+func (m *MemoryKV) Close() error {
+	return nil
+}
+
+func applyKVBatch(data map[string][]byte, batch *KVBatch) {
+	for _, op := range batch.ops {
+		if op.Delete {
+			delete(data, op.Key)
+		} else {
+			data[op.Key] = append([]byte(nil), op.Value...)
+		}
+	}
+}
+
+func sortedKeys(data map[string][]byte, prefix string) []string {
+	var keys []string
+	for key := range data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	StateDBFile = "state.db"
+
+	maxKVRecord           = 512 * 1024 * 1024
+	kvCompactMinSize      = 4 * 1024 * 1024
+	kvLockTimeout         = 30 * time.Second
+	kvOpPut          byte = 1
+	kvOpDelete       byte = 2
+)
+
+var (
+	stateStore     KVStore
+	stateStoreErr  error
+	stateStoreOnce sync.Once
+)
+
+// GetStateStore returns the node's embedded key-value store, opening
+// state.db in the data directory on first use.
+func GetStateStore() (KVStore, error) {
+	stateStoreOnce.Do(func() {
+		if stateStoreErr = EnsureDataDir(); stateStoreErr != nil {
+			return
+		}
+		stateStore, stateStoreErr = OpenLogKV(filepath.Join(DataDir, StateDBFile))
+	})
+	return stateStore, stateStoreErr
+}
+
+// LogKV is an append-only key-value log in the style of Bitcask. Every
+// batch is one checksummed record, fsynced before it is applied, and the
+// live keys are held in memory. When the log grows well past its live data
+// it is rewritten to a fresh file and swapped in with a rename.
+//
+// The node and CLI commands open the same log, so writers, repairs and
+// compactions take an OS lock on a .lock file next to it. Readers don't
+// need it: they only ever apply complete records.
+type LogKV struct {
+	path string
+	file *os.File
+	info os.FileInfo
+	data map[string][]byte
+	end  int64
+	live int64
+	mu   sync.Mutex
+}
+
+func OpenLogKV(path string) (*LogKV, error) {
+	l := &LogKV{path: path}
+
+	lock, err := l.lockWriters()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Close()
+
+	if err := l.reload(true); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lockWriters waits for other processes to finish writing the log and takes
+// the write lock. Closing the returned file releases it.
+func (l *LogKV) lockWriters() (*os.File, error) {
+	return waitForLock(l.path + ".lock")
+}
+
+// waitForLock takes the exclusive lock on path, waiting up to
+// kvLockTimeout for the process that holds it.
+func waitForLock(path string) (*os.File, error) {
+	deadline := time.Now().Add(kvLockTimeout)
+	for {
+		lock, err := openLocked(path)
+		if err == nil {
+			return lock, nil
+		}
+		if err != errLocked {
+			return nil, fmt.Errorf("failed to lock %s: %v", filepath.Base(path), err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for another process holding %s", filepath.Base(path))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (l *LogKV) reload(repair bool) error {
+	if l.file != nil {
+		l.file.Close()
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %v", l.path, err)
+	}
+
+	l.file = file
+	l.info = info
+	l.data = make(map[string][]byte)
+	l.end = 0
+	l.live = 0
+	return l.replay(repair)
+}
+
+// replay applies every complete record after l.end. With repair set, a torn
+// or corrupt tail is truncated; otherwise it is left alone in case another
+// process is still writing it.
+func (l *LogKV) replay(repair bool) error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %v", l.path, err)
+	}
+	if info.Size() == l.end {
+		return nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(l.file, l.end, info.Size()-l.end))
+	for {
+		payload, err := readRecord(reader, maxKVRecord)
+		if err != nil {
+			break
+		}
+
+		batch, err := decodeKVBatch(payload)
+		if err != nil {
+			break
+		}
+		l.apply(batch)
+		l.end += int64(recordHeaderSize + len(payload))
+	}
+
+	if l.end < info.Size() && repair {
+		fmt.Printf("State store: discarding %d bytes of incomplete batch at the end of %s\n", info.Size()-l.end, filepath.Base(l.path))
+		if err := l.file.Truncate(l.end); err != nil {
+			return fmt.Errorf("failed to truncate torn batch: %v", err)
+		}
+		return l.file.Sync()
+	}
+	return nil
+}
+
+// refresh picks up batches written by another process since the last read,
+// and reloads everything if the log was compacted underneath us. Only a
+// caller holding the write lock may repair, since a torn tail is then one
+// a crashed writer left behind rather than one still being written.
+func (l *LogKV) refresh(repair bool) error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %v", l.path, err)
+	}
+	if !os.SameFile(info, l.info) || info.Size() < l.end {
+		return l.reload(repair)
+	}
+	return l.replay(repair)
+}
+
+func (l *LogKV) apply(batch *KVBatch) {
+	for _, op := range batch.ops {
+		if old, exists := l.data[op.Key]; exists {
+			l.live -= int64(len(op.Key) + len(old))
+		}
+		if !op.Delete {
+			l.live += int64(len(op.Key) + len(op.Value))
+		}
+	}
+	applyKVBatch(l.data, batch)
+}
+
+func (l *LogKV) Get(key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.refresh(false); err != nil {
+		return nil, false, err
+	}
+	value, exists := l.data[key]
+	return value, exists, nil
+}
+
+func (l *LogKV) Scan(prefix string, fn func(key string, value []byte) bool) error {
+	l.mu.Lock()
+	if err := l.refresh(false); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	keys := sortedKeys(l.data, prefix)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = l.data[key]
+	}
+	l.mu.Unlock()
+
+	for i, key := range keys {
+		if !fn(key, values[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (l *LogKV) Write(batch *KVBatch) error {
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWriters()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := l.refresh(true); err != nil {
+		return err
+	}
+
+	batch = batch.resolve(l.data)
+	record := encodeRecord(encodeKVBatch(batch))
+	if _, err := l.file.Write(record); err != nil {
+		return fmt.Errorf("failed to write batch: %v", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync batch: %v", err)
+	}
+
+	l.apply(batch)
+	l.end += int64(len(record))
+
+	if l.end > kvCompactMinSize && l.end > 4*l.live {
+		return l.compact()
+	}
+	return nil
+}
+
+func (l *LogKV) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := l.lockWriters()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := l.refresh(true); err != nil {
+		return err
+	}
+	return l.compact()
+}
+
+func (l *LogKV) compact() error {
+	snapshot := NewKVBatch()
+	for _, key := range sortedKeys(l.data, "") {
+		snapshot.Put(key, l.data[key])
+	}
+
+	tmpPath := l.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted log: %v", err)
+	}
+	if snapshot.Len() > 0 {
+		if _, err := tmp.Write(encodeRecord(encodeKVBatch(snapshot))); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write compacted log: %v", err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync compacted log: %v", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace log: %v", err)
+	}
+	syncDir(filepath.Dir(l.path))
+
+	return l.reload(false)
+}
+
+func (l *LogKV) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func encodeKVBatch(batch *KVBatch) []byte {
+	var payload []byte
+	for _, op := range batch.ops {
+		if op.Delete {
+			payload = append(payload, kvOpDelete)
+		} else {
+			payload = append(payload, kvOpPut)
+		}
+		payload = binary.AppendUvarint(payload, uint64(len(op.Key)))
+		payload = append(payload, op.Key...)
+		if !op.Delete {
+			payload = binary.AppendUvarint(payload, uint64(len(op.Value)))
+			payload = append(payload, op.Value...)
+		}
+	}
+	return payload
+}
+
+func decodeKVBatch(payload []byte) (*KVBatch, error) {
+	batch := NewKVBatch()
+
+	readBytes := func() ([]byte, error) {
+		length, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < length {
+			return nil, fmt.Errorf("truncated batch")
+		}
+		value := payload[n : n+int(length)]
+		payload = payload[n+int(length):]
+		return value, nil
+	}
+
+	for len(payload) > 0 {
+		op := payload[0]
+		payload = payload[1:]
+
+		key, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case kvOpPut:
+			value, err := readBytes()
+			if err != nil {
+				return nil, err
+			}
+			batch.Put(string(key), value)
+		case kvOpDelete:
+			batch.Delete(string(key))
+		default:
+			return nil, fmt.Errorf("unknown batch operation %d", op)
+		}
+	}
+	return batch, nil
+}
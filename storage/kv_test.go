@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestLogKV(t *testing.T, path string) *LogKV {
+	t.Helper()
+	kv, err := OpenLogKV(path)
+	if err != nil {
+		t.Fatalf("OpenLogKV: %v", err)
+	}
+	t.Cleanup(func() { kv.Close() })
+	return kv
+}
+
+func kvContents(t *testing.T, kv KVStore, prefix string) string {
+	t.Helper()
+	var pairs []string
+	err := kv.Scan(prefix, func(key string, value []byte) bool {
+		pairs = append(pairs, key+"="+string(value))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func TestKVStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) KVStore{
+		"memory": func(t *testing.T) KVStore { return NewMemoryKV() },
+		"log": func(t *testing.T) KVStore {
+			return openTestLogKV(t, filepath.Join(t.TempDir(), StateDBFile))
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			kv := open(t)
+
+			batch := NewKVBatch()
+			batch.Put("a/1", []byte("one"))
+			batch.Put("a/2", []byte("two"))
+			batch.Put("b/1", []byte("three"))
+			if err := kv.Write(batch); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			batch = NewKVBatch()
+			batch.Delete("a/1")
+			batch.Put("a/2", []byte("TWO"))
+			batch.Merge("a/3", func(stored []byte) []byte { return []byte(fmt.Sprintf("[%s]", stored)) })
+			batch.Merge("b/1", func(stored []byte) []byte { return append(stored, '!') })
+			if err := kv.Write(batch); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			if got, want := kvContents(t, kv, ""), "a/2=TWO,a/3=[],b/1=three!"; got != want {
+				t.Errorf("contents = %s, want %s", got, want)
+			}
+			if got, want := kvContents(t, kv, "a/"), "a/2=TWO,a/3=[]"; got != want {
+				t.Errorf("a/ contents = %s, want %s", got, want)
+			}
+			if _, exists, _ := kv.Get("a/1"); exists {
+				t.Error("deleted key is still there")
+			}
+
+			var seen int
+			kv.Scan("", func(key string, value []byte) bool {
+				seen++
+				return false
+			})
+			if seen != 1 {
+				t.Errorf("Scan visited %d keys after being told to stop", seen)
+			}
+		})
+	}
+}
+
+func TestLogKVReplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, path string, end int64)
+		want   string
+	}{
+		{
+			name:   "clean",
+			damage: func(t *testing.T, path string, end int64) {},
+			want:   "k1=v1,k2=v2,k3=v3",
+		},
+		{
+			name: "torn batch",
+			damage: func(t *testing.T, path string, end int64) {
+				truncateFile(t, path, end+recordHeaderSize+4)
+			},
+			want: "k1=v1,k2=v1",
+		},
+		{
+			name: "torn header",
+			damage: func(t *testing.T, path string, end int64) {
+				truncateFile(t, path, end+3)
+			},
+			want: "k1=v1,k2=v1",
+		},
+		{
+			name: "corrupt batch",
+			damage: func(t *testing.T, path string, end int64) {
+				flipByte(t, path, end+recordHeaderSize+2)
+			},
+			want: "k1=v1,k2=v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), StateDBFile)
+			kv, err := OpenLogKV(path)
+			if err != nil {
+				t.Fatalf("OpenLogKV: %v", err)
+			}
+
+			first := NewKVBatch()
+			first.Put("k1", []byte("v1"))
+			first.Put("k2", []byte("v1"))
+			if err := kv.Write(first); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			end := kv.end
+
+			// The second batch changes one key and adds two; after a torn
+			// write none of it may show.
+			second := NewKVBatch()
+			second.Put("k2", []byte("v2"))
+			second.Put("k3", []byte("v3"))
+			second.Delete("k4")
+			if err := kv.Write(second); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			kv.Close()
+
+			tt.damage(t, path, end)
+
+			kv = openTestLogKV(t, path)
+			if got := kvContents(t, kv, ""); got != tt.want {
+				t.Errorf("after reopen = %s, want %s", got, tt.want)
+			}
+
+			more := NewKVBatch()
+			more.Put("k5", []byte("v5"))
+			if err := kv.Write(more); err != nil {
+				t.Fatalf("Write after replay: %v", err)
+			}
+			kv.Close()
+
+			kv = openTestLogKV(t, path)
+			if got, want := kvContents(t, kv, ""), tt.want+",k5=v5"; got != want {
+				t.Errorf("after second reopen = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// Two LogKVs on one file stand in for the node and a CLI command.
+func TestLogKVSharedByProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateDBFile)
+	node := openTestLogKV(t, path)
+	cli := openTestLogKV(t, path)
+
+	batch := NewKVBatch()
+	batch.Put("list", []byte("a"))
+	if err := node.Write(batch); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	appendItem := func(item string) *KVBatch {
+		batch := NewKVBatch()
+		batch.Merge("list", func(stored []byte) []byte { return append(stored, item...) })
+		return batch
+	}
+	if err := cli.Write(appendItem("b")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := node.Write(appendItem("c")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if value, _, _ := cli.Get("list"); string(value) != "abc" {
+		t.Errorf("list = %q, want abc", value)
+	}
+
+	// The node compacts the log into a new file; the CLI's next write must
+	// land in that file, not the one that was replaced.
+	if err := node.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if err := cli.Write(appendItem("d")); err != nil {
+		t.Fatalf("Write after compaction: %v", err)
+	}
+	if value, _, _ := node.Get("list"); string(value) != "abcd" {
+		t.Errorf("list = %q, want abcd", value)
+	}
+
+	reopened := openTestLogKV(t, path)
+	if value, _, _ := reopened.Get("list"); string(value) != "abcd" {
+		t.Errorf("reopened list = %q, want abcd", value)
+	}
+}
+
+func TestLogKVWriteWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateDBFile)
+	kv := openTestLogKV(t, path)
+
+	lock, err := openLocked(path + ".lock")
+	if err != nil {
+		t.Fatalf("openLocked: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		batch := NewKVBatch()
+		batch.Put("key", []byte("value"))
+		done <- kv.Write(batch)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Write finished while another process held the lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lock.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Errorf("batch was not written after the lock was released")
+	}
+}
@@ -2,11 +2,52 @@ package storage
 
 import (
 	"chainlog/core"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 )
 
 type LedgerManager struct {
 	Blockchain *core.Blockchain
+	State      *StateManager
+
+	store            *BlockStore
+	kv               KVStore
+	savedIndexTip    string
+	savedIndexHeight int64
+	prunedHeight     int64
+	pendingSeen      map[string]bool
+
+	// committed is the chain metadata as this process last loaded or
+	// saved it. A save only replaces metadata that still matches it.
+	committed *chainMeta
+}
+
+const (
+	chainMetaKey    = "chain/meta"
+	chainMempoolKey = "chain/mempool"
+
+	// LedgerLockFile is held while a process loads, recovers or saves the
+	// chain, so the block store, the journal and the chain metadata only
+	// change one process at a time.
+	LedgerLockFile = "ledger.lock"
+)
+
+type ledgerMeta struct {
+	PendingTx   []*core.Transaction `json:"pending_transactions"`
+	Difficulty  int                 `json:"difficulty"`
+	BlockReward uint64              `json:"block_reward"`
+}
+
+type chainMeta struct {
+	Height      int64  `json:"height"`
+	TipHash     string `json:"tip_hash"`
+	Difficulty  int    `json:"difficulty"`
+	BlockReward uint64 `json:"block_reward"`
+
+	PrunedHeight int64 `json:"pruned_height,omitempty"`
 }
 
 func NewLedgerManager(bc *core.Blockchain) *LedgerManager {
@@ -15,53 +56,291 @@ func NewLedgerManager(bc *core.Blockchain) *LedgerManager {
 	}
 }
 
+// AttachState makes SaveBlockchain commit account changes in the same
+// batch as the chain metadata and indexes they belong to.
+func (lm *LedgerManager) AttachState(sm *StateManager) {
+	lm.State = sm
+}
+
+func lockLedger() (*os.File, error) {
+	return waitForLock(filepath.Join(DataDir, LedgerLockFile))
+}
+
+func (lm *LedgerManager) stateStore() (KVStore, error) {
+	if lm.kv == nil {
+		kv, err := GetStateStore()
+		if err != nil {
+			return nil, err
+		}
+		lm.kv = kv
+	}
+	return lm.kv, nil
+}
+
+// SaveBlockchain appends new blocks to the block store, then commits the
+// chain metadata, mempool, index entries and attached account state as one
+// atomic batch.
+//
+// If another process has saved a different tip since this one loaded the
+// chain, only the mempool and accounts are saved, and only if this process
+// has not changed the chain itself; otherwise the save fails and the chain
+// has to be reloaded.
 func (lm *LedgerManager) SaveBlockchain() error {
 	if err := EnsureDataDir(); err != nil {
 		return err
 	}
-	
-	blockchainData := struct {
-		Chain       []*core.Block  `json:"chain"`
-		PendingTx   []*core.Transaction `json:"pending_transactions"`
-		Difficulty  int            `json:"difficulty"`
-		BlockReward uint64         `json:"block_reward"`
-	}{
-		Chain:       lm.Blockchain.Chain,
-		PendingTx:   lm.Blockchain.PendingTx,
-		Difficulty:  lm.Blockchain.Difficulty,
-		BlockReward: lm.Blockchain.BlockReward,
+	lock, err := lockLedger()
+	if err != nil {
+		return err
 	}
-	
-	if err := SaveToFile(blockchainData, BlocksFile); err != nil {
+	defer lock.Close()
+
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+	saved, err := lm.savedTip(kv)
+	if err != nil {
+		return err
+	}
+	if saved != nil && !saved.sameTip(lm.committed) {
+		if lm.committed == nil || !lm.committed.isTipOf(lm.Blockchain) {
+			return fmt.Errorf("another process saved the chain at height %d since it was loaded; reload it and try again", saved.Height)
+		}
+		return lm.saveMempool(kv)
+	}
+
+	store, err := lm.blockStore()
+	if err != nil {
+		return err
+	}
+	fork, err := lm.storeForkPoint(store)
+	if err != nil {
 		return err
 	}
+
+	height := int64(len(lm.Blockchain.Chain))
+	journaled := fork < store.Height() || fork < height
+	if journaled {
+		err := beginJournal(&journalEntry{
+			Fork:      fork,
+			Height:    height,
+			TipHash:   lm.Blockchain.GetLastBlock().Hash,
+			StartedAt: time.Now().Unix(),
+		}, store)
+		if err != nil {
+			return err
+		}
+		if err := lm.syncBlockStore(store, fork); err != nil {
+			return err
+		}
+	}
+
+	batch := NewKVBatch()
+	meta := lm.stageChainMeta(batch)
+
+	indexSaved, err := lm.stageIndex(kv, batch)
+	if err != nil {
+		return err
+	}
+
+	var accounts map[string]string
+	if lm.State != nil {
+		accounts = lm.State.stage(batch)
+	}
+
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	lm.committed = meta
+	if journaled {
+		if err := clearJournal(); err != nil {
+			return err
+		}
+	}
+	indexSaved()
+	if lm.State != nil {
+		lm.State.markSaved(accounts)
+	}
 	
 	fmt.Printf("Saved blockchain: %d blocks, %d pending transactions\n",
 		len(lm.Blockchain.Chain), len(lm.Blockchain.PendingTx))
-	
+
+	if Pruning.Enabled() {
+		if err := lm.pruneBlocks(store); err != nil {
+			return fmt.Errorf("failed to prune blocks: %v", err)
+		}
+	}
+	return nil
+}
+
+// savedTip reads the chain metadata last committed by any process. A save
+// that a crashed process left in the journal is recovered first, since the
+// metadata doesn't describe the block store until then.
+func (lm *LedgerManager) savedTip(kv KVStore) (*chainMeta, error) {
+	if entry, _, err := readJournal(); err != nil {
+		return nil, err
+	} else if entry != nil {
+		if lm.store != nil {
+			lm.store.Close()
+			lm.store = nil
+		}
+		store, err := lm.blockStore()
+		if err != nil {
+			return nil, err
+		}
+		if err := lm.recover(store); err != nil {
+			return nil, fmt.Errorf("recovery failed: %v", err)
+		}
+	}
+
+	raw, exists, err := kv.Get(chainMetaKey)
+	if err != nil || !exists {
+		return nil, err
+	}
+	var meta chainMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("invalid chain metadata: %v", err)
+	}
+	return &meta, nil
+}
+
+// sameTip reports whether two saves describe the same blocks on disk.
+func (m *chainMeta) sameTip(other *chainMeta) bool {
+	return other != nil && m.Height == other.Height && m.TipHash == other.TipHash &&
+		m.PrunedHeight == other.PrunedHeight
+}
+
+func (m *chainMeta) isTipOf(bc *core.Blockchain) bool {
+	return m.Height == int64(len(bc.Chain)) && m.TipHash == bc.GetLastBlock().Hash
+}
+
+// saveMempool commits the mempool and account changes of a process whose
+// chain another process has since moved past, leaving the chain alone.
+func (lm *LedgerManager) saveMempool(kv KVStore) error {
+	batch := NewKVBatch()
+	batch.Merge(chainMempoolKey, lm.mergeMempool)
+
+	var accounts map[string]string
+	if lm.State != nil {
+		accounts = lm.State.stage(batch)
+	}
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	if lm.State != nil {
+		lm.State.markSaved(accounts)
+	}
+
+	fmt.Printf("Saved %d pending transactions; the chain was saved by another process\n",
+		len(lm.Blockchain.PendingTx))
+	return nil
+}
+
+// storeForkPoint returns the height up to which the block store matches the
+// in-memory chain.
+func (lm *LedgerManager) storeForkPoint(store *BlockStore) (int64, error) {
+	chain := lm.Blockchain.Chain
+	fork := store.Height()
+	if fork > int64(len(chain)) {
+		fork = int64(len(chain))
+	}
+
+	for fork > 0 {
+		storedHash := store.TipHash()
+		if fork != store.Height() {
+			block, err := store.ReadBlock(fork - 1)
+			if err != nil {
+				return 0, err
+			}
+			storedHash = block.Hash
+		}
+		if storedHash == chain[fork-1].Hash {
+			break
+		}
+		fork--
+	}
+	return fork, nil
+}
+
+// syncBlockStore cuts the store back to the fork if the stored tip is no
+// longer on the chain, then appends the blocks it has not seen yet.
+func (lm *LedgerManager) syncBlockStore(store *BlockStore, fork int64) error {
+	chain := lm.Blockchain.Chain
+	if fork < store.Height() {
+		fmt.Printf("Block store: rolling back from height %d to %d\n", store.Height(), fork)
+		if err := store.Truncate(fork); err != nil {
+			return err
+		}
+	}
+	if fork < int64(len(chain)) {
+		return store.Append(chain[fork:]...)
+	}
 	return nil
 }
 
+func (lm *LedgerManager) blockStore() (*BlockStore, error) {
+	if lm.store == nil {
+		store, err := OpenBlockStore()
+		if err != nil {
+			return nil, err
+		}
+		lm.store = store
+	}
+	return lm.store, nil
+}
+
 func (lm *LedgerManager) LoadBlockchain() error {
-	if !FileExists(BlocksFile) {
+	if !BlockStoreExists() && !FileExists(BlocksFile) {
 		return fmt.Errorf("blockchain file does not exist, starting fresh")
 	}
-	
-	var blockchainData struct {
-		Chain       []*core.Block  `json:"chain"`
-		PendingTx   []*core.Transaction `json:"pending_transactions"`
-		Difficulty  int            `json:"difficulty"`
-		BlockReward uint64         `json:"block_reward"`
+	lock, err := lockLedger()
+	if err != nil {
+		return err
 	}
-	
-	if err := LoadFromFile(&blockchainData, BlocksFile); err != nil {
+	defer lock.Close()
+
+	if !BlockStoreExists() {
+		if err := lm.migrateBlocksFile(); err != nil {
+			return err
+		}
+	}
+
+	if lm.store != nil {
+		lm.store.Close()
+		lm.store = nil
+	}
+	store, err := lm.blockStore()
+	if err != nil {
 		return err
 	}
-	
-	lm.Blockchain.Chain = blockchainData.Chain
-	lm.Blockchain.PendingTx = blockchainData.PendingTx
-	lm.Blockchain.Difficulty = blockchainData.Difficulty
-	lm.Blockchain.BlockReward = blockchainData.BlockReward
+	if err := lm.recover(store); err != nil {
+		return fmt.Errorf("recovery failed: %v", err)
+	}
+	if store.Height() == 0 {
+		return fmt.Errorf("block store is empty, starting fresh")
+	}
+
+	chain := make([]*core.Block, 0, store.Height())
+	err = store.Iterate(func(block *core.Block) error {
+		chain = append(chain, block)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	lm.Blockchain.Chain = chain
+
+	if err := lm.loadChainMeta(); err != nil {
+		return err
+	}
+	if lm.Blockchain.PendingTx == nil {
+		lm.Blockchain.PendingTx = []*core.Transaction{}
+	}
+
+	if err := lm.LoadIndex(); err != nil {
+		fmt.Printf("Building chain index: %v\n", err)
+	}
 	
 	fmt.Printf("Loaded blockchain: %d blocks, %d pending transactions\n",
 		len(lm.Blockchain.Chain), len(lm.Blockchain.PendingTx))
@@ -69,6 +348,192 @@ func (lm *LedgerManager) LoadBlockchain() error {
 	return nil
 }
 
+// migrateBlocksFile moves a chain saved by older versions as one blocks.json
+// file into the segmented block store, keeping the old file as a backup.
+func (lm *LedgerManager) migrateBlocksFile() error {
+	var legacy struct {
+		Chain []*core.Block `json:"chain"`
+		ledgerMeta
+	}
+	if err := LoadFromFile(&legacy, BlocksFile); err != nil {
+		return err
+	}
+
+	store, err := lm.blockStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Truncate(0); err != nil {
+		return err
+	}
+	if err := store.Append(legacy.Chain...); err != nil {
+		return err
+	}
+	lm.Blockchain.Chain = legacy.Chain
+	if err := lm.saveLedgerMeta(&legacy.ledgerMeta); err != nil {
+		return err
+	}
+
+	backup := BlocksFile + ".migrated"
+	if err := os.Rename(filepath.Join(DataDir, BlocksFile), filepath.Join(DataDir, backup)); err != nil {
+		return fmt.Errorf("failed to move %s aside: %v", BlocksFile, err)
+	}
+
+	fmt.Printf("Migrated %d blocks from %s into %s/ (old file kept as %s)\n",
+		len(legacy.Chain), BlocksFile, BlocksDir, backup)
+	return nil
+}
+
+// stageChainMeta adds the chain metadata and mempool to batch and returns
+// the metadata, which is committed once the batch is written.
+func (lm *LedgerManager) stageChainMeta(batch *KVBatch) *chainMeta {
+	meta := &chainMeta{
+		Height:      int64(len(lm.Blockchain.Chain)),
+		TipHash:     lm.Blockchain.GetLastBlock().Hash,
+		Difficulty:  lm.Blockchain.Difficulty,
+		BlockReward: lm.Blockchain.BlockReward,
+
+		PrunedHeight: lm.prunedHeight,
+	}
+	encoded, _ := json.Marshal(meta)
+
+	batch.Put(chainMetaKey, encoded)
+	batch.Merge(chainMempoolKey, lm.mergeMempool)
+	return meta
+}
+
+// mergeMempool combines the saved mempool with the in-memory one against
+// the mempool this process last loaded or saved: transactions another
+// process queued since are added, and ones it removed since, by mining
+// them or dropping them, stay removed. Transactions this process removed,
+// or that are in one of its blocks, stay removed too.
+func (lm *LedgerManager) mergeMempool(stored []byte) []byte {
+	var saved []*core.Transaction
+	if len(stored) > 0 {
+		json.Unmarshal(stored, &saved)
+	}
+	stillSaved := make(map[string]bool, len(saved))
+	for _, tx := range saved {
+		stillSaved[tx.ID] = true
+	}
+
+	var pending []*core.Transaction
+	queued := make(map[string]bool, len(lm.Blockchain.PendingTx))
+	for _, tx := range lm.Blockchain.PendingTx {
+		if lm.pendingSeen[tx.ID] && !stillSaved[tx.ID] {
+			continue
+		}
+		pending = append(pending, tx)
+		queued[tx.ID] = true
+	}
+
+	if len(saved) > 0 {
+		confirmed := lm.Blockchain.Index().Transactions
+		for _, tx := range saved {
+			if _, exists := confirmed[tx.ID]; exists || queued[tx.ID] || lm.pendingSeen[tx.ID] {
+				continue
+			}
+			pending = append(pending, tx)
+			queued[tx.ID] = true
+		}
+	}
+	if pending == nil {
+		pending = []*core.Transaction{}
+	}
+
+	lm.Blockchain.PendingTx = pending
+	lm.pendingSeen = queued
+	mempool, _ := json.Marshal(pending)
+	return mempool
+}
+
+func (lm *LedgerManager) loadChainMeta() error {
+	if FileExists(LedgerFile) {
+		var legacy ledgerMeta
+		if err := LoadFromFile(&legacy, LedgerFile); err != nil {
+			return err
+		}
+		if err := lm.saveLedgerMeta(&legacy); err != nil {
+			return err
+		}
+		os.Rename(filepath.Join(DataDir, LedgerFile), filepath.Join(DataDir, LedgerFile+".migrated"))
+	}
+
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+
+	if raw, exists, err := kv.Get(chainMetaKey); err != nil {
+		return err
+	} else if exists {
+		var meta chainMeta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return fmt.Errorf("invalid chain metadata: %v", err)
+		}
+		lm.Blockchain.Difficulty = meta.Difficulty
+		lm.Blockchain.BlockReward = meta.BlockReward
+		lm.prunedHeight = meta.PrunedHeight
+		lm.committed = &meta
+	}
+
+	if raw, exists, err := kv.Get(chainMempoolKey); err != nil {
+		return err
+	} else if exists {
+		var pending []*core.Transaction
+		if err := json.Unmarshal(raw, &pending); err != nil {
+			return fmt.Errorf("invalid mempool: %v", err)
+		}
+		lm.Blockchain.PendingTx = pending
+	}
+
+	lm.pendingSeen = make(map[string]bool, len(lm.Blockchain.PendingTx))
+	for _, tx := range lm.Blockchain.PendingTx {
+		lm.pendingSeen[tx.ID] = true
+	}
+	return nil
+}
+
+// PendingCount reads how many transactions are waiting in the saved mempool,
+// which other processes may have added to since the chain was loaded.
+func (lm *LedgerManager) PendingCount() (int, error) {
+	kv, err := lm.stateStore()
+	if err != nil {
+		return 0, err
+	}
+
+	raw, exists, err := kv.Get(chainMempoolKey)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var pending []json.RawMessage
+	if err := json.Unmarshal(raw, &pending); err != nil {
+		return 0, fmt.Errorf("invalid mempool: %v", err)
+	}
+	return len(pending), nil
+}
+
+// saveLedgerMeta imports the mempool and chain parameters that older
+// versions kept in blocks.json or ledger.json.
+func (lm *LedgerManager) saveLedgerMeta(legacy *ledgerMeta) error {
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+
+	lm.Blockchain.PendingTx = legacy.PendingTx
+	lm.Blockchain.Difficulty = legacy.Difficulty
+	lm.Blockchain.BlockReward = legacy.BlockReward
+
+	batch := NewKVBatch()
+	meta := lm.stageChainMeta(batch)
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	lm.committed = meta
+	return nil
+}
+
 func (lm *LedgerManager) SaveBlock(block *core.Block) error {
 	return lm.SaveBlockchain()
 }
@@ -96,5 +561,8 @@ func (lm *LedgerManager) DisplayStorageInfo() {
 	fmt.Printf("├─ Blocks: %d\n", blockCount)
 	fmt.Printf("├─ Pending Transactions: %d\n", pendingTx)
 	fmt.Printf("├─ Data Size: %.2f KB\n", float64(dataSize)/1024)
-	fmt.Printf("└─ Persisted: %t\n", FileExists(BlocksFile))
+	if pruned := lm.Blockchain.PrunedHeight(); pruned > 0 {
+		fmt.Printf("├─ Pruned: transactions kept from block %d\n", pruned)
+	}
+	fmt.Printf("└─ Persisted: %t\n", BlockStoreExists())
 }
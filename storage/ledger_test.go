@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mempoolIDs(t *testing.T, kv KVStore) string {
+	t.Helper()
+	raw, _, err := kv.Get(chainMempoolKey)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	var pending []*core.Transaction
+	if err := json.Unmarshal(raw, &pending); err != nil {
+		t.Fatalf("invalid mempool: %v", err)
+	}
+	var ids []string
+	for _, tx := range pending {
+		ids = append(ids, tx.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// A miner and a submitting command both save the mempool they loaded; each
+// save must keep what the other queued and drop what it removed itself.
+func TestMempoolMergesConcurrentSaves(t *testing.T) {
+	kv := NewMemoryKV()
+	saveMempool := func(lm *LedgerManager) {
+		batch := NewKVBatch()
+		lm.stageChainMeta(batch)
+		if err := kv.Write(batch); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	load := func() *LedgerManager {
+		lm := &LedgerManager{Blockchain: core.NewBlockchain(), kv: kv}
+		if err := lm.loadChainMeta(); err != nil {
+			t.Fatalf("loadChainMeta: %v", err)
+		}
+		return lm
+	}
+
+	first := load()
+	first.Blockchain.PendingTx = []*core.Transaction{{ID: "tx-1"}, {ID: "tx-2"}}
+	saveMempool(first)
+
+	miner := load()
+	submitter := load()
+
+	// The submitter queues a transaction while the miner mines tx-1 and
+	// drops tx-2 as invalid.
+	submitter.Blockchain.PendingTx = append(submitter.Blockchain.PendingTx, &core.Transaction{ID: "tx-3"})
+	saveMempool(submitter)
+
+	block := core.NewBlock(1, "", miner.Blockchain.GetLastBlock().Hash)
+	block.Transactions = []*core.Transaction{miner.Blockchain.PendingTx[0]}
+	miner.Blockchain.AppendBlock(block)
+	miner.Blockchain.PendingTx = nil
+	saveMempool(miner)
+
+	if got := mempoolIDs(t, kv); got != "tx-3" {
+		t.Errorf("mempool after the miner saved = %s, want tx-3", got)
+	}
+	if len(miner.Blockchain.PendingTx) != 1 {
+		t.Errorf("miner did not adopt the submitted transaction")
+	}
+
+	// The submitter's next save adds to what is queued.
+	submitter.Blockchain.PendingTx = append(submitter.Blockchain.PendingTx, &core.Transaction{ID: "tx-4"})
+	saveMempool(submitter)
+	if got := mempoolIDs(t, kv); !strings.Contains(got, "tx-3") || !strings.Contains(got, "tx-4") {
+		t.Errorf("mempool = %s, want tx-3 and tx-4 kept", got)
+	}
+}
+
+// A command that loaded the chain before the miner saved a new block must
+// not put the old tip back when it saves what it queued.
+func TestStaleSaveKeepsNewerTip(t *testing.T) {
+	DataDir = t.TempDir()
+	path := filepath.Join(DataDir, StateDBFile)
+
+	first := testLedger(t, openTestLogKV(t, path))
+	first.Blockchain.Chain = extendChain(first.Blockchain.Chain, 2, "base")
+	if err := first.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	first.store.Close()
+
+	load := func() *LedgerManager {
+		lm := testLedger(t, openTestLogKV(t, path))
+		if err := lm.LoadBlockchain(); err != nil {
+			t.Fatalf("LoadBlockchain: %v", err)
+		}
+		t.Cleanup(func() { lm.store.Close() })
+		return lm
+	}
+	miner := load()
+	command := load()
+	other := load()
+
+	miner.Blockchain.Chain = extendChain(miner.Blockchain.Chain, 1, "mined")
+	if err := miner.SaveBlockchain(); err != nil {
+		t.Fatalf("miner SaveBlockchain: %v", err)
+	}
+
+	command.Blockchain.PendingTx = append(command.Blockchain.PendingTx, &core.Transaction{ID: "queued"})
+	if err := command.SaveBlockchain(); err != nil {
+		t.Fatalf("command SaveBlockchain: %v", err)
+	}
+
+	other.Blockchain.Chain = extendChain(other.Blockchain.Chain, 1, "fork")
+	if err := other.SaveBlockchain(); err == nil {
+		t.Error("a stale process saved a chain of its own over the newer tip")
+	}
+
+	reloaded := load()
+	if got, want := reloaded.Blockchain.GetLastBlock().Hash, miner.Blockchain.GetLastBlock().Hash; got != want {
+		t.Errorf("tip after the stale saves is %.8s at height %d, want the miner's %.8s", got, len(reloaded.Blockchain.Chain), want)
+	}
+	if got := mempoolIDs(t, reloaded.kv); got != "queued" {
+		t.Errorf("mempool = %s, want queued", got)
+	}
+}
+
+func TestSaveWaitsForLedgerLock(t *testing.T) {
+	DataDir = t.TempDir()
+	lm := testLedger(t, openTestLogKV(t, filepath.Join(DataDir, StateDBFile)))
+	lm.Blockchain.Chain = extendChain(lm.Blockchain.Chain, 1, "base")
+
+	lock, err := openLocked(filepath.Join(DataDir, LedgerLockFile))
+	if err != nil {
+		t.Fatalf("openLocked: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- lm.SaveBlockchain() }()
+	select {
+	case err := <-done:
+		t.Fatalf("SaveBlockchain finished while another process held the lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lock.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	lm.store.Close()
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"chainlog/core"
+	"chainlog/crypto"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const MultisigFile = "multisig.json"
+
+func loadMultisigAccounts() (map[string]*crypto.MultisigAccount, error) {
+	accounts := make(map[string]*crypto.MultisigAccount)
+	if !FileExists(MultisigFile) {
+		return accounts, nil
+	}
+
+	if err := LoadFromFile(&accounts, MultisigFile); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func SaveMultisigAccount(account *crypto.MultisigAccount) error {
+	if err := EnsureDataDir(); err != nil {
+		return err
+	}
+
+	accounts, err := loadMultisigAccounts()
+	if err != nil {
+		return err
+	}
+
+	accounts[account.Address] = account
+	return SaveToFile(accounts, MultisigFile)
+}
+
+func LoadMultisigAccount(address string) (*crypto.MultisigAccount, error) {
+	accounts, err := loadMultisigAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	account, exists := accounts[address]
+	if !exists {
+		return nil, fmt.Errorf("multisig account not found in storage: %s", address)
+	}
+
+	if err := account.Verify(); err != nil {
+		return nil, fmt.Errorf("stored multisig account %s is corrupt: %v", address, err)
+	}
+	return account, nil
+}
+
+func GetAllMultisigAccounts() ([]*crypto.MultisigAccount, error) {
+	accounts, err := loadMultisigAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*crypto.MultisigAccount, 0, len(accounts))
+	for _, account := range accounts {
+		list = append(list, account)
+	}
+	return list, nil
+}
+
+func SavePartialTransaction(tx *core.Transaction, path string) error {
+	data, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write transaction file: %v", err)
+	}
+	return nil
+}
+
+func LoadPartialTransaction(path string) (*core.Transaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction file: %v", err)
+	}
+
+	var tx core.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction: %v", err)
+	}
+
+	if tx.Multisig == nil {
+		return nil, fmt.Errorf("%s is not a multisig transaction", path)
+	}
+
+	if tx.ID != tx.CalculateID() {
+		return nil, fmt.Errorf("transaction ID is invalid")
+	}
+	return &tx, nil
+}
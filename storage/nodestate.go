@@ -2,38 +2,132 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
-	"path/filepath"
+	"time"
 )
 
 type NodeState struct {
-	IsRunning bool   `json:"is_running"`
+	PID       int    `json:"pid"`
 	Port      string `json:"port"`
 	DataDir   string `json:"data_dir"`
+	StartedAt int64  `json:"started_at"`
 }
 
-func IsNodeRunning() bool {
-	path := filepath.Join(DataDir, "node_state.json")
-	_, err := os.Stat(path)
-	return err == nil
+var errLocked = errors.New("lock is held by another process")
+
+// nodeLockRetry is how long a starting node keeps trying the lock before it
+// decides another node holds it. RunningNode takes the lock for a moment to
+// probe it, and a node starting just then must not take the probe for a
+// running node.
+const nodeLockRetry = 250 * time.Millisecond
+
+// NodeLock is the running node's hold on its data directory. The lock file
+// carries the node's PID and port, but only the OS-level lock on it counts:
+// a file left behind by a process that was killed is stale and gets taken
+// over by the next node to start.
+type NodeLock struct {
+	file *os.File
 }
 
-func SaveNodeState(port string) error {
-	state := &NodeState{
-		IsRunning: true,
+func AcquireNodeLock(port string) (*NodeLock, error) {
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	previous := readNodeState()
+	file, err := openLocked(Path(NodeLockFile))
+	for deadline := time.Now().Add(nodeLockRetry); err == errLocked && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		file, err = openLocked(Path(NodeLockFile))
+	}
+	if err == errLocked {
+		if previous != nil {
+			return nil, fmt.Errorf("a node is already running on %s (PID %d, port %s)", DataDir, previous.PID, previous.Port)
+		}
+		return nil, fmt.Errorf("a node is already running on %s", DataDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	if previous != nil {
+		fmt.Printf("Removing stale lock left by PID %d\n", previous.PID)
+	}
+	// Older versions marked a running node with node_state.json.
+	os.Remove(Path(NodeStateFile))
+
+	data, _ := json.Marshal(&NodeState{
+		PID:       os.Getpid(),
 		Port:      port,
 		DataDir:   DataDir,
+		StartedAt: time.Now().Unix(),
+	})
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt(data, 0)
+	}
+	if err == nil {
+		err = file.Sync()
 	}
-	
-	path := filepath.Join(DataDir, "node_state.json")
-	data, err := json.Marshal(state)
 	if err != nil {
-		return err
+		file.Close()
+		return nil, fmt.Errorf("failed to write lock file: %v", err)
 	}
-	return os.WriteFile(path, data, 0644)
+	return &NodeLock{file: file}, nil
 }
 
-func DeleteNodeState() error {
-	path := filepath.Join(DataDir, "node_state.json")
-	return os.Remove(path)
+// Release empties the lock file and drops the lock. The file itself stays,
+// so a process that opened it just before can't end up locking a file that
+// has already been unlinked.
+func (l *NodeLock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// RunningNode returns the state of the node holding this data directory's
+// lock, if there is one. It probes by taking the lock and dropping it at
+// once; AcquireNodeLock retries for long enough to get past a probe.
+func RunningNode() (*NodeState, bool) {
+	path := Path(NodeLockFile)
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+
+	file, err := openLocked(path)
+	if err == nil {
+		file.Close()
+		return nil, false
+	}
+	if err != errLocked {
+		return nil, false
+	}
+
+	state := readNodeState()
+	if state == nil {
+		state = &NodeState{DataDir: DataDir}
+	}
+	return state, true
+}
+
+func IsNodeRunning() bool {
+	_, running := RunningNode()
+	return running
+}
+
+func readNodeState() *NodeState {
+	data, err := os.ReadFile(Path(NodeLockFile))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var state NodeState
+	if json.Unmarshal(data, &state) != nil {
+		return nil
+	}
+	return &state
 }
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// A node starting while another process probes the lock must wait out the
+// probe, while a lock that stays held still means a node is running.
+func TestAcquireNodeLockDuringProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		hold    time.Duration
+		started bool
+	}{
+		{name: "free", started: true},
+		{name: "probed", hold: 20 * time.Millisecond, started: true},
+		{name: "held", hold: 2 * nodeLockRetry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DataDir = t.TempDir()
+			if tt.hold > 0 {
+				held, err := openLocked(Path(NodeLockFile))
+				if err != nil {
+					t.Fatalf("openLocked: %v", err)
+				}
+				released := time.AfterFunc(tt.hold, func() { held.Close() })
+				defer func() {
+					if released.Stop() {
+						held.Close()
+					}
+				}()
+			}
+
+			lock, err := AcquireNodeLock("8080")
+			if started := err == nil; started != tt.started {
+				t.Fatalf("AcquireNodeLock error = %v, want started %t", err, tt.started)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "already running") {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			defer lock.Release()
+			if state, running := RunningNode(); !running || state.Port != "8080" {
+				t.Errorf("RunningNode = %+v, %t; want the started node", state, running)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package storage 
 
 import (
+	"chainlog/config"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,12 +10,30 @@ import (
 )
 
 const (
-	DataDir        = "./chainlog-data"
-	BlocksFile     = "blocks.json"
-	StateFile      = "state.json"
-	WalletsFile    = "wallets.json"
+	BlocksFile         = "blocks.json"
+	LedgerFile         = "ledger.json"
+	StateFile          = "state.json"
+	WalletsFile        = "wallets.json"
+	WalletsBackupFile  = "wallets.json.bak"
+	StakingFile        = "staking.json"
+	NodesFile          = "nodes.json"
+	NodeStateFile      = "node_state.json"
+	NodeLockFile       = "node.lock"
+	BroadcastQueueFile = "pending_broadcasts.txt"
 )
 
+// DataDir is where every file the node keeps lives. It is set once from the
+// loaded configuration, before anything is read or written.
+var DataDir = config.DefaultDataDir
+
+func SetDataDir(dir string) {
+	DataDir = dir
+}
+
+func Path(filename string) string {
+	return filepath.Join(DataDir, filename)
+}
+
 func EnsureDataDir() error {
 	if err := os.MkdirAll(DataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %v", err)
@@ -24,6 +43,12 @@ func EnsureDataDir() error {
 }
 
 func SaveToFile(data interface{}, filename string) error {
+	return saveToFileMode(data, filename, 0644)
+}
+
+// saveToFileMode is SaveToFile with the file mode chosen by the caller,
+// for files such as wallets.json that only the owner may read.
+func saveToFileMode(data interface{}, filename string, perm os.FileMode) error {
 	filePath := filepath.Join(DataDir, filename)
 	
 	jsonData, err := json.MarshalIndent(data, "", "  ")
@@ -31,7 +56,7 @@ func SaveToFile(data interface{}, filename string) error {
 		return fmt.Errorf("failed to marshal data: %v", err)
 	}
 	
-	if err := ioutil.WriteFile(filePath, jsonData, 0644); err != nil {
+	if err := WriteFileAtomic(filePath, jsonData, perm); err != nil {
 		return fmt.Errorf("failed to write file: %v", err)
 	}
 	
@@ -39,6 +64,53 @@ func SaveToFile(data interface{}, filename string) error {
 	return nil
 }
 
+// WriteFileAtomic writes data to a temporary file next to path, fsyncs it
+// and renames it over path, so a crash leaves either the old contents or
+// the new ones and never a half-written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory so that renames and new files in it survive
+// a crash.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
 func LoadFromFile(data interface{}, filename string) error {
 	filePath := filepath.Join(DataDir, filename)
 	
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"bufio"
+	"chainlog/config"
+	"chainlog/core"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// pruneTmpPrefix marks the segment and offsets files a prune writes before
+// renaming them into place.
+const pruneTmpPrefix = "prune-"
+
+// Pruning holds the configured limits on kept transaction bodies. Like
+// DataDir it is set once from the loaded configuration.
+var Pruning config.PruneConfig
+
+func SetPruning(limits config.PruneConfig) {
+	Pruning = limits
+}
+
+// PrunedHeight returns the height below which prune mode has discarded
+// every block's transactions.
+func (lm *LedgerManager) PrunedHeight() int64 {
+	return lm.prunedHeight
+}
+
+// pruneBlocks discards the transactions of the blocks that fall outside the
+// configured limits, on disk and in memory. The tip always keeps its
+// transactions.
+func (lm *LedgerManager) pruneBlocks(store *BlockStore) error {
+	from := lm.prunedHeight
+	if from > store.Height() {
+		from = store.Height()
+	}
+
+	target, err := pruneTarget(store, from)
+	if err != nil {
+		return err
+	}
+	if target <= from {
+		return nil
+	}
+
+	if err := store.Prune(from, target); err != nil {
+		return err
+	}
+	var dropped []*core.Transaction
+	for height := from; height < target; height++ {
+		block := lm.Blockchain.Chain[height]
+		if block.Pruned {
+			continue
+		}
+		for _, tx := range block.Transactions {
+			if !tx.KeepsState() {
+				dropped = append(dropped, tx)
+			}
+		}
+		block.Prune()
+	}
+
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+	lm.prunedHeight = target
+	batch := NewKVBatch()
+	meta := lm.stageChainMeta(batch)
+	lm.stageForget(batch, dropped)
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	lm.committed = meta
+
+	fmt.Printf("Pruned transactions of blocks %d-%d\n", from, target-1)
+	return nil
+}
+
+// stageForget rewrites the index lists that named pruned transactions.
+// Their locations stay, since references to them are checked by ID.
+func (lm *LedgerManager) stageForget(batch *KVBatch, txs []*core.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	index := lm.Blockchain.Index()
+	index.Forget(txs)
+
+	addresses := make(map[string]bool)
+	types := make(map[core.TransactionType]bool)
+	for _, tx := range txs {
+		addresses[tx.Sender] = true
+		if tx.Receiver != "" {
+			addresses[tx.Receiver] = true
+		}
+		types[tx.Type] = true
+	}
+
+	putList := func(key string, ids []string) {
+		if len(ids) == 0 {
+			batch.Delete(key)
+			return
+		}
+		encoded, _ := json.Marshal(ids)
+		batch.Put(key, encoded)
+	}
+	for address := range addresses {
+		putList(indexAddrPrefix+address, index.Addresses[address])
+	}
+	for txType := range types {
+		putList(indexTypePrefix+strconv.Itoa(int(txType)), index.Types[txType])
+	}
+}
+
+// pruneTarget works out the height below which blocks should lose their
+// transactions: everything but the newest keep_blocks, and everything that
+// doesn't fit in max_size_mb counting back from the tip.
+func pruneTarget(store *BlockStore, from int64) (int64, error) {
+	height := store.Height()
+	var target int64
+
+	if Pruning.KeepBlocks > 0 {
+		target = height - int64(Pruning.KeepBlocks)
+	}
+	if Pruning.MaxSizeMB > 0 {
+		budget := int64(Pruning.MaxSizeMB) * 1024 * 1024
+		var total int64
+		for h := height - 1; h >= from && h >= target; h-- {
+			size, err := store.recordSize(h)
+			if err != nil {
+				return 0, err
+			}
+			total += size
+			if total > budget {
+				target = h + 1
+				break
+			}
+		}
+	}
+
+	if target > height-1 {
+		target = height - 1
+	}
+	return target, nil
+}
+
+func (s *BlockStore) recordSize(height int64) (int64, error) {
+	at := s.offsets[height]
+	if next := height + 1; next < s.Height() && s.offsets[next].Segment == at.Segment {
+		return int64(s.offsets[next].Offset - at.Offset), nil
+	}
+	length, err := s.recordLength(at)
+	if err != nil {
+		return 0, err
+	}
+	return recordHeaderSize + int64(length), nil
+}
+
+// Prune rewrites every segment holding blocks in [from, below) so that
+// those blocks keep only their headers and Merkle roots. Each segment is
+// written to a temporary file along with the offsets that match it; the
+// segment is renamed into place first and the offsets second, and
+// finishPrune completes or undoes the pair after a crash.
+func (s *BlockStore) Prune(from, below int64) error {
+	if below > s.Height() {
+		below = s.Height()
+	}
+
+	for height := from; height < below; {
+		segment := s.offsets[height].Segment
+		first := height
+		for first > 0 && s.offsets[first-1].Segment == segment {
+			first--
+		}
+		end := height
+		for end < s.Height() && s.offsets[end].Segment == segment {
+			end++
+		}
+
+		if err := s.pruneSegment(segment, first, end, below); err != nil {
+			return err
+		}
+		height = end
+	}
+	return nil
+}
+
+func (s *BlockStore) pruneSegment(segment uint32, first, end, below int64) error {
+	path := filepath.Join(s.dir, segmentName(segment))
+	tmpPath := filepath.Join(s.dir, pruneTmpPrefix+segmentName(segment))
+	offsetsPath := filepath.Join(s.dir, BlockOffsetFile)
+	offsetsTmp := filepath.Join(s.dir, pruneTmpPrefix+BlockOffsetFile)
+
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %v", err)
+	}
+	defer in.Close()
+	reader := bufio.NewReaderSize(in, 256*1024)
+
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create pruned segment: %v", err)
+	}
+	defer os.Remove(tmpPath)
+
+	offsets := append([]blockOffset(nil), s.offsets...)
+	var position uint64
+	for height := first; height < end; height++ {
+		payload, err := readRecord(reader, maxBlockRecord)
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to read block %d: %v", height, err)
+		}
+
+		if height < below {
+			var block core.Block
+			if err := json.Unmarshal(payload, &block); err != nil {
+				out.Close()
+				return fmt.Errorf("failed to decode block %d: %v", height, err)
+			}
+			if !block.Pruned {
+				block.Prune()
+				payload, _ = json.Marshal(&block)
+			}
+		}
+
+		record := encodeRecord(payload)
+		if _, err := out.Write(record); err != nil {
+			out.Close()
+			return fmt.Errorf("failed to write pruned segment: %v", err)
+		}
+		offsets[height] = blockOffset{Segment: segment, Offset: position}
+		position += uint64(len(record))
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to sync pruned segment: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, len(offsets)*blockOffsetSize)
+	for _, entry := range offsets {
+		data = binary.BigEndian.AppendUint32(data, entry.Segment)
+		data = binary.BigEndian.AppendUint64(data, entry.Offset)
+	}
+	if err := WriteFileAtomic(offsetsTmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pruned offsets: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(offsetsTmp)
+		return fmt.Errorf("failed to replace segment: %v", err)
+	}
+	syncDir(s.dir)
+	if err := os.Rename(offsetsTmp, offsetsPath); err != nil {
+		return fmt.Errorf("failed to replace block offsets: %v", err)
+	}
+	syncDir(s.dir)
+
+	index, err := os.OpenFile(offsetsPath, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open block offsets: %v", err)
+	}
+	s.index.Close()
+	s.index = index
+	s.offsets = offsets
+
+	if segment == s.tailID {
+		return s.openTail()
+	}
+	return nil
+}
+
+// finishPrune completes or undoes a segment rewrite that a crash cut short.
+// A leftover segment means it was never renamed into place, so both
+// temporary files are dropped; leftover offsets alone mean the segment was
+// replaced and only the offsets still have to follow.
+func finishPrune(dir string) error {
+	offsetsTmp := filepath.Join(dir, pruneTmpPrefix+BlockOffsetFile)
+	segments, err := filepath.Glob(filepath.Join(dir, pruneTmpPrefix+"seg-*"))
+	if err != nil {
+		return err
+	}
+
+	if len(segments) > 0 {
+		for _, path := range segments {
+			os.Remove(path)
+		}
+		os.Remove(offsetsTmp)
+		return nil
+	}
+	if _, err := os.Stat(offsetsTmp); err == nil {
+		fmt.Println("Block store: completing an interrupted prune")
+		if err := os.Rename(offsetsTmp, filepath.Join(dir, BlockOffsetFile)); err != nil {
+			return fmt.Errorf("failed to complete prune: %v", err)
+		}
+		syncDir(dir)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"chainlog/config"
+	"chainlog/core"
+	"chainlog/crypto"
+	"path/filepath"
+	"testing"
+)
+
+// Pruning keeps the transactions later ones are validated against and
+// takes the rest out of the index lists, on disk and in memory.
+func TestPruneKeepsStateTransactions(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+	SetPruning(config.PruneConfig{KeepBlocks: 2})
+	defer SetPruning(config.PruneConfig{})
+
+	lm := testLedger(t, kv)
+	chain := lm.Blockchain.Chain
+	tip := chain[0]
+	block := core.NewBlock(1, "", tip.Hash)
+	block.Transactions = []*core.Transaction{
+		{ID: "data-1", Type: core.DataTx, Sender: "alice"},
+		{ID: "grant-1", Type: core.DelegationTx, Sender: "alice", Receiver: "bob", Data: core.DelegationGrant},
+		{ID: "stream-1", Type: core.DataTx, Sender: "alice", Stream: "logs", Sequence: 1},
+		{ID: "data-2", Type: core.DataTx, Sender: "bob"},
+	}
+	block.MerkleRoot = block.ComputeMerkleRoot()
+	block.Hash = block.CalculateHash()
+	lm.Blockchain.Chain = extendChain(append(chain, block), 3, "later")
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	lm.store.Close()
+
+	check := func(name string, bc *core.Blockchain) {
+		if !bc.Chain[1].Pruned {
+			t.Fatalf("%s: block 1 was not pruned", name)
+		}
+		for _, txID := range []string{"grant-1", "stream-1"} {
+			if tx, found := bc.FindTransaction(txID); tx == nil || tx.ID != txID || found != bc.Chain[1] {
+				t.Errorf("%s: kept transaction %s is not found", name, txID)
+			}
+		}
+		for _, txID := range []string{"data-1", "data-2"} {
+			if tx, _ := bc.FindTransaction(txID); tx != nil {
+				t.Errorf("%s: pruned transaction %s still has its body", name, txID)
+			}
+			if tx, block := bc.FindReference(txID); tx == nil || block != bc.Chain[1] {
+				t.Errorf("%s: pruned transaction %s can no longer be found by ID", name, txID)
+			}
+		}
+		if !bc.IsDelegate("alice", "bob") {
+			t.Errorf("%s: delegation was lost", name)
+		}
+		if got := bc.LastSequence("logs", "alice"); got != 1 {
+			t.Errorf("%s: last stream sequence = %d, want 1", name, got)
+		}
+		if ids := bc.Index().Addresses["bob"]; len(ids) != 1 || ids[0] != "grant-1" {
+			t.Errorf("%s: bob's transactions = %v, want grant-1", name, ids)
+		}
+	}
+	check("in memory", lm.Blockchain)
+
+	reloaded := testLedger(t, kv)
+	if err := reloaded.LoadBlockchain(); err != nil {
+		t.Fatalf("LoadBlockchain: %v", err)
+	}
+	defer reloaded.store.Close()
+	check("reloaded", reloaded.Blockchain)
+}
+
+// Entries whose blocks were pruned can still be attested and amended.
+func TestPruneKeepsReferences(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+	SetPruning(config.PruneConfig{KeepBlocks: 2})
+	defer SetPruning(config.PruneConfig{})
+
+	owner, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	witness, err := crypto.NewWallet()
+	if err != nil {
+		t.Fatalf("NewWallet: %v", err)
+	}
+	original, err := core.NewDataTransaction("entry", owner, 1)
+	if err != nil {
+		t.Fatalf("NewDataTransaction: %v", err)
+	}
+	revision, err := core.NewAmendmentTransaction(original.ID, "entry v2", owner, 1)
+	if err != nil {
+		t.Fatalf("NewAmendmentTransaction: %v", err)
+	}
+
+	lm := testLedger(t, kv)
+	chain := lm.Blockchain.Chain
+	block := core.NewBlock(1, "", chain[0].Hash)
+	block.Transactions = []*core.Transaction{original, revision}
+	block.MerkleRoot = block.ComputeMerkleRoot()
+	block.Hash = block.CalculateHash()
+	lm.Blockchain.Chain = extendChain(append(chain, block), 3, "later")
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	lm.store.Close()
+
+	reloaded := testLedger(t, kv)
+	if err := reloaded.LoadBlockchain(); err != nil {
+		t.Fatalf("LoadBlockchain: %v", err)
+	}
+	defer reloaded.store.Close()
+	bc := reloaded.Blockchain
+	if !bc.Chain[1].Pruned {
+		t.Fatal("block 1 was not pruned")
+	}
+	if tx, _ := bc.FindTransaction(original.ID); tx != nil {
+		t.Fatal("the original entry kept its body")
+	}
+
+	tests := []struct {
+		name  string
+		build func() (*core.Transaction, error)
+		valid bool
+	}{
+		{
+			name: "attest the pruned entry",
+			build: func() (*core.Transaction, error) {
+				return core.NewAttestationTransaction(original.ID, "seen", witness, 1)
+			},
+			valid: true,
+		},
+		{
+			name: "amend the pruned entry",
+			build: func() (*core.Transaction, error) {
+				return core.NewAmendmentTransaction(original.ID, "entry v3", owner, 1)
+			},
+			valid: true,
+		},
+		{
+			name: "amend the kept revision",
+			build: func() (*core.Transaction, error) {
+				return core.NewAmendmentTransaction(revision.ID, "entry v3", owner, 1)
+			},
+			valid: true,
+		},
+		{
+			name: "amend as someone else",
+			build: func() (*core.Transaction, error) {
+				return core.NewAmendmentTransaction(original.ID, "forged", witness, 1)
+			},
+		},
+		{
+			name: "attest an unknown entry",
+			build: func() (*core.Transaction, error) {
+				return core.NewAttestationTransaction("missing", "seen", witness, 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := tt.build()
+			if err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			if got := core.NewValidator(bc).ValidateTransaction(tx); got != tt.valid {
+				t.Errorf("ValidateTransaction = %t, want %t", got, tt.valid)
+			}
+		})
+	}
+
+	history, err := bc.ResolveRevisions(original.ID)
+	if err != nil {
+		t.Fatalf("ResolveRevisions: %v", err)
+	}
+	if len(history.Revisions) != 1 || history.Revisions[0].Transaction.ID != revision.ID {
+		t.Errorf("revisions of the pruned entry = %d, want the kept one", len(history.Revisions))
+	}
+}
+
+// Rewriting the saved index from scratch, as after a reorg, keeps the
+// locations of pruned transactions.
+func TestPruneSurvivesIndexRewrite(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+	SetPruning(config.PruneConfig{KeepBlocks: 2})
+	defer SetPruning(config.PruneConfig{})
+
+	lm := testLedger(t, kv)
+	lm.Blockchain.Chain = extendChain(lm.Blockchain.Chain, 4, "old")
+	if err := lm.SaveBlockchain(); err != nil {
+		t.Fatalf("SaveBlockchain: %v", err)
+	}
+	if !lm.Blockchain.Chain[1].Pruned {
+		t.Fatal("block 1 was not pruned")
+	}
+
+	lm.savedIndexTip, lm.savedIndexHeight = "", 0
+	if err := lm.SaveIndex(); err != nil {
+		t.Fatalf("SaveIndex: %v", err)
+	}
+	lm.store.Close()
+
+	reloaded := testLedger(t, kv)
+	if err := reloaded.LoadBlockchain(); err != nil {
+		t.Fatalf("LoadBlockchain: %v", err)
+	}
+	defer reloaded.store.Close()
+	if tx, block := reloaded.Blockchain.FindReference("old-1"); tx == nil || block.Index != 1 {
+		t.Error("pruned transaction old-1 is lost after the index was rewritten")
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const recordHeaderSize = 8
+
+var recordCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// encodeRecord frames a payload as a 4-byte length, a CRC32-C of the
+// payload and the payload itself. Block segments, the state log and the
+// journal all use this framing so a torn write is always detectable.
+func encodeRecord(payload []byte) []byte {
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, recordCRCTable))
+	return append(record, payload...)
+}
+
+func readRecord(reader io.Reader, maxLength uint32) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length == 0 || length > maxLength {
+		return nil, fmt.Errorf("invalid record length %d", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, recordCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("record checksum mismatch")
+	}
+	return payload, nil
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"chainlog/core"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SearchIndexFile is where older versions kept the whole search index. It
+// is moved into the state store on first load.
+const SearchIndexFile = "search_index.json"
+
+// The search index lives in the state store with one key per block hash,
+// document and (term, transaction) posting, so adding a block writes only
+// that block's entries.
+const (
+	searchMetaKey     = "search/meta"
+	searchBlockPrefix = "search/block/"
+	searchDocPrefix   = "search/doc/"
+	searchTermPrefix  = "search/term/"
+)
+
+type searchMeta struct {
+	Height      int64  `json:"height"`
+	TipHash     string `json:"tip_hash"`
+	TotalLength int64  `json:"total_length"`
+}
+
+func SearchIndexEnabled() bool {
+	if FileExists(SearchIndexFile) {
+		return true
+	}
+	kv, err := GetStateStore()
+	if err != nil {
+		return false
+	}
+	_, exists, err := kv.Get(searchMetaKey)
+	return err == nil && exists
+}
+
+func LoadSearchIndex() (*core.SearchIndex, error) {
+	kv, err := GetStateStore()
+	if err != nil {
+		return nil, err
+	}
+	return loadSearchIndex(kv)
+}
+
+func loadSearchIndex(kv KVStore) (*core.SearchIndex, error) {
+	if FileExists(SearchIndexFile) {
+		if err := migrateSearchIndexFile(kv); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, exists, err := kv.Get(searchMetaKey)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("search index is not enabled (run 'search rebuild' to build it)")
+	}
+	var meta searchMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("invalid search index metadata: %v", err)
+	}
+
+	index := core.NewSearchIndex()
+	index.Height = meta.Height
+	index.TotalLength = meta.TotalLength
+	index.BlockHashes = make([]string, meta.Height)
+
+	err = kv.Scan("search/", func(key string, value []byte) bool {
+		switch {
+		case strings.HasPrefix(key, searchBlockPrefix):
+			height, err := strconv.ParseInt(strings.TrimPrefix(key, searchBlockPrefix), 10, 64)
+			if err == nil && height < meta.Height {
+				index.BlockHashes[height] = string(value)
+			}
+		case strings.HasPrefix(key, searchDocPrefix):
+			var doc core.SearchDocument
+			if json.Unmarshal(value, &doc) == nil {
+				index.Documents[strings.TrimPrefix(key, searchDocPrefix)] = doc
+			}
+		case strings.HasPrefix(key, searchTermPrefix):
+			posting := strings.TrimPrefix(key, searchTermPrefix)
+			slash := strings.LastIndex(posting, "/")
+			count, err := strconv.Atoi(string(value))
+			if slash < 0 || err != nil {
+				break
+			}
+			term, txID := posting[:slash], posting[slash+1:]
+			if index.Postings[term] == nil {
+				index.Postings[term] = make(map[string]int)
+			}
+			index.Postings[term][txID] = count
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if index.TipHash() != meta.TipHash {
+		return nil, fmt.Errorf("search index is incomplete (run 'search rebuild' to rebuild it)")
+	}
+	return index, nil
+}
+
+// migrateSearchIndexFile moves an index kept as one JSON file into the
+// state store.
+func migrateSearchIndexFile(kv KVStore) error {
+	index := core.NewSearchIndex()
+	if err := LoadFromFile(index, SearchIndexFile); err != nil {
+		return err
+	}
+
+	batch := NewKVBatch()
+	stageSearchIndex(batch, index)
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(DataDir, SearchIndexFile))
+}
+
+// stageSearchIndex adds every entry of index to batch.
+func stageSearchIndex(batch *KVBatch, index *core.SearchIndex) {
+	for height, hash := range index.BlockHashes {
+		batch.Put(searchBlockPrefix+strconv.Itoa(height), []byte(hash))
+	}
+	for txID, doc := range index.Documents {
+		encoded, _ := json.Marshal(doc)
+		batch.Put(searchDocPrefix+txID, encoded)
+	}
+	for term, postings := range index.Postings {
+		for txID, count := range postings {
+			batch.Put(searchTermPrefix+term+"/"+txID, []byte(strconv.Itoa(count)))
+		}
+	}
+	stageSearchMeta(batch, index)
+}
+
+func stageSearchMeta(batch *KVBatch, index *core.SearchIndex) {
+	meta, _ := json.Marshal(&searchMeta{Height: index.Height, TipHash: index.TipHash(), TotalLength: index.TotalLength})
+	batch.Put(searchMetaKey, meta)
+}
+
+// stageSearchBlocks adds the entries of the blocks the index holds from
+// height from on to batch. It finds them through the blocks' transactions
+// rather than by walking the whole index.
+func stageSearchBlocks(batch *KVBatch, index *core.SearchIndex, chain []*core.Block, from int64) {
+	for height := from; height < index.Height; height++ {
+		batch.Put(searchBlockPrefix+strconv.FormatInt(height, 10), []byte(index.BlockHashes[height]))
+
+		for _, tx := range chain[height].Transactions {
+			doc, indexed := index.Documents[tx.ID]
+			if !indexed || doc.Height != height {
+				continue
+			}
+			encoded, _ := json.Marshal(doc)
+			batch.Put(searchDocPrefix+tx.ID, encoded)
+
+			for _, term := range core.SearchTokens(tx) {
+				batch.Put(searchTermPrefix+term+"/"+tx.ID, []byte(strconv.Itoa(index.Postings[term][tx.ID])))
+			}
+		}
+	}
+	stageSearchMeta(batch, index)
+}
+
+// stageSearchReset deletes every saved search index entry.
+func stageSearchReset(kv KVStore, batch *KVBatch) error {
+	return kv.Scan("search/", func(key string, value []byte) bool {
+		batch.Delete(key)
+		return true
+	})
+}
+
+// SyncSearchIndex adds the blocks the saved index is missing. Only a reorg
+// rewrites it from the start.
+func SyncSearchIndex(bc *core.Blockchain) (*core.SearchIndex, error) {
+	kv, err := GetStateStore()
+	if err != nil {
+		return nil, err
+	}
+	return syncSearchIndex(kv, bc)
+}
+
+func syncSearchIndex(kv KVStore, bc *core.Blockchain) (*core.SearchIndex, error) {
+	index, err := loadSearchIndex(kv)
+	if err != nil {
+		return nil, err
+	}
+
+	from := index.Height
+	added, rebuilt := index.Sync(bc.Chain)
+	if added == 0 && !rebuilt {
+		return index, nil
+	}
+
+	batch := NewKVBatch()
+	if rebuilt {
+		fmt.Printf("Search index did not match the chain, rebuilt %d blocks\n", added)
+		if err := stageSearchReset(kv, batch); err != nil {
+			return nil, err
+		}
+		from = 0
+	}
+	stageSearchBlocks(batch, index, bc.Chain, from)
+	if err := kv.Write(batch); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func RebuildSearchIndex(bc *core.Blockchain) (*core.SearchIndex, error) {
+	kv, err := GetStateStore()
+	if err != nil {
+		return nil, err
+	}
+	return rebuildSearchIndex(kv, bc)
+}
+
+func rebuildSearchIndex(kv KVStore, bc *core.Blockchain) (*core.SearchIndex, error) {
+	index := core.NewSearchIndex()
+	index.Sync(bc.Chain)
+
+	batch := NewKVBatch()
+	if err := stageSearchReset(kv, batch); err != nil {
+		return nil, err
+	}
+	stageSearchBlocks(batch, index, bc.Chain, 0)
+	if err := kv.Write(batch); err != nil {
+		return nil, err
+	}
+	os.Remove(filepath.Join(DataDir, SearchIndexFile))
+	return index, nil
+}
+
+func DeleteSearchIndex() error {
+	kv, err := GetStateStore()
+	if err != nil {
+		return err
+	}
+	batch := NewKVBatch()
+	if err := stageSearchReset(kv, batch); err != nil {
+		return err
+	}
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(DataDir, SearchIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove search index: %v", err)
+	}
+	return nil
+}
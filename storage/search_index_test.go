@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"chainlog/core"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingKV remembers the keys of every batch written through it.
+type recordingKV struct {
+	KVStore
+	written []string
+}
+
+func (r *recordingKV) Write(batch *KVBatch) error {
+	for _, op := range batch.ops {
+		r.written = append(r.written, op.Key)
+	}
+	return r.KVStore.Write(batch)
+}
+
+// searchChain extends chain with n blocks whose entries mention branch.
+func searchChain(chain []*core.Block, n int, branch string) []*core.Block {
+	chain = append([]*core.Block(nil), chain...)
+	for i := 0; i < n; i++ {
+		tip := chain[len(chain)-1]
+		block := core.NewBlock(tip.Index+1, "", tip.Hash)
+		block.Transactions = []*core.Transaction{{
+			ID:     fmt.Sprintf("%s-%d", branch, block.Index),
+			Data:   fmt.Sprintf("user=%s login %d", branch, block.Index),
+			Sender: branch,
+		}}
+		block.MerkleRoot = block.ComputeMerkleRoot()
+		block.Hash = block.CalculateHash()
+		chain = append(chain, block)
+	}
+	return chain
+}
+
+func checkSavedSearchIndex(t *testing.T, kv KVStore, want *core.SearchIndex) {
+	t.Helper()
+	saved, err := loadSearchIndex(kv)
+	if err != nil {
+		t.Fatalf("loadSearchIndex: %v", err)
+	}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved index differs: height %d with %d entries, want height %d with %d", saved.Height, len(saved.Documents), want.Height, len(want.Documents))
+	}
+}
+
+func TestSearchIndexSavesOnlyNewBlocks(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := &recordingKV{KVStore: openTestLogKV(t, filepath.Join(DataDir, StateDBFile))}
+
+	bc := core.NewBlockchain()
+	bc.Chain = searchChain(bc.Chain, 3, "alice")
+	if _, err := rebuildSearchIndex(kv, bc); err != nil {
+		t.Fatalf("rebuildSearchIndex: %v", err)
+	}
+
+	bc.Chain = searchChain(bc.Chain, 1, "bob")
+	kv.written = nil
+	index, err := syncSearchIndex(kv, bc)
+	if err != nil {
+		t.Fatalf("syncSearchIndex: %v", err)
+	}
+	for _, key := range kv.written {
+		if key != searchMetaKey && key != searchBlockPrefix+"4" && !strings.HasSuffix(key, "bob-4") {
+			t.Errorf("appending block 4 wrote %s", key)
+		}
+	}
+	checkSavedSearchIndex(t, kv, index)
+	if got := index.Search("bob", 10).Total; got != 1 {
+		t.Errorf("search for bob found %d entries, want 1", got)
+	}
+
+	// A reorg replaces blocks 3 and 4, so the saved index starts over.
+	bc.Chain = searchChain(bc.Chain[:3], 3, "carol")
+	index, err = syncSearchIndex(kv, bc)
+	if err != nil {
+		t.Fatalf("syncSearchIndex: %v", err)
+	}
+	fresh := core.NewSearchIndex()
+	fresh.Sync(bc.Chain)
+	if !reflect.DeepEqual(index, fresh) {
+		t.Error("index after the reorg differs from one built from scratch")
+	}
+	checkSavedSearchIndex(t, kv, fresh)
+}
+
+func TestSearchIndexMigratesFile(t *testing.T) {
+	DataDir = t.TempDir()
+	kv := openTestLogKV(t, filepath.Join(DataDir, StateDBFile))
+
+	bc := core.NewBlockchain()
+	bc.Chain = searchChain(bc.Chain, 2, "alice")
+	index := core.NewSearchIndex()
+	index.Sync(bc.Chain)
+	if err := SaveToFile(index, SearchIndexFile); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	checkSavedSearchIndex(t, kv, index)
+	if FileExists(SearchIndexFile) {
+		t.Error("the old index file was not removed")
+	}
+}
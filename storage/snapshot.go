@@ -0,0 +1,378 @@
+package storage
+
+import (
+	"bytes"
+	"chainlog/core"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const SnapshotVersion = 1
+
+// SnapshotTrustDistance is how far below the snapshot's block the trusted
+// block may be. Headers above the trusted block are only covered by their
+// proof of work, and the minimum difficulty makes a long run of them cheap
+// to forge, so the trusted block has to be a recent checkpoint.
+const SnapshotTrustDistance = 100
+
+// Snapshot is the node's state at the tip of its chain: accounts, indexes,
+// staking and chain parameters, together with the header chain leading to
+// the tip so that a new node can check which chain the snapshot is for
+// without replaying every block.
+//
+// Block headers do not commit to the state, so the state itself cannot be
+// verified: StateHash only shows it is the state the snapshot's creator
+// wrote, and restoring a snapshot means trusting its creator.
+type Snapshot struct {
+	Version   int                 `json:"version"`
+	Height    int64               `json:"height"`
+	BlockHash string              `json:"block_hash"`
+	CreatedAt int64               `json:"created_at"`
+	StateHash string              `json:"state_hash"`
+	Headers   []*core.BlockHeader `json:"headers"`
+	Tip       *core.Block         `json:"tip"`
+	State     SnapshotState       `json:"state"`
+}
+
+type SnapshotState struct {
+	Difficulty  int             `json:"difficulty"`
+	BlockReward uint64          `json:"block_reward"`
+	Accounts    []*AccountState `json:"accounts"`
+	Index       []SnapshotEntry `json:"index"`
+	Staking     json.RawMessage `json:"staking,omitempty"`
+}
+
+type SnapshotEntry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// CreateSnapshot captures the saved state at the tip of the loaded chain.
+// Accounts are not kept per height, so a snapshot can only be taken at the
+// tip.
+func (lm *LedgerManager) CreateSnapshot() (*Snapshot, error) {
+	if err := lm.SaveIndex(); err != nil {
+		return nil, fmt.Errorf("failed to bring the chain index up to date: %v", err)
+	}
+	kv, err := lm.stateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	tip := lm.Blockchain.GetLastBlock()
+	if tip.Pruned {
+		return nil, fmt.Errorf("the tip block %d has been pruned", tip.Index)
+	}
+
+	snap := &Snapshot{
+		Version:   SnapshotVersion,
+		Height:    int64(len(lm.Blockchain.Chain)),
+		BlockHash: tip.Hash,
+		CreatedAt: time.Now().Unix(),
+		Tip:       tip,
+		State: SnapshotState{
+			Difficulty:  lm.Blockchain.Difficulty,
+			BlockReward: lm.Blockchain.BlockReward,
+			Accounts:    []*AccountState{},
+			Index:       []SnapshotEntry{},
+		},
+	}
+	for _, block := range lm.Blockchain.Chain {
+		snap.Headers = append(snap.Headers, block.Header())
+	}
+
+	err = kv.Scan(accountKeyPrefix, func(key string, value []byte) bool {
+		var account AccountState
+		if json.Unmarshal(value, &account) == nil {
+			snap.State.Accounts = append(snap.State.Accounts, &account)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = kv.Scan("index/", func(key string, value []byte) bool {
+		snap.State.Index = append(snap.State.Index, SnapshotEntry{Key: key, Value: value})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile(Path(StakingFile)); err == nil {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			return nil, fmt.Errorf("invalid staking data: %v", err)
+		}
+		snap.State.Staking = compact.Bytes()
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read staking data: %v", err)
+	}
+
+	snap.StateHash, err = snap.State.hash()
+	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func (s *SnapshotState) hash() (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot state: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks the snapshot and that its header chain includes the block
+// trusted, such as a checkpoint hash the user knows or the tip of the local
+// chain, no more than SnapshotTrustDistance blocks below the snapshot's
+// block. Without that, any chain of valid headers would pass.
+func (s *Snapshot) Verify(trusted string) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	if trusted == "" {
+		return fmt.Errorf("no trusted block hash to check the header chain against")
+	}
+	for _, header := range s.Headers {
+		if header.Hash != trusted {
+			continue
+		}
+		if distance := s.Height - 1 - header.Index; distance > SnapshotTrustDistance {
+			return fmt.Errorf("trusted block %.16s... is %d blocks below the snapshot block; trust a block within %d blocks of height %d", trusted, distance, SnapshotTrustDistance, s.Height-1)
+		}
+		return nil
+	}
+	return fmt.Errorf("header chain does not include the trusted block %.16s...", trusted)
+}
+
+// Check checks the snapshot on its own: the header chain links up from the
+// genesis block to the snapshot's block with valid proof of work, the tip
+// block matches its header and its transactions, and the state matches its
+// checksum.
+func (s *Snapshot) Check() error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	if s.Height < 1 || int64(len(s.Headers)) != s.Height {
+		return fmt.Errorf("snapshot has %d headers for height %d", len(s.Headers), s.Height)
+	}
+	if err := core.VerifyHeaders(s.Headers); err != nil {
+		return fmt.Errorf("invalid header chain: %v", err)
+	}
+	if s.Headers[s.Height-1].Hash != s.BlockHash {
+		return fmt.Errorf("header chain ends at %.16s..., not at the snapshot block %.16s...", s.Headers[s.Height-1].Hash, s.BlockHash)
+	}
+
+	if s.Tip == nil || s.Tip.Pruned || s.Tip.Index != s.Height-1 || s.Tip.Hash != s.BlockHash {
+		return fmt.Errorf("snapshot does not include block %d", s.Height-1)
+	}
+	if *s.Tip.Header() != *s.Headers[s.Height-1] {
+		return fmt.Errorf("tip block does not match its header")
+	}
+	if s.Tip.MerkleRoot != "" && s.Tip.MerkleRoot != s.Tip.ComputeMerkleRoot() {
+		return fmt.Errorf("tip block transactions do not match its merkle root")
+	}
+
+	stateHash, err := s.State.hash()
+	if err != nil {
+		return err
+	}
+	if stateHash != s.StateHash {
+		return fmt.Errorf("state does not match the snapshot's state checksum")
+	}
+
+	for _, entry := range s.State.Index {
+		if entry.Key != indexMetaKey {
+			continue
+		}
+		var meta indexMeta
+		if err := json.Unmarshal(entry.Value, &meta); err != nil {
+			return fmt.Errorf("invalid chain index metadata: %v", err)
+		}
+		if meta.Height != s.Height || meta.TipHash != s.BlockHash {
+			return fmt.Errorf("chain index is at height %d, not at the snapshot height %d", meta.Height, s.Height)
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the saved state with the snapshot's. Its header
+// chain must include the trusted block hash, or, if none is given, the tip
+// of the local chain. The local chain, if there is one, must be part of the
+// snapshot's header chain; the blocks it is missing up to the snapshot
+// height are stored as pruned blocks rebuilt from their headers.
+func (lm *LedgerManager) RestoreSnapshot(snap *Snapshot, trusted string) error {
+	if err := snap.Check(); err != nil {
+		return fmt.Errorf("snapshot is invalid: %v", err)
+	}
+	if err := EnsureDataDir(); err != nil {
+		return err
+	}
+
+	var local []*core.Block
+	if err := lm.LoadBlockchain(); err != nil {
+		fmt.Printf("No local chain to check against (%v)\n", err)
+		lm.Blockchain.PendingTx = []*core.Transaction{}
+	} else {
+		local = lm.Blockchain.Chain
+	}
+
+	if trusted == "" && len(local) > 0 {
+		trusted = local[len(local)-1].Hash
+	}
+	if trusted == "" {
+		return fmt.Errorf("there is no local chain to check the snapshot against; give the hash of a block you trust")
+	}
+	if err := snap.Verify(trusted); err != nil {
+		return err
+	}
+
+	if int64(len(local)) > snap.Height {
+		return fmt.Errorf("local chain is at height %d, past the snapshot height %d", len(local), snap.Height)
+	}
+	for i, block := range local {
+		if block.Hash != snap.Headers[i].Hash {
+			return fmt.Errorf("local block %d does not match the snapshot's header chain", i)
+		}
+	}
+
+	lock, err := lockLedger()
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	chain := append([]*core.Block(nil), local...)
+	for height := int64(len(local)); height < snap.Height-1; height++ {
+		chain = append(chain, core.NewPrunedBlock(snap.Headers[height]))
+	}
+	if int64(len(local)) < snap.Height {
+		chain = append(chain, snap.Tip)
+	}
+
+	lm.Blockchain.Chain = chain
+	lm.Blockchain.Difficulty = snap.State.Difficulty
+	lm.Blockchain.BlockReward = snap.State.BlockReward
+
+	store, err := lm.blockStore()
+	if err != nil {
+		return err
+	}
+	fork, err := lm.storeForkPoint(store)
+	if err != nil {
+		return err
+	}
+	err = beginJournal(&journalEntry{
+		Fork:      fork,
+		Height:    snap.Height,
+		TipHash:   snap.BlockHash,
+		StartedAt: time.Now().Unix(),
+	}, store)
+	if err != nil {
+		return err
+	}
+	if err := lm.syncBlockStore(store, fork); err != nil {
+		return err
+	}
+
+	kv, err := lm.stateStore()
+	if err != nil {
+		return err
+	}
+	batch := NewKVBatch()
+	for _, prefix := range []string{accountKeyPrefix, "index/"} {
+		err := kv.Scan(prefix, func(key string, value []byte) bool {
+			batch.Delete(key)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, account := range snap.State.Accounts {
+		encoded, _ := json.Marshal(account)
+		batch.Put(accountKeyPrefix+account.Address, encoded)
+	}
+	for _, entry := range snap.State.Index {
+		batch.Put(entry.Key, entry.Value)
+	}
+	meta := lm.stageChainMeta(batch)
+
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	lm.committed = meta
+	if err := clearJournal(); err != nil {
+		return err
+	}
+
+	if len(snap.State.Staking) > 0 {
+		if err := WriteFileAtomic(Path(StakingFile), snap.State.Staking, 0644); err != nil {
+			return fmt.Errorf("failed to restore staking data: %v", err)
+		}
+	}
+
+	if err := lm.LoadIndex(); err != nil {
+		return err
+	}
+	if lm.State != nil {
+		return lm.State.LoadState()
+	}
+	return nil
+}
+
+func WriteSnapshot(w io.Writer, snap *Snapshot) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(snap); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+	return zw.Close()
+}
+
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot file: %v", err)
+	}
+	defer zr.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+	return &snap, nil
+}
+
+func EncodeSnapshot(snap *Snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, snap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func SaveSnapshotFile(path string, snap *Snapshot) error {
+	data, err := EncodeSnapshot(snap)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, 0644)
+}
+
+func LoadSnapshotFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSnapshot(file)
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"chainlog/core"
+	"strings"
+	"testing"
+)
+
+// minedSnapshot returns a snapshot of a chain of height blocks, each
+// carrying the minimum proof of work.
+func minedSnapshot(t *testing.T, height int) *Snapshot {
+	t.Helper()
+	chain := []*core.Block{core.NewBlock(0, "genesis", "")}
+	for len(chain) < height {
+		tip := chain[len(chain)-1]
+		block := core.NewBlock(tip.Index+1, "", tip.Hash)
+		for block.Hash = block.CalculateHash(); !core.MeetsDifficulty(block.Hash, core.MinDifficulty); block.Hash = block.CalculateHash() {
+			block.Nonce++
+		}
+		chain = append(chain, block)
+	}
+
+	snap := &Snapshot{
+		Version:   SnapshotVersion,
+		Height:    int64(height),
+		BlockHash: chain[height-1].Hash,
+		Tip:       chain[height-1],
+		State:     SnapshotState{Accounts: []*AccountState{}, Index: []SnapshotEntry{}},
+	}
+	for _, block := range chain {
+		snap.Headers = append(snap.Headers, block.Header())
+	}
+	stateHash, err := snap.State.hash()
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	snap.StateHash = stateHash
+	return snap
+}
+
+func TestSnapshotVerifyTrustDistance(t *testing.T) {
+	height := SnapshotTrustDistance + 10
+	snap := minedSnapshot(t, height)
+	tip := int64(height - 1)
+
+	tests := []struct {
+		name    string
+		trusted string
+		want    string
+	}{
+		{name: "tip", trusted: snap.BlockHash},
+		{name: "recent checkpoint", trusted: snap.Headers[tip-SnapshotTrustDistance].Hash},
+		{name: "old checkpoint", trusted: snap.Headers[tip-SnapshotTrustDistance-1].Hash, want: "blocks below the snapshot block"},
+		{name: "genesis", trusted: snap.Headers[0].Hash, want: "blocks below the snapshot block"},
+		{name: "unknown block", trusted: strings.Repeat("0", 64), want: "does not include the trusted block"},
+		{name: "no trusted block", want: "no trusted block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := snap.Verify(tt.trusted)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Verify: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Verify = %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
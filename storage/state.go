@@ -2,7 +2,11 @@ package storage
 
 import (
 	"chainlog/crypto"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 type AccountState struct {
@@ -13,29 +17,132 @@ type AccountState struct {
 
 type StateManager struct {
 	Accounts map[string]*AccountState `json:"accounts"`
+
+	kv    KVStore
+	saved map[string]string
 }
 
+const accountKeyPrefix = "account/"
+
 func NewStateManager() *StateManager {
 	return &StateManager{
 		Accounts: make(map[string]*AccountState),
+		saved:    make(map[string]string),
 	}
 }
 
+func NewStateManagerWithStore(kv KVStore) *StateManager {
+	sm := NewStateManager()
+	sm.kv = kv
+	return sm
+}
+
+func (sm *StateManager) store() (KVStore, error) {
+	if sm.kv == nil {
+		kv, err := GetStateStore()
+		if err != nil {
+			return nil, err
+		}
+		sm.kv = kv
+	}
+	return sm.kv, nil
+}
+
 func (sm *StateManager) SaveState() error {
-	if err := EnsureDataDir(); err != nil {
+	kv, err := sm.store()
+	if err != nil {
 		return err
 	}
-	
-	return SaveToFile(sm, StateFile)
+
+	batch := NewKVBatch()
+	changes := sm.stage(batch)
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	if err := kv.Write(batch); err != nil {
+		return err
+	}
+	sm.markSaved(changes)
+
+	fmt.Printf("Saved %d account(s) to %s\n", len(changes), StateDBFile)
+	return nil
+}
+
+// stage adds every account that changed since the last save to batch.
+func (sm *StateManager) stage(batch *KVBatch) map[string]string {
+	changes := make(map[string]string)
+	for address, account := range sm.Accounts {
+		encoded, err := json.Marshal(account)
+		if err != nil {
+			continue
+		}
+		if sm.saved[address] != string(encoded) {
+			batch.Put(accountKeyPrefix+address, encoded)
+			changes[address] = string(encoded)
+		}
+	}
+	return changes
+}
+
+func (sm *StateManager) markSaved(changes map[string]string) {
+	for address, encoded := range changes {
+		sm.saved[address] = encoded
+	}
 }
 
 func (sm *StateManager) LoadState() error {
-	if !FileExists(StateFile) {
+	kv, err := sm.store()
+	if err != nil {
+		return err
+	}
+
+	sm.Accounts = make(map[string]*AccountState)
+	sm.saved = make(map[string]string)
+	err = kv.Scan(accountKeyPrefix, func(key string, value []byte) bool {
+		var account AccountState
+		if json.Unmarshal(value, &account) == nil {
+			address := strings.TrimPrefix(key, accountKeyPrefix)
+			sm.Accounts[address] = &account
+			sm.saved[address] = string(value)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(sm.Accounts) == 0 && FileExists(StateFile) {
+		return sm.migrateStateFile()
+	}
+	if len(sm.Accounts) == 0 {
 		fmt.Println("No saved state found, starting fresh")
-		return nil
 	}
-	
-	return LoadFromFile(sm, StateFile)
+	return nil
+}
+
+func (sm *StateManager) migrateStateFile() error {
+	var legacy struct {
+		Accounts map[string]*AccountState `json:"accounts"`
+	}
+	if err := LoadFromFile(&legacy, StateFile); err != nil {
+		return err
+	}
+
+	for address, account := range legacy.Accounts {
+		sm.Accounts[address] = account
+	}
+	if err := sm.SaveState(); err != nil {
+		return err
+	}
+
+	backup := StateFile + ".migrated"
+	if err := os.Rename(filepath.Join(DataDir, StateFile), filepath.Join(DataDir, backup)); err != nil {
+		return fmt.Errorf("failed to move %s aside: %v", StateFile, err)
+	}
+	fmt.Printf("Migrated %d account(s) from %s into %s (old file kept as %s)\n",
+		len(legacy.Accounts), StateFile, StateDBFile, backup)
+	return nil
 }
 
 func (sm *StateManager) UpdateAccount(address string, balance uint64, nonce uint64) {
@@ -2,7 +2,10 @@ package storage
 
 import (
 	"chainlog/crypto"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -93,15 +96,29 @@ func (wm *WalletManager) loadWallets() error {
 
 	var wallets map[string]*StoredWallet
 	if err := LoadFromFile(&wallets, WalletsFile); err != nil { 
-		return err
+		if !FileExists(WalletsBackupFile) {
+			return err
+		}
+		fmt.Printf("Warning: %v, restoring wallets from %s\n", err, WalletsBackupFile)
+		if err := LoadFromFile(&wallets, WalletsBackupFile); err != nil {
+			return err
+		}
 	}
 
 	wm.Wallets = wallets
 	return nil
 }
 
+// saveToFile keeps the previous wallets.json as wallets.json.bak before
+// replacing it, since it holds the only copy of the keys.
 func (wm *WalletManager) saveToFile() error {
-	return SaveToFile(wm.Wallets, WalletsFile) 
+	current, err := os.ReadFile(filepath.Join(DataDir, WalletsFile))
+	if err == nil && json.Valid(current) {
+		if err := WriteFileAtomic(filepath.Join(DataDir, WalletsBackupFile), current, 0600); err != nil {
+			return fmt.Errorf("failed to back up wallets: %v", err)
+		}
+	}
+	return saveToFileMode(wm.Wallets, WalletsFile, 0600)
 }
 
 func (wm *WalletManager) WalletCount() int {